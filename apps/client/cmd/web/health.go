@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+)
+
+// newHealthRegistry returns the registry holding the web client's liveness and readiness checks.
+// The API is a non-critical readiness dependency: pages still render, showing it as down.
+func newHealthRegistry(api *contract.Client) *health.Registry {
+	checks := health.NewRegistry()
+	checks.MustRegister(health.Liveness, health.Check{
+		Name:     "process",
+		Critical: true,
+		Func:     func(context.Context) error { return nil },
+	})
+	checks.MustRegister(health.Readiness, health.Check{
+		Name:    "api",
+		Timeout: time.Second,
+		Func:    apiLivenessCheck(api),
+	})
+	return checks
+}
+
+// apiLivenessCheck fails unless the API liveness probe reports a live API.
+func apiLivenessCheck(api *contract.Client) health.CheckFunc {
+	return func(ctx context.Context) error {
+		report, err := api.Liveness(ctx)
+		if err != nil {
+			return err
+		}
+		if report.Status == health.StatusFail {
+			return errors.New("API liveness probe reported " + string(report.Status))
+		}
+		return nil
+	}
+}
@@ -1,26 +1,183 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/supergeoff/go-starter/apps/client/internal/auth"
+	"github.com/supergeoff/go-starter/apps/client/internal/config"
+	"github.com/supergeoff/go-starter/apps/client/internal/csrf"
 	"github.com/supergeoff/go-starter/apps/client/internal/handlers"
+	"github.com/supergeoff/go-starter/apps/client/internal/pages"
+	"github.com/supergeoff/go-starter/apps/client/internal/security"
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+	"github.com/supergeoff/go-starter/apps/client/templates"
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+	"github.com/supergeoff/go-starter/pkg/metrics"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+	"github.com/supergeoff/go-starter/pkg/tracing"
 )
 
-func setupRouter() *chi.Mux {
+// setupRouter configures and returns the chi router for the given configuration.
+// A nil tracer disables tracing. Nil sessions or users default to in-memory stores.
+func setupRouter(
+	cfg config.Config,
+	tracer *tracing.Tracer,
+	sessions *session.Manager,
+	users auth.UserStore,
+) *chi.Mux {
+	if sessions == nil {
+		sessions = session.NewManager(session.NewMemoryStore(), newRandomCodec())
+	}
+	if users == nil {
+		users = auth.NewMemoryUserStore()
+	}
 	r := chi.NewRouter()
-	fs := http.FileServer(http.Dir("build/assets"))
+	reg := metrics.NewRegistry()
+	metrics.RegisterRuntimeMetrics(reg)
+	r.Use(
+		middleware.RequestID,
+		tracing.Middleware(tracer),
+		middleware.Logger(slog.Default()),
+		metrics.Middleware(reg),
+		security.Headers(cfg.Security),
+	)
+	r.Method(http.MethodGet, "/metrics", reg.Handler())
+	if cfg.Security.CSPReport {
+		// Outside of the CSRF protection: browsers send reports without a token.
+		r.Post(security.ReportPath, security.ReportHandler)
+	}
+	fs := http.FileServer(http.Dir(cfg.AssetsDir))
 	r.Handle("/static/*", http.StripPrefix("/static/", fs))
-	r.Get("/", handlers.IndexHandler)
+	// Outbound calls to the API are instrumented and carry the ID and trace context of the
+	// request that triggered them.
+	transport, err := newAPITransport(cfg)
+	if err != nil {
+		panic("Error: " + err.Error())
+	}
+	apiClient := &http.Client{Transport: middleware.PropagateRequestID(tracing.Transport(
+		metrics.InstrumentRoundTripper(reg, "api", transport),
+	))}
+	api := contract.NewClient(cfg.APIBaseURL,
+		contract.WithHTTPClient(apiClient), contract.WithBearerToken(cfg.APIToken))
+	checks := newHealthRegistry(api)
+	r.Get("/livez", checks.Handler(health.Liveness))
+	r.Get("/readyz", checks.Handler(health.Readiness))
+	p := pages.NewHandler(api, reg)
+	r.Get(pages.HealthEventsPath, p.HealthEvents)
+	a := pages.NewAuthHandler(auth.NewService(users))
+	// Pages share the session, and every form they serve is protected against CSRF.
+	r.Group(func(r chi.Router) {
+		r.Use(sessions.Middleware, csrf.Protect)
+		r.Get("/", handlers.NewIndexHandler(p))
+		r.Get(auth.LoginPath, a.LoginForm)
+		r.Post(auth.LoginPath, a.Login)
+		r.Get("/signup", a.SignupForm)
+		r.Post("/signup", a.Signup)
+		r.Post("/logout", a.Logout)
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAuth)
+			r.Get("/account", a.Account)
+		})
+	})
 	return r
 }
 
+// newAPITransport returns the transport of the calls to the API: HTTP/2 without TLS with
+// api_h2c, and TLS trusting the certificates of api_ca_file when it is set.
+func newAPITransport(cfg config.Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.APIH2C {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = &protocols
+	}
+	if cfg.APICAFile != "" {
+		certs, err := os.ReadFile(cfg.APICAFile)
+		if err != nil {
+			slog.Error(
+				"Failed to read the API CA certificates",
+				"path",
+				cfg.APICAFile,
+				"error",
+				err,
+			)
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(certs) {
+			return nil, errors.New("api_ca_file holds no PEM certificate: " + cfg.APICAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+	return transport, nil
+}
+
+// newRandomCodec returns a session codec with a random secret, for sessions that do not
+// need to survive a restart.
+func newRandomCodec() *session.Codec {
+	secret := make([]byte, session.MinSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		panic("Error: failed to generate a session secret: " + err.Error())
+	}
+	codec, err := session.NewCodec(secret)
+	if err != nil {
+		panic("Error: " + err.Error())
+	}
+	return codec
+}
+
 func main() {
-	r := setupRouter()
-	err := http.ListenAndServe(":3001", r)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run loads the configuration, serves until a stop signal is received and returns the
+// process exit code.
+func run(args []string) int {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return lifecycle.ExitOK
+	}
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return lifecycle.ExitUsage
+	}
+	if cfg.TemplatesDir != "" {
+		if err := templates.UseDir(cfg.TemplatesDir); err != nil {
+			slog.Error("Failed to load templates", "dir", cfg.TemplatesDir, "error", err)
+			return lifecycle.ExitUsage
+		}
+	}
+	// The pages are loaded by now, they are looked up without locking from here on.
+	templates.Freeze()
+
+	sessions, err := session.New(cfg.Session)
 	if err != nil {
-		slog.Error("Server failed to start", "error", err)
-		panic("Server failed to start")
+		return lifecycle.ExitServeError
 	}
+	users, err := auth.NewUserStore(cfg.Auth)
+	if err != nil {
+		return lifecycle.ExitServeError
+	}
+
+	tracer := tracing.New("web", cfg.Tracing)
+	srv := lifecycle.New(
+		cfg.Addr,
+		setupRouter(cfg, tracer, sessions, users),
+		lifecycle.WithShutdownTimeout(cfg.ShutdownTimeout),
+		lifecycle.WithTLS(cfg.TLS),
+	)
+	srv.OnShutdown("tracing", tracer.Shutdown)
+	slog.Info("Server starting", "addr", cfg.Addr, "tls", cfg.TLS.Enabled())
+	return lifecycle.ExitCode(srv.Run(context.Background()))
 }
@@ -1,12 +1,27 @@
 package main
 
 import (
+	"encoding/json"
+	"encoding/pem"
+	"io"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/client/internal/config"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+	"github.com/supergeoff/go-starter/pkg/middleware"
 )
 
 // TestSetupRouter_WebAppRoutes tests the router setup for web application routes
@@ -15,11 +30,13 @@ import (
 func TestSetupRouter_WebAppRoutes(t *testing.T) {
 	// This test assumes that a setupRouter() function, matching the provided
 	// codeToTest structure, exists in the current 'main' package.
-	r := setupRouter()
+	r := setupRouter(config.Default(), nil, nil, nil)
 	require.NotNil(t, r, "setupRouter() should return a non-nil chi.Mux router")
 
 	var (
 		foundIndexGet      bool
+		foundLivez         bool
+		foundReadyz        bool
 		foundStaticRoute   bool
 		staticRoutePattern = "/static/*"
 	)
@@ -38,6 +55,12 @@ func TestSetupRouter_WebAppRoutes(t *testing.T) {
 			if method == http.MethodGet && route == "/" {
 				foundIndexGet = true
 			}
+			if method == http.MethodGet && route == "/livez" {
+				foundLivez = true
+			}
+			if method == http.MethodGet && route == "/readyz" {
+				foundReadyz = true
+			}
 
 			if route == staticRoutePattern {
 				// This confirms that a handler is registered for the "/static/*" pattern.
@@ -51,5 +74,290 @@ func TestSetupRouter_WebAppRoutes(t *testing.T) {
 	)
 	require.NoError(t, err, "chi.Walk should not return an error during router traversal")
 	assert.True(t, foundIndexGet, "Expected GET / route to be registered")
+	assert.True(t, foundLivez, "Expected GET /livez route to be registered")
+	assert.True(t, foundReadyz, "Expected GET /readyz route to be registered")
 	assert.True(t, foundStaticRoute, "Expected "+staticRoutePattern+" route to be registered")
 }
+
+func TestRun_ConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "help requested", args: []string{"-h"}, expectedCode: lifecycle.ExitOK},
+		{name: "unknown flag", args: []string{"-unknown"}, expectedCode: lifecycle.ExitUsage},
+		{name: "invalid addr", args: []string{"-addr", "nope"}, expectedCode: lifecycle.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCode, run(tt.args), "run returned wrong exit code")
+		})
+	}
+}
+
+func TestSetupRouter_ReadinessReportsAPI(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiStatus      int
+		apiReport      health.Status
+		expectedStatus health.Status
+	}{
+		{
+			name:           "API live",
+			apiStatus:      http.StatusOK,
+			apiReport:      health.StatusPass,
+			expectedStatus: health.StatusPass,
+		},
+		{
+			name:           "API failing",
+			apiStatus:      http.StatusServiceUnavailable,
+			apiReport:      health.StatusFail,
+			expectedStatus: health.StatusWarn,
+		},
+		{
+			name:           "API not answering a report",
+			apiStatus:      http.StatusBadGateway,
+			expectedStatus: health.StatusWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "/livez", r.URL.Path, "readiness should probe the API liveness")
+					w.WriteHeader(tt.apiStatus)
+					if tt.apiReport != "" {
+						_ = json.NewEncoder(w).Encode(health.Report{Status: tt.apiReport})
+					}
+				}),
+			)
+			defer api.Close()
+
+			cfg := config.Default()
+			cfg.APIBaseURL = api.URL
+			rr := httptest.NewRecorder()
+			setupRouter(
+				cfg,
+				nil,
+				nil,
+				nil,
+			).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			// The API is not critical to the web client, so readiness never fails because of it.
+			assert.Equal(t, http.StatusOK, rr.Code, "readiness returned wrong status code")
+			var report health.Report
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report), "decoding report")
+			assert.Equal(t, tt.expectedStatus, report.Status, "readiness status mismatch")
+		})
+	}
+}
+
+func TestSetupRouter_ForwardsRequestID(t *testing.T) {
+	forwarded := make(map[string]string)
+	var mu sync.Mutex
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		forwarded[r.URL.Path] = r.Header.Get(middleware.RequestIDHeader)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status":"pass","checks":[]}`))
+	}))
+	defer api.Close()
+
+	cfg := config.Default()
+	cfg.APIBaseURL = api.URL
+	r := setupRouter(cfg, nil, nil, nil)
+
+	for path, id := range map[string]string{"/": "trace-index", "/readyz": "trace-readyz"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(middleware.RequestIDHeader, id)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, id, rr.Header().Get(middleware.RequestIDHeader),
+			"response to %s should echo the request ID", path)
+	}
+
+	// The index page calls the API readiness probe; the client readiness probe calls the API liveness.
+	assert.Equal(t, map[string]string{"/readyz": "trace-index", "/livez": "trace-readyz"},
+		forwarded, "outbound API calls should carry the inbound request ID")
+}
+
+func TestSetupRouter_APITransport(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  func(*httptest.Server)
+		mutate func(cfg *config.Config, api *httptest.Server)
+	}{
+		{
+			name: "h2c",
+			start: func(api *httptest.Server) {
+				var protocols http.Protocols
+				protocols.SetHTTP1(true)
+				protocols.SetUnencryptedHTTP2(true)
+				api.Config.Protocols = &protocols
+				api.Start()
+			},
+			mutate: func(cfg *config.Config, _ *httptest.Server) { cfg.APIH2C = true },
+		},
+		{
+			name: "TLS with a CA file",
+			start: func(api *httptest.Server) {
+				api.EnableHTTP2 = true
+				api.StartTLS()
+			},
+			mutate: func(cfg *config.Config, api *httptest.Server) {
+				cfg.APICAFile = filepath.Join(t.TempDir(), "ca.pem")
+				ca := pem.EncodeToMemory(
+					&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw},
+				)
+				require.NoError(t, os.WriteFile(cfg.APICAFile, ca, 0o600), "Setup: writing CA file")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				proto string
+			)
+			api := httptest.NewUnstartedServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					proto = r.Proto
+					mu.Unlock()
+					_, _ = w.Write([]byte(`{"status":"pass","checks":[]}`))
+				},
+			))
+			tt.start(api)
+			defer api.Close()
+
+			cfg := config.Default()
+			cfg.APIBaseURL = api.URL
+			tt.mutate(&cfg, api)
+			rr := httptest.NewRecorder()
+			setupRouter(cfg, nil, nil, nil).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code, "the API should be reachable")
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "HTTP/2.0", proto, "the API should be called over HTTP/2")
+		})
+	}
+}
+
+func TestSetupRouter_Metrics(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"pass","checks":[]}`))
+	}))
+	defer api.Close()
+
+	cfg := config.Default()
+	cfg.APIBaseURL = api.URL
+	r := setupRouter(cfg, nil, nil, nil)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+
+	assert.Equal(t, http.StatusOK, rr.Code, "metrics endpoint returned wrong status code")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/",status="200"} 1`,
+		"metrics should count inbound requests by route pattern")
+	assert.Contains(t, body, `http_client_requests_total{target="api",method="GET",status="200"} 1`,
+		"metrics should count outbound API calls")
+	assert.Contains(t, body, "web_api_up 1", "metrics should expose the last observed API health")
+}
+
+func TestSetupRouter_AuthFlow(t *testing.T) {
+	srv := httptest.NewTLSServer(setupRouter(config.Default(), nil, nil, nil))
+	defer srv.Close()
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err, "creating cookie jar")
+	client := srv.Client()
+	client.Jar = jar
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	tokenField := regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+	// get returns the status, redirect location and CSRF token of the page at path.
+	get := func(path string) (int, string, string) {
+		res, err := client.Get(srv.URL + path)
+		require.NoError(t, err, "GET %s", path)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err, "reading %s", path)
+		token := ""
+		if m := tokenField.FindSubmatch(body); m != nil {
+			token = string(m[1])
+		}
+		return res.StatusCode, res.Header.Get("Location"), token
+	}
+	post := func(path string, form url.Values) (int, string) {
+		res, err := client.PostForm(srv.URL+path, form)
+		require.NoError(t, err, "POST %s", path)
+		defer res.Body.Close()
+		return res.StatusCode, res.Header.Get("Location")
+	}
+
+	status, location, _ := get("/account")
+	assert.Equal(t, http.StatusSeeOther, status, "anonymous visitors should be redirected")
+	assert.Equal(t, "/login?next=%2Faccount", location, "redirect should target the login page")
+
+	_, _, token := get("/signup")
+	require.NotEmpty(t, token, "the signup form should carry a CSRF token")
+	credentials := url.Values{"email": {"Alice@Example.com"}, "password": {"correct horse"}}
+	status, _ = post("/signup", credentials)
+	assert.Equal(t, http.StatusForbidden, status, "signup without a CSRF token should be refused")
+
+	credentials.Set("csrf_token", token)
+	status, location = post("/signup", credentials)
+	assert.Equal(t, http.StatusSeeOther, status, "signup should succeed")
+	assert.Equal(t, "/", location, "signup should redirect home")
+
+	status, _, token = get("/account")
+	assert.Equal(t, http.StatusOK, status, "signed-up users should see their account")
+	status, location = post("/logout", url.Values{"csrf_token": {token}})
+	assert.Equal(t, http.StatusSeeOther, status, "logout should succeed")
+	status, _, _ = get("/account")
+	assert.Equal(t, http.StatusSeeOther, status, "logged-out users should be redirected")
+
+	_, _, token = get("/login?next=/account")
+	credentials = url.Values{
+		"email": {"alice@example.com"}, "password": {"wrong password"},
+		"next": {"/account"}, "csrf_token": {token},
+	}
+	status, _ = post("/login", credentials)
+	assert.Equal(t, http.StatusUnauthorized, status, "a wrong password should be refused")
+	credentials.Set("password", "correct horse")
+	status, location = post("/login", credentials)
+	assert.Equal(t, http.StatusSeeOther, status, "login should succeed")
+	assert.Equal(t, "/account", location, "login should redirect to the next page")
+	status, _, _ = get("/account")
+	assert.Equal(t, http.StatusOK, status, "logged-in users should see their account")
+}
+
+func TestSetupRouter_SecurityHeaders(t *testing.T) {
+	r := setupRouter(config.Default(), nil, nil, nil)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "login page returned wrong status code")
+	assert.Contains(t, rr.Header().Get("Content-Security-Policy"), "'nonce-",
+		"pages should carry a nonce-based policy")
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"),
+		"pages should not be sniffed")
+
+	// Browsers send reports without a CSRF token.
+	req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(
+		`{"csp-report": {"document-uri": "http://localhost/", "violated-directive": "img-src"}}`))
+	req.Header.Set("Content-Type", "application/csp-report")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code, "reports should be collected")
+}
@@ -5,6 +5,9 @@ go 1.24.2
 require (
 	github.com/go-chi/chi/v5 v5.2.1
 	github.com/stretchr/testify v1.10.0
+	github.com/supergeoff/go-starter/contract v0.0.0-00010101000000-000000000000
+	github.com/supergeoff/go-starter/pkg v0.0.0-00010101000000-000000000000
+	golang.org/x/crypto v0.39.0
 )
 
 require (
@@ -15,3 +18,8 @@ require (
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/supergeoff/go-starter/contract => ../../contract
+	github.com/supergeoff/go-starter/pkg => ../../pkg
+)
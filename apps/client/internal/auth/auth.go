@@ -0,0 +1,141 @@
+// Package auth implements the accounts of the web client: signup and password login on top
+// of the session package, and a middleware restricting routes to logged-in users.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password length bounds. bcrypt ignores anything past 72 bytes, so longer passwords are
+// refused rather than silently truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// Session keys holding the logged-in user.
+const (
+	sessionUserID    = "user_id"
+	sessionUserEmail = "user_email"
+)
+
+var (
+	// ErrInvalidEmail is returned by Signup for a malformed email.
+	ErrInvalidEmail = errors.New("email address is not valid")
+	// ErrInvalidPassword is returned by Signup for a password that is too short or too long.
+	ErrInvalidPassword = errors.New("password must be between 8 and 72 bytes long")
+	// ErrInvalidCredentials is returned by Authenticate for an unknown email or a wrong
+	// password, which are not told apart.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// Service signs users up and authenticates them against a UserStore.
+type Service struct {
+	users UserStore
+	cost  int
+	now   func() time.Time
+
+	// dummyHash is compared against when the email is unknown, so that the response time
+	// does not reveal which emails are registered.
+	dummyHash func() []byte
+}
+
+// NewService returns a Service storing users in users.
+func NewService(users UserStore) *Service {
+	s := &Service{users: users, cost: bcrypt.DefaultCost, now: time.Now}
+	s.dummyHash = sync.OnceValue(func() []byte {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), s.cost)
+		return hash
+	})
+	return s
+}
+
+// NormalizeEmail trims and lowercases email, as stored and looked up.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Signup creates a user with the given email and password. It returns ErrInvalidEmail,
+// ErrInvalidPassword or ErrEmailTaken when the user cannot be created.
+func (s *Service) Signup(ctx context.Context, email, password string) (User, error) {
+	email = NormalizeEmail(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return User{}, ErrInvalidEmail
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return User{}, ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return User{}, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return User{}, err
+	}
+	u := User{
+		ID:           hex.EncodeToString(id),
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    s.now().UTC(),
+	}
+	if err := s.users.Create(ctx, u); err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+// Authenticate returns the user matching email and password, or ErrInvalidCredentials.
+func (s *Service) Authenticate(ctx context.Context, email, password string) (User, error) {
+	u, err := s.users.ByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash(), []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// Principal identifies the logged-in user of a request.
+type Principal struct {
+	ID    string
+	Email string
+}
+
+// LogIn records u as the user of the session carried by ctx, under a new session ID.
+func LogIn(ctx context.Context, u User) {
+	s := session.FromContext(ctx)
+	s.Renew()
+	s.Set(sessionUserID, u.ID)
+	s.Set(sessionUserEmail, u.Email)
+}
+
+// LogOut destroys the session carried by ctx.
+func LogOut(ctx context.Context) {
+	session.FromContext(ctx).Destroy()
+}
+
+// CurrentUser returns the user logged into the session carried by ctx, if any.
+func CurrentUser(ctx context.Context) (Principal, bool) {
+	s := session.FromContext(ctx)
+	id := s.Get(sessionUserID)
+	if id == "" {
+		return Principal{}, false
+	}
+	return Principal{ID: id, Email: s.Get(sessionUserEmail)}, true
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestService(users UserStore) *Service {
+	s := NewService(users)
+	s.cost = bcrypt.MinCost
+	return s
+}
+
+func TestService_Signup(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		password      string
+		expectedError error
+	}{
+		{name: "valid", email: " Bob@Example.com ", password: "long enough"},
+		{
+			name:          "malformed email",
+			email:         "bob",
+			password:      "long enough",
+			expectedError: ErrInvalidEmail,
+		},
+		{
+			name:          "display name",
+			email:         "Bob <bob@example.com>",
+			password:      "long enough",
+			expectedError: ErrInvalidEmail,
+		},
+		{
+			name:          "short password",
+			email:         "bob@example.com",
+			password:      "short",
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:          "long password",
+			email:         "bob@example.com",
+			password:      strings.Repeat("x", MaxPasswordLength+1),
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:          "taken email",
+			email:         "alice@example.com",
+			password:      "long enough",
+			expectedError: ErrEmailTaken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(NewMemoryUserStore())
+			_, err := svc.Signup(context.Background(), "alice@example.com", "long enough")
+			require.NoError(t, err, "seeding a user should not fail")
+
+			u, err := svc.Signup(context.Background(), tt.email, tt.password)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError, "Signup error mismatch")
+				return
+			}
+			require.NoError(t, err, "Signup should succeed")
+			assert.Equal(t, "bob@example.com", u.Email, "email should be normalized")
+			assert.NotEmpty(t, u.ID, "user should get an ID")
+			assert.NotContains(t, string(u.PasswordHash), tt.password, "password should be hashed")
+		})
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	svc := newTestService(NewMemoryUserStore())
+	created, err := svc.Signup(context.Background(), "alice@example.com", "long enough")
+	require.NoError(t, err, "Signup should not fail")
+
+	u, err := svc.Authenticate(context.Background(), "ALICE@example.com", "long enough")
+	require.NoError(t, err, "Authenticate should accept the right password")
+	assert.Equal(t, created.ID, u.ID, "Authenticate returned the wrong user")
+
+	_, err = svc.Authenticate(context.Background(), "alice@example.com", "wrong password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "a wrong password should be refused")
+	_, err = svc.Authenticate(context.Background(), "bob@example.com", "long enough")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "an unknown email should be refused")
+}
+
+func TestFileUserStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "users.json")
+	store, err := NewFileUserStore(path)
+	require.NoError(t, err, "NewFileUserStore should accept a missing file")
+	svc := newTestService(store)
+	_, err = svc.Signup(context.Background(), "alice@example.com", "long enough")
+	require.NoError(t, err, "Signup should not fail")
+
+	reopened, err := NewFileUserStore(path)
+	require.NoError(t, err, "NewFileUserStore should load the saved users")
+	svc = newTestService(reopened)
+	_, err = svc.Authenticate(context.Background(), "alice@example.com", "long enough")
+	assert.NoError(t, err, "users should survive reopening the store")
+	assert.ErrorIs(t, reopened.Create(context.Background(), User{Email: "alice@example.com"}),
+		ErrEmailTaken, "reopened store should know the existing emails")
+}
+
+func TestRequireAuth(t *testing.T) {
+	codec, err := session.NewCodec([]byte(strings.Repeat("s", session.MinSecretLength)))
+	require.NoError(t, err, "NewCodec should not fail")
+	m := session.NewManager(session.NewMemoryStore(), codec)
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			LogIn(r.Context(), User{ID: "u1", Email: "alice@example.com"})
+			return
+		}
+		RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := CurrentUser(r.Context())
+			_, _ = w.Write([]byte(user.Email))
+		})).ServeHTTP(w, r)
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/account?tab=1", nil))
+	assert.Equal(t, http.StatusSeeOther, rr.Code, "anonymous GET should be redirected")
+	assert.Equal(t, "/login?next=%2Faccount%3Ftab%3D1", rr.Header().Get("Location"),
+		"redirect should carry the requested page")
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/account", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "anonymous POST should be refused")
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/login", nil))
+	req := httptest.NewRequest(http.MethodGet, "/account", nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "logged-in users should be let through")
+	assert.Equal(t, "alice@example.com", rr.Body.String(), "CurrentUser mismatch")
+}
+
+func TestRedirectTarget(t *testing.T) {
+	tests := map[string]string{
+		"":                      "/",
+		"/account?tab=1":        "/account?tab=1",
+		"https://evil.example":  "/",
+		"//evil.example/path":   "/",
+		"/\\evil.example":       "/",
+		"account":               "/",
+		"javascript:alert(1)//": "/",
+	}
+	for next, expected := range tests {
+		assert.Equal(t, expected, RedirectTarget(next), "RedirectTarget(%q) mismatch", next)
+	}
+}
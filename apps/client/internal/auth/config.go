@@ -0,0 +1,24 @@
+package auth
+
+// Config selects where users are stored. It is meant to be embedded in the configuration of
+// the web client.
+type Config struct {
+	UsersFile string `config:"users_file" usage:"JSON file storing the users, empty to keep them in memory"`
+}
+
+// DefaultConfig returns a configuration persisting users next to the binary's data.
+func DefaultConfig() Config {
+	return Config{UsersFile: "data/users.json"}
+}
+
+// NewUserStore returns the UserStore selected by cfg.
+func NewUserStore(cfg Config) (UserStore, error) {
+	if cfg.UsersFile == "" {
+		return NewMemoryUserStore(), nil
+	}
+	s, err := NewFileUserStore(cfg.UsersFile)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginPath is the page RequireAuth sends anonymous visitors to.
+const LoginPath = "/login"
+
+// RequireAuth lets requests from logged-in users through. Anonymous GET and HEAD requests
+// are redirected to the login page, which sends them back once logged in; other anonymous
+// requests get 401 Unauthorized. It must be installed after session.Manager.Middleware.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CurrentUser(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		target := LoginPath + "?" + url.Values{"next": {r.URL.RequestURI()}}.Encode()
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	})
+}
+
+// RedirectTarget returns next if it is a path on this site, and "/" otherwise, so that
+// the next parameter of the login page cannot redirect to another site.
+func RedirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") ||
+		strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	return next
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUserNotFound is returned when no user has the requested email.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailTaken is returned when creating a user with an email already in use.
+	ErrEmailTaken = errors.New("email already registered")
+)
+
+// User is an account of the web client.
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash []byte    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserStore persists users. Implementations must be safe for concurrent use and must
+// treat emails as already normalized.
+type UserStore interface {
+	// Create stores a new user, or returns ErrEmailTaken.
+	Create(ctx context.Context, u User) error
+	// ByEmail returns the user with the given email, or ErrUserNotFound.
+	ByEmail(ctx context.Context, email string) (User, error)
+}
+
+// MemoryUserStore keeps users in process memory, which suits tests and demos.
+type MemoryUserStore struct {
+	mu      sync.RWMutex
+	byEmail map[string]User
+}
+
+// NewMemoryUserStore returns an empty MemoryUserStore.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{byEmail: make(map[string]User)}
+}
+
+// Create implements UserStore.
+func (s *MemoryUserStore) Create(_ context.Context, u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byEmail[u.Email]; ok {
+		return ErrEmailTaken
+	}
+	s.byEmail[u.Email] = u
+	return nil
+}
+
+// ByEmail implements UserStore.
+func (s *MemoryUserStore) ByEmail(_ context.Context, email string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.byEmail[email]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// FileUserStore keeps users in memory and persists them to a JSON file, rewritten
+// atomically on every change. It suits a single instance with few users.
+type FileUserStore struct {
+	mem  *MemoryUserStore
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileUserStore returns a FileUserStore persisting to path, loading the users it
+// already holds. A missing file is created on the first signup.
+func NewFileUserStore(path string) (*FileUserStore, error) {
+	s := &FileUserStore{mem: NewMemoryUserStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read users file", "path", path, "error", err)
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		slog.Error("Failed to decode users file", "path", path, "error", err)
+		return nil, err
+	}
+	for _, u := range users {
+		s.mem.byEmail[u.Email] = u
+	}
+	return s, nil
+}
+
+// Create implements UserStore.
+func (s *FileUserStore) Create(ctx context.Context, u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.mem.Create(ctx, u); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.mem.mu.Lock()
+		delete(s.mem.byEmail, u.Email)
+		s.mem.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ByEmail implements UserStore.
+func (s *FileUserStore) ByEmail(ctx context.Context, email string) (User, error) {
+	return s.mem.ByEmail(ctx, email)
+}
+
+// save writes every user to the file, through a temporary file renamed over it.
+func (s *FileUserStore) save() error {
+	s.mem.mu.RLock()
+	users := make([]User, 0, len(s.mem.byEmail))
+	for _, u := range s.mem.byEmail {
+		users = append(users, u)
+	}
+	s.mem.mu.RUnlock()
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), ".users-*")
+	if err != nil {
+		return err
+	}
+	// Removing fails once the file is renamed, which is expected.
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
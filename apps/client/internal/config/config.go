@@ -0,0 +1,93 @@
+// Package config defines the runtime configuration of the web client.
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/client/internal/auth"
+	"github.com/supergeoff/go-starter/apps/client/internal/security"
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+	pkgconfig "github.com/supergeoff/go-starter/pkg/config"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+	"github.com/supergeoff/go-starter/pkg/tracing"
+)
+
+// EnvPrefix is the prefix of every environment variable read by the web client, e.g. WEB_ADDR.
+const EnvPrefix = "WEB"
+
+// Config holds the runtime configuration of the web client.
+type Config struct {
+	Addr            string              `config:"addr"             usage:"address the HTTP server listens on"`
+	APIBaseURL      string              `config:"api_base_url"     usage:"base URL of the API server"`
+	APIToken        string              `config:"api_token"        usage:"credentials sent to the API server"`
+	APICAFile       string              `config:"api_ca_file"      usage:"CA certificates trusted for the API"`
+	APIH2C          bool                `config:"api_h2c"          usage:"HTTP/2 without TLS to an http API"`
+	AssetsDir       string              `config:"assets_dir"       usage:"directory served under /static/"`
+	TemplatesDir    string              `config:"templates_dir"    usage:"templates read from disk and reloaded, for development"`
+	ShutdownTimeout time.Duration       `config:"shutdown_timeout" usage:"drain deadline on shutdown"`
+	Tracing         tracing.Config      `config:"tracing"`
+	Session         session.Config      `config:"session"`
+	Auth            auth.Config         `config:"auth"`
+	Security        security.Config     `config:"security"`
+	TLS             lifecycle.TLSConfig `config:"tls"`
+}
+
+// Default returns the configuration used when no other source overrides a setting.
+func Default() Config {
+	return Config{
+		Addr:            ":3001",
+		APIBaseURL:      "http://localhost:3000",
+		AssetsDir:       "build/assets",
+		ShutdownTimeout: 15 * time.Second,
+		Tracing:         tracing.DefaultConfig(),
+		Session:         session.DefaultConfig(),
+		Auth:            auth.DefaultConfig(),
+		Security:        security.DefaultConfig(),
+		TLS:             lifecycle.DefaultTLSConfig(),
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return errors.New("addr must be a host:port address, got: " + c.Addr)
+	}
+	u, err := url.Parse(c.APIBaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("api_base_url must be an absolute http(s) URL, got: " + c.APIBaseURL)
+	}
+	if c.APIH2C && u.Scheme != "http" {
+		return errors.New("api_h2c requires an http api_base_url, HTTPS negotiates HTTP/2")
+	}
+	if c.AssetsDir == "" {
+		return errors.New("assets_dir must not be empty")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("shutdown_timeout must be positive")
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+	if err := c.Session.Validate(); err != nil {
+		return err
+	}
+	if err := c.Security.Validate(); err != nil {
+		return err
+	}
+	return c.TLS.Validate()
+}
+
+// Load resolves the configuration from defaults, the config file, WEB_* environment
+// variables and the given command-line arguments, in that order of precedence.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+	err := pkgconfig.Load(&cfg, pkgconfig.Options{
+		Name:      "web",
+		EnvPrefix: EnvPrefix,
+		Args:      args,
+	})
+	return cfg, err
+}
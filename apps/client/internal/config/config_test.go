@@ -0,0 +1,98 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		mutate        func(c *Config)
+		containsError string
+	}{
+		{
+			name:   "defaults are valid",
+			mutate: func(c *Config) {},
+		},
+		{
+			name:          "addr without port",
+			mutate:        func(c *Config) { c.Addr = "localhost" },
+			containsError: "addr must be a host:port address",
+		},
+		{
+			name:          "relative API base URL",
+			mutate:        func(c *Config) { c.APIBaseURL = "/api" },
+			containsError: "api_base_url must be an absolute http(s) URL",
+		},
+		{
+			name:          "unsupported API scheme",
+			mutate:        func(c *Config) { c.APIBaseURL = "ftp://localhost:3000" },
+			containsError: "api_base_url must be an absolute http(s) URL",
+		},
+		{
+			name:          "empty assets directory",
+			mutate:        func(c *Config) { c.AssetsDir = "" },
+			containsError: "assets_dir must not be empty",
+		},
+		{
+			name:          "short session secret",
+			mutate:        func(c *Config) { c.Session.Secrets = []string{"secret"} },
+			containsError: "session.secrets must be at least 32 bytes long",
+		},
+		{
+			name:          "negative HSTS max age",
+			mutate:        func(c *Config) { c.Security.HSTSMaxAge = -1 },
+			containsError: "security.hsts_max_age must not be negative",
+		},
+		{
+			name: "h2c with an HTTPS API",
+			mutate: func(c *Config) {
+				c.APIBaseURL = "https://localhost:3000"
+				c.APIH2C = true
+			},
+			containsError: "api_h2c requires an http api_base_url",
+		},
+		{
+			name:          "certificate without key",
+			mutate:        func(c *Config) { c.TLS.CertFile = "cert.pem" },
+			containsError: "tls.cert_file and tls.key_file must be set together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if tt.containsError == "" {
+				assert.NoError(t, err, "Validate should accept the configuration")
+				return
+			}
+			assert.ErrorContains(t, err, tt.containsError, "Validate error mismatch")
+		})
+	}
+}
+
+func TestLoad_FlagsOverrideDefaults(t *testing.T) {
+	t.Setenv(EnvPrefix+"_ADDR", ":4001")
+
+	cfg, err := Load([]string{"-api-base-url", "https://api.example.com"})
+	assert.NoError(t, err, "Load should not fail")
+	assert.Equal(t, ":4001", cfg.Addr, "Addr should come from the environment")
+	assert.Equal(t, "https://api.example.com", cfg.APIBaseURL, "APIBaseURL should come from flags")
+	assert.Equal(t, Default().AssetsDir, cfg.AssetsDir, "AssetsDir should keep its default")
+	assert.Empty(t, cfg.TemplatesDir, "templates should be embedded by default")
+}
+
+func TestLoad_SessionSecretsFromEnvironment(t *testing.T) {
+	current, previous := strings.Repeat("a", 32), strings.Repeat("b", 32)
+	t.Setenv(EnvPrefix+"_SESSION_SECRETS", current+","+previous)
+
+	cfg, err := Load(nil)
+	assert.NoError(t, err, "Load should not fail")
+	assert.Equal(t, []string{current, previous}, cfg.Session.Secrets,
+		"Session.Secrets should be split from the environment")
+}
@@ -0,0 +1,209 @@
+// Package csrf protects the web client against cross-site request forgery with the
+// synchronizer token pattern. The token lives in the session, unsafe requests must echo it,
+// and HTML forms posting back to the site get it injected automatically.
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+const (
+	// FieldName is the form field carrying the token.
+	FieldName = "csrf_token"
+	// HeaderName is the request header carrying the token, for scripts.
+	HeaderName = "X-CSRF-Token"
+	// sessionKey is the session value holding the token.
+	sessionKey = "csrf_token"
+)
+
+type contextKey struct{}
+
+// postForm matches the opening tag of a form submitted with the POST method.
+var postForm = regexp.MustCompile(`(?i)<form\b[^>]*\bmethod\s*=\s*["']?post\b[^>]*>`)
+
+// Token returns the CSRF token of the request carried by ctx, or "" outside of Protect.
+func Token(ctx context.Context) string {
+	token, _ := ctx.Value(contextKey{}).(string)
+	return token
+}
+
+// Field returns a hidden input carrying the CSRF token of the request carried by ctx, for
+// forms built outside of HTML responses, which Protect does not rewrite.
+func Field(ctx context.Context) template.HTML {
+	return template.HTML(hiddenField(Token(ctx)))
+}
+
+// hiddenField returns the hidden input carrying token.
+func hiddenField(token string) string {
+	return `<input type="hidden" name="` + FieldName + `" value="` +
+		template.HTMLEscapeString(token) + `">`
+}
+
+// Protect rejects unsafe requests (any method but GET, HEAD, OPTIONS and TRACE) that do
+// not carry the session token in the csrf_token form field or the X-CSRF-Token header,
+// with 403 Forbidden. It injects the token into every POST form of HTML responses. It must
+// be installed after session.Manager.Middleware.
+func Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.FromContext(r.Context())
+		if s == nil {
+			panic("Error: csrf.Protect must be installed after the session middleware")
+		}
+		token := s.Get(sessionKey)
+		if token == "" {
+			var err error
+			token, err = newToken()
+			if err != nil {
+				middleware.LoggerFromContext(r.Context()).
+					Error("Failed to generate CSRF token", "error", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError),
+					http.StatusInternalServerError)
+				return
+			}
+			s.Set(sessionKey, token)
+		}
+
+		if !safeMethod(r.Method) && !validToken(r, token) {
+			middleware.LoggerFromContext(r.Context()).
+				Warn("Rejected request without a valid CSRF token", "method", r.Method)
+			http.Error(w, "Forbidden - invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		iw := &injectWriter{ResponseWriter: w, token: token}
+		next.ServeHTTP(iw, r.WithContext(context.WithValue(r.Context(), contextKey{}, token)))
+		iw.finish()
+	})
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// validToken reports whether r carries token in its header or form.
+func validToken(r *http.Request, token string) bool {
+	sent := r.Header.Get(HeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(FieldName)
+	}
+	return sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// injectWriter buffers HTML responses to add the token to their POST forms. Other
+// responses are passed through untouched, so streaming keeps working.
+type injectWriter struct {
+	http.ResponseWriter
+	token   string
+	status  int
+	decided bool
+	inject  bool
+	buf     bytes.Buffer
+}
+
+// decide chooses between buffering and passing through, from the response content type.
+func (w *injectWriter) decide(firstChunk []byte) {
+	w.decided = true
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" && w.Header().Get("Content-Encoding") == "" && firstChunk != nil {
+		contentType = http.DetectContentType(firstChunk)
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.inject = strings.HasPrefix(contentType, "text/html") &&
+		w.Header().Get("Content-Encoding") == ""
+}
+
+// WriteHeader records the status of HTML responses, and forwards any other.
+func (w *injectWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if !w.decided && w.Header().Get("Content-Type") != "" {
+		w.decide(nil)
+	}
+	if w.decided && !w.inject {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Write buffers the body of HTML responses, and forwards any other.
+func (w *injectWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decide(b)
+		if !w.inject && w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	if w.inject {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes responses that are passed through. Buffered HTML is sent by finish.
+func (w *injectWriter) Flush() {
+	if !w.decided {
+		w.decide(nil)
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	if w.inject {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *injectWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish sends a buffered HTML response with the token added to its POST forms.
+func (w *injectWriter) finish() {
+	if !w.decided {
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		return
+	}
+	if !w.inject {
+		return
+	}
+	field := hiddenField(w.token)
+	body := postForm.ReplaceAllFunc(w.buf.Bytes(), func(tag []byte) []byte {
+		// tag aliases the buffer, so it must not be appended to in place.
+		return append(append(make([]byte, 0, len(tag)+len(field)), tag...), field...)
+	})
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,106 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/client/internal/session"
+)
+
+const page = `<!DOCTYPE html><html><body>
+<form method="post" action="/login"><button>Log in</button></form>
+<form method="get" action="/search"></form>
+<FORM class="x" METHOD=POST></FORM>
+</body></html>`
+
+// newTestHandler returns page behind the session and CSRF middlewares, answering unsafe
+// requests with "ok".
+func newTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	codec, err := session.NewCodec([]byte(strings.Repeat("s", session.MinSecretLength)))
+	require.NoError(t, err, "NewCodec should not fail")
+	m := session.NewManager(session.NewMemoryStore(), codec)
+	return m.Middleware(Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`<form method="post">`))
+		default:
+			if r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(page))
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		}
+	})))
+}
+
+var tokenField = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="([^"]+)">`)
+
+func TestProtect(t *testing.T) {
+	h := newTestHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code, "GET should be allowed without a token")
+	fields := tokenField.FindAllStringSubmatch(rr.Body.String(), -1)
+	require.Len(t, fields, 2, "the token should be injected into both POST forms only")
+	token := fields[0][1]
+	assert.Contains(t, rr.Body.String(), `action="/login">`+fields[0][0],
+		"the token should follow the form opening tag")
+	assert.Equal(t, strconv.Itoa(rr.Body.Len()), rr.Header().Get("Content-Length"),
+		"content length should match the rewritten body")
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"),
+		"content type should be sniffed before rewriting")
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1, "the token should be stored in the session")
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		header         string
+		expectedStatus int
+	}{
+		{name: "no token", expectedStatus: http.StatusForbidden},
+		{
+			name:           "wrong token",
+			form:           url.Values{FieldName: {"nope"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "form token",
+			form:           url.Values{FieldName: {token}},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "header token", header: token, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				req.Header.Set(HeaderName, tt.header)
+			}
+			req.AddCookie(cookies[0])
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code, "unexpected status code")
+		})
+	}
+}
+
+func TestProtect_PassesThroughOtherContent(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestHandler(t).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	assert.Equal(t, `<form method="post">`, rr.Body.String(),
+		"non-HTML responses should not be rewritten")
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/supergeoff/go-starter/apps/client/internal/pages"
 )
 
-func IndexHandler(w http.ResponseWriter, r *http.Request) {
-	pages.Home(w, r)
+// NewIndexHandler returns the handler serving the index page.
+func NewIndexHandler(p *pages.Handler) http.HandlerFunc {
+	return p.Home
 }
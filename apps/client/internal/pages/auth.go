@@ -0,0 +1,130 @@
+package pages
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/supergeoff/go-starter/apps/client/internal/auth"
+	"github.com/supergeoff/go-starter/apps/client/templates"
+	"github.com/supergeoff/go-starter/apps/client/templates/components"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// AuthHandler serves the signup, login, logout and account pages.
+type AuthHandler struct {
+	auth *auth.Service
+}
+
+// NewAuthHandler returns an AuthHandler managing accounts through svc.
+func NewAuthHandler(svc *auth.Service) *AuthHandler {
+	return &AuthHandler{auth: svc}
+}
+
+// LoginForm renders the login page, or redirects users who are already logged in.
+func (h *AuthHandler) LoginForm(w http.ResponseWriter, r *http.Request) {
+	next := auth.RedirectTarget(r.URL.Query().Get("next"))
+	if _, ok := auth.CurrentUser(r.Context()); ok {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	templates.Login(loginPageData("", next, "")).Respond(w, r, http.StatusOK)
+}
+
+// Login logs the user in and sends them back to the page they came from.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	email, next := r.PostFormValue("email"), auth.RedirectTarget(r.PostFormValue("next"))
+	u, err := h.auth.Authenticate(r.Context(), email, r.PostFormValue("password"))
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		templates.Login(loginPageData(email, next, "Invalid email or password.")).
+			Respond(w, r, http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		middleware.LoggerFromContext(r.Context()).Error("Failed to authenticate", "error", err)
+		templates.Login(loginPageData(email, next, "Something went wrong, please retry.")).
+			Respond(w, r, http.StatusInternalServerError)
+		return
+	}
+	auth.LogIn(r.Context(), u)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// SignupForm renders the signup page.
+func (h *AuthHandler) SignupForm(w http.ResponseWriter, r *http.Request) {
+	templates.Signup(signupPageData("", "")).Respond(w, r, http.StatusOK)
+}
+
+// Signup creates an account, logs it in and redirects to the home page.
+func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
+	email := r.PostFormValue("email")
+	u, err := h.auth.Signup(r.Context(), email, r.PostFormValue("password"))
+	if err != nil {
+		status, message := http.StatusUnprocessableEntity, ""
+		switch {
+		case errors.Is(err, auth.ErrInvalidEmail):
+			message = "Enter a valid email address."
+		case errors.Is(err, auth.ErrInvalidPassword):
+			message = "Passwords must be between 8 and 72 characters long."
+		case errors.Is(err, auth.ErrEmailTaken):
+			status, message = http.StatusConflict, "This email is already registered."
+		default:
+			middleware.LoggerFromContext(r.Context()).Error("Failed to sign up", "error", err)
+			status, message = http.StatusInternalServerError, "Something went wrong, please retry."
+		}
+		templates.Signup(signupPageData(email, message)).Respond(w, r, status)
+		return
+	}
+	auth.LogIn(r.Context(), u)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Logout ends the session and redirects to the home page.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	auth.LogOut(r.Context())
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Account renders the account page of the logged-in user. It must be mounted behind
+// auth.RequireAuth.
+func (h *AuthHandler) Account(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.CurrentUser(r.Context())
+	templates.Account(templates.AccountPageData{
+		Email:      user.Email,
+		ButtonData: components.ButtonProps{Variant: "outline", Text: "Log out"},
+	}).Respond(w, r, http.StatusOK)
+}
+
+func loginPageData(email, next, errMessage string) templates.LoginPageData {
+	return templates.LoginPageData{
+		Fields: []components.FormFieldProps{
+			{
+				Label: "Email", Name: "email", Type: "email", Value: email,
+				Autocomplete: "email", Required: true,
+			},
+			{
+				Label: "Password", Name: "password", Type: "password",
+				Autocomplete: "current-password", Required: true,
+			},
+		},
+		ButtonData: components.ButtonProps{Text: "Log in", Type: "submit"},
+		Next:       next,
+		Error:      errMessage,
+	}
+}
+
+func signupPageData(email, errMessage string) templates.SignupPageData {
+	return templates.SignupPageData{
+		Fields: []components.FormFieldProps{
+			{
+				Label: "Email", Name: "email", Type: "email", Value: email,
+				Autocomplete: "email", Required: true,
+			},
+			{
+				Label: "Password", Name: "password", Type: "password",
+				Autocomplete: "new-password", Required: true,
+			},
+		},
+		ButtonData: components.ButtonProps{Text: "Sign up", Type: "submit"},
+		Error:      errMessage,
+	}
+}
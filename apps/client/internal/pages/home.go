@@ -1,56 +1,182 @@
 package pages
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/supergeoff/go-starter/apps/client/templates"
 	"github.com/supergeoff/go-starter/apps/client/templates/components" // Import components for ButtonProps
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/metrics"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+	"github.com/supergeoff/go-starter/pkg/sse"
 )
 
-func Home(w http.ResponseWriter, r *http.Request) {
-	var apiResponse struct {
-		Message string `json:"message"`
+// HealthEventsPath is the path of the stream of the status button of the home page.
+const HealthEventsPath = "/health/events"
+
+// Delays before following the health events of the API again after a failure, doubled
+// on each attempt.
+const (
+	minHealthRetry = time.Second
+	maxHealthRetry = 30 * time.Second
+)
+
+// Handler serves the application pages.
+// It holds the dependencies the pages need to talk to the API server.
+type Handler struct {
+	api    *contract.Client
+	events *sse.Broker
+
+	apiUp        *metrics.Gauge
+	apiCheckedAt *metrics.Gauge
+}
+
+// NewHandler returns a Handler that queries the API server through api, and records the
+// API health it observes in reg. If reg is nil, metrics are not exposed.
+func NewHandler(api *contract.Client, reg *metrics.Registry) *Handler {
+	if reg == nil {
+		reg = metrics.NewRegistry()
 	}
+	h := &Handler{
+		api: api,
+		apiUp: reg.NewGauge(
+			"web_api_up",
+			"Whether the last observed API readiness report was passing or degraded (1) or not (0).",
+		),
+		apiCheckedAt: reg.NewGauge("web_api_last_check_timestamp_seconds",
+			"Unix time of the last observed API readiness report."),
+	}
+	h.events = sse.NewBroker(sse.WithSource(h.followAPIHealth))
+	return h
+}
 
+// Home renders the home page with the readiness report of the API.
+func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	var pageData templates.HomePageData
+	logger := middleware.LoggerFromContext(r.Context())
 
-	resp, err := http.Get("http://localhost:3000/api")
+	report, err := h.api.Readiness(r.Context())
 	if err != nil {
-		slog.Error("Error making GET request", "error", err)
-		apiResponse.Message = "down"
-	} else {
-		defer func() {
-			err := resp.Body.Close()
-			if err != nil {
-				slog.Error("Failed to close response body", "error", err)
-			}
-		}()
-		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-			slog.Error("Failed to decode JSON", "error", err)
-		}
+		logger.Error("Failed to fetch API readiness", "error", err)
+	}
+	h.observeAPIHealth(report.Status)
+
+	pageData.ButtonData = statusButton(report.Status)
+	pageData.HealthEventsURL = HealthEventsPath
+	for _, check := range report.Checks {
+		pageData.Checks = append(pageData.Checks, components.HealthCheckProps{
+			Name:      check.Name,
+			Status:    string(check.Status),
+			Critical:  check.Critical,
+			LatencyMS: check.LatencyMS,
+			Error:     check.Error,
+		})
 	}
 
+	templates.Home(pageData).Respond(w, r, http.StatusOK)
+}
+
+// statusButton returns the button showing the overall API status.
+func statusButton(status health.Status) components.ButtonProps {
 	// Initialize ButtonData with default values
 	buttonProps := components.ButtonProps{
 		Variant: "default", // Set a default variant
 		Size:    "default", // Set a default size
 	}
 
-	// Logic for button based on API response
-	if apiResponse.Message == "check" {
+	// Logic for button based on the overall API status
+	switch status {
+	case health.StatusPass:
 		buttonProps.Text = "OK"
 		buttonProps.Variant = "success"
-	} else {
+	case health.StatusWarn:
+		buttonProps.Text = "Degraded"
+		buttonProps.Variant = "secondary"
+	default:
 		buttonProps.Text = "Down"
 		buttonProps.Variant = "destructive"
 	}
+	return buttonProps
+}
+
+// HealthEvents streams the status button of the home page, rendered again each time the
+// API status changes, as "health" events. Browsers resume after the last event they
+// received when they reconnect.
+func (h *Handler) HealthEvents(w http.ResponseWriter, r *http.Request) {
+	h.events.ServeHTTP(w, r)
+}
+
+// followAPIHealth publishes the status button to the health events each time the API
+// status changes, while browsers follow them. It follows the health events of the API,
+// resuming after the last one received when their stream ends, and shows the API as down
+// while it cannot reach them.
+func (h *Handler) followAPIHealth(ctx context.Context, b *sse.Broker) {
+	var (
+		lastID    string
+		published health.Status
+		retry     = minHealthRetry
+	)
+	publish := func(status health.Status) {
+		h.observeAPIHealth(status)
+		if status == published {
+			return
+		}
+		var buf bytes.Buffer
+		if err := templates.HealthStatus(statusButton(status)).RenderContext(ctx, &buf); err != nil {
+			slog.Error("Error rendering template", "error", err)
+			return
+		}
+		b.Publish(contract.EventHealth, buf.String())
+		published = status
+	}
+
+	for {
+		stream, err := h.api.HealthEvents(ctx, lastID)
+		if err == nil {
+			retry = minHealthRetry
+			for {
+				event, err := stream.Next()
+				if err != nil {
+					break
+				}
+				lastID = event.ID
+				publish(event.Report.Status)
+			}
+			if d := stream.Retry(); d > 0 {
+				retry = d
+			}
+			_ = stream.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("Failed to follow API health events", "error", err, "retry", retry)
+			publish(health.StatusFail)
+		}
 
-	pageData.ButtonData = buttonProps // Assign the prepared buttonProps
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		if err != nil {
+			retry = min(2*retry, maxHealthRetry)
+		}
+	}
+}
 
-	err = templates.Home(pageData).Render(w)
-	if err != nil {
-		slog.Error("Error rendering template", "error", err)
+// observeAPIHealth records the last observed API status in the metrics.
+func (h *Handler) observeAPIHealth(status health.Status) {
+	up := 0.0
+	if status == health.StatusPass || status == health.StatusWarn {
+		up = 1
 	}
+	h.apiUp.Set(up)
+	h.apiCheckedAt.SetToCurrentTime()
 }
@@ -6,16 +6,18 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/metrics"
+	"github.com/supergeoff/go-starter/pkg/sse"
 )
 
-// Mock Response struct to match the one in home.go
-type MockResponse struct {
-	Message string `json:"message"`
-}
-
 // mockRoundTripper allows controlling HTTP responses for testing.
 type mockRoundTripper struct {
 	RoundTripFunc func(*http.Request) (*http.Response, error)
@@ -29,6 +31,26 @@ func (mrt *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 }
 
 func TestHome(t *testing.T) {
+	readyReport := health.Report{
+		Status: health.StatusPass,
+		Checks: []health.Result{
+			{Name: "database", Status: health.StatusPass, Critical: true, LatencyMS: 1.25},
+		},
+	}
+	degradedReport := health.Report{
+		Status: health.StatusWarn,
+		Checks: []health.Result{
+			{Name: "database", Status: health.StatusPass, Critical: true},
+			{Name: "cache", Status: health.StatusFail, Error: "cache unreachable"},
+		},
+	}
+	downReport := health.Report{
+		Status: health.StatusFail,
+		Checks: []health.Result{
+			{Name: "database", Status: health.StatusFail, Critical: true, Error: "db down"},
+		},
+	}
+
 	tests := []struct {
 		name                string
 		apiStatusCode       int
@@ -37,24 +59,40 @@ func TestHome(t *testing.T) {
 		expectedStatus      int
 		expectedButtonClass string
 		expectedButtonText  string
+		expectedContent     []string // Per-check fragments expected in the page
+		expectedAPIUp       float64  // Expected value of the web_api_up gauge
 	}{
 		{
-			name:                "successful API call - check message",
+			name:                "API ready",
 			apiStatusCode:       http.StatusOK,
-			apiResponseBody:     MockResponse{Message: "check"},
+			apiResponseBody:     readyReport,
 			apiError:            nil,
 			expectedStatus:      http.StatusOK,
 			expectedButtonClass: "bg-green-500",
 			expectedButtonText:  "OK",
+			expectedAPIUp:       1,
+			expectedContent:     []string{"database", "(critical)", "1.2 ms"},
 		},
 		{
-			name:                "successful API call - other message",
+			name:                "API degraded",
 			apiStatusCode:       http.StatusOK,
-			apiResponseBody:     MockResponse{Message: "hello"},
+			apiResponseBody:     degradedReport,
 			apiError:            nil,
 			expectedStatus:      http.StatusOK,
+			expectedButtonClass: "bg-secondary",
+			expectedButtonText:  "Degraded",
+			expectedAPIUp:       1,
+			expectedContent:     []string{"cache", "cache unreachable", "bg-yellow-500"},
+		},
+		{
+			name:                "API not ready",
+			apiStatusCode:       http.StatusServiceUnavailable,
+			apiResponseBody:     downReport,
+			apiError:            nil,
+			expectedStatus:      http.StatusOK, // The page still renders when the API is down
 			expectedButtonClass: "bg-red-600",
 			expectedButtonText:  "Down",
+			expectedContent:     []string{"database", "db down"},
 		},
 		{
 			name:                "API call failed",
@@ -65,10 +103,19 @@ func TestHome(t *testing.T) {
 			expectedButtonClass: "bg-red-600",
 			expectedButtonText:  "Down",
 		},
+		{
+			name:                "unexpected status code",
+			apiStatusCode:       http.StatusInternalServerError,
+			apiResponseBody:     "internal error",
+			apiError:            nil,
+			expectedStatus:      http.StatusOK,
+			expectedButtonClass: "bg-red-600",
+			expectedButtonText:  "Down",
+		},
 		{
 			name:                "JSON decoding failed",
 			apiStatusCode:       http.StatusOK,
-			apiResponseBody:     `{"message": 123}`, // Invalid JSON for the struct
+			apiResponseBody:     `{"status": 123}`, // Invalid JSON for the struct
 			apiError:            nil,
 			expectedStatus:      http.StatusOK, // Home function still renders template on JSON error
 			expectedButtonClass: "bg-red-600",
@@ -79,9 +126,10 @@ func TestHome(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Mock the HTTP client's RoundTripper
-			originalTransport := http.DefaultClient.Transport
-			http.DefaultClient.Transport = &mockRoundTripper{
+			var requestedURL string
+			client := &http.Client{Transport: &mockRoundTripper{
 				RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+					requestedURL = req.URL.String()
 					if tt.apiError != nil {
 						return nil, tt.apiError
 					}
@@ -106,17 +154,24 @@ func TestHome(t *testing.T) {
 
 					return recorder.Result(), nil
 				},
-			}
-			defer func() { http.DefaultClient.Transport = originalTransport }() // Restore original transport
+			}}
 
 			// Create a ResponseRecorder and a dummy Request
 			rr := httptest.NewRecorder()
 			req := httptest.NewRequest("GET", "/", nil)
 
-			// Call the Home function
-			Home(rr, req)
+			// Call the Home handler against the configured API base URL
+			reg := metrics.NewRegistry()
+			api := contract.NewClient("http://api.test/", contract.WithHTTPClient(client))
+			NewHandler(api, reg).Home(rr, req)
 
-			// Assert the status code and body
+			// Assert the API was called at the configured base URL, then the status code and body
+			assert.Equal(
+				t,
+				"http://api.test/readyz",
+				requestedURL,
+				"Handler called the wrong API URL",
+			)
 			assert.Equal(t, tt.expectedStatus, rr.Code, "Handler returned wrong status code")
 
 			// Assert on specific elements instead of the full HTML
@@ -134,6 +189,73 @@ func TestHome(t *testing.T) {
 				tt.expectedButtonText,
 				"Button should have the expected text",
 			)
+			for _, fragment := range tt.expectedContent {
+				assert.Contains(t, bodyString, fragment, "Page should render the check results")
+			}
+
+			// Assert the observed API health is exposed as a metric
+			assert.Equal(
+				t,
+				tt.expectedAPIUp,
+				reg.NewGauge("web_api_up", "").Value(),
+				"web_api_up gauge mismatch",
+			)
 		})
 	}
 }
+
+func TestHandler_HealthEvents(t *testing.T) {
+	// The API reports a pass on the first stream, which it ends, then a failure on the
+	// stream resuming after it.
+	var connections atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, contract.PathHealthEvents, r.URL.Path, "wrong API path")
+		sw, err := sse.NewWriter(w)
+		if !assert.NoError(t, err, "Setup: starting the API stream") {
+			return
+		}
+		switch connections.Add(1) {
+		case 1:
+			_ = sw.Send(sse.Event{Retry: 10 * time.Millisecond})
+			_ = sw.Send(sse.Event{ID: "1", Type: contract.EventHealth, Data: `{"status":"pass"}`})
+		default:
+			assert.Equal(t, "1", r.Header.Get("Last-Event-ID"), "the stream should resume")
+			_ = sw.Send(sse.Event{ID: "2", Type: contract.EventHealth, Data: `{"status":"fail"}`})
+			<-r.Context().Done()
+		}
+	}))
+	defer api.Close()
+	web := httptest.NewServer(http.HandlerFunc(
+		NewHandler(contract.NewClient(api.URL), nil).HealthEvents))
+	defer web.Close()
+
+	resp, err := http.Get(web.URL)
+	require.NoError(t, err, "following the health events should not fail")
+	defer func() { _ = resp.Body.Close() }()
+	rd := sse.NewReader(resp.Body)
+	for _, expected := range []string{"OK", "Down"} {
+		e, err := rd.Next()
+		require.NoError(t, err, "reading a health event")
+		assert.Equal(t, contract.EventHealth, e.Type, "event type mismatch")
+		assert.Contains(t, e.Data, "<button", "events should carry the status button")
+		assert.Contains(t, e.Data, expected, "the button should show the API status")
+	}
+}
+
+func TestHandler_HealthEventsAPIUnreachable(t *testing.T) {
+	client := &http.Client{Transport: &mockRoundTripper{
+		RoundTripFunc: func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+	}}
+	api := contract.NewClient("http://api.test/", contract.WithHTTPClient(client))
+	web := httptest.NewServer(http.HandlerFunc(NewHandler(api, nil).HealthEvents))
+	defer web.Close()
+
+	resp, err := http.Get(web.URL)
+	require.NoError(t, err, "following the health events should not fail")
+	defer func() { _ = resp.Body.Close() }()
+	e, err := sse.NewReader(resp.Body).Next()
+	require.NoError(t, err, "reading a health event")
+	assert.Contains(t, e.Data, "Down", "an unreachable API should be shown as down")
+}
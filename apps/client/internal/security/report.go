@@ -0,0 +1,100 @@
+package security
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// maxReportSize bounds the body of violation reports, which browsers keep small.
+const maxReportSize = 64 << 10
+
+// violation is a CSP violation, as reported with report-uri or report-to.
+type violation struct {
+	DocumentURL        string `json:"documentURL"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"sourceFile"`
+	LineNumber         int    `json:"lineNumber"`
+}
+
+// legacyReport is the body sent for the report-uri directive, as application/csp-report.
+type legacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+	} `json:"csp-report"`
+}
+
+// report is one entry of a Reporting API batch, sent for the report-to directive as
+// application/reports+json.
+type report struct {
+	Type string    `json:"type"`
+	Body violation `json:"body"`
+}
+
+// ReportHandler collects CSP violation reports, in the formats of both the report-uri and
+// the report-to directives, and logs them. It answers 204 No Content.
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context())
+	r.Body = http.MaxBytesReader(w, r.Body, maxReportSize)
+	dec := json.NewDecoder(r.Body)
+
+	var violations []violation
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/csp-report", "application/json":
+		var legacy legacyReport
+		if err := dec.Decode(&legacy); err != nil {
+			http.Error(w, "malformed report", http.StatusBadRequest)
+			return
+		}
+		v := legacy.Report
+		directive := v.EffectiveDirective
+		if directive == "" {
+			directive = v.ViolatedDirective
+		}
+		violations = append(violations, violation{
+			DocumentURL:        v.DocumentURI,
+			BlockedURL:         v.BlockedURI,
+			EffectiveDirective: directive,
+			Disposition:        v.Disposition,
+			SourceFile:         v.SourceFile,
+			LineNumber:         v.LineNumber,
+		})
+	case "application/reports+json":
+		var batch []report
+		if err := dec.Decode(&batch); err != nil {
+			http.Error(w, "malformed report", http.StatusBadRequest)
+			return
+		}
+		for _, rep := range batch {
+			if rep.Type == "csp-violation" {
+				violations = append(violations, rep.Body)
+			}
+		}
+	default:
+		http.Error(w, "unsupported report format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	for _, v := range violations {
+		logger.Warn("CSP violation",
+			"document", v.DocumentURL,
+			"blocked", v.BlockedURL,
+			"directive", v.EffectiveDirective,
+			"disposition", v.Disposition,
+			"source", v.SourceFile,
+			"line", v.LineNumber,
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
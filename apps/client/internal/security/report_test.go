@@ -0,0 +1,63 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:        "report-uri",
+			contentType: "application/csp-report",
+			body: `{"csp-report": {"document-uri": "https://app.test/", ` +
+				`"violated-directive": "script-src-elem", "blocked-uri": "inline"}}`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "report-to",
+			contentType: "application/reports+json",
+			body: `[{"type": "csp-violation", "body": {"documentURL": "https://app.test/", ` +
+				`"effectiveDirective": "style-src-elem", "blockedURL": "inline"}}]`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "malformed",
+			contentType:    "application/csp-report",
+			body:           `{"csp-report":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too large",
+			contentType:    "application/reports+json",
+			body:           "[" + strings.Repeat(" ", maxReportSize) + "]",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported format",
+			contentType:    "text/plain",
+			body:           "blocked",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, ReportPath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			ReportHandler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "status code mismatch")
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package security sets the security headers of the web client: a Content-Security-Policy
+// allowing inline scripts and styles only when they carry the nonce of the request, HSTS,
+// and the headers against MIME sniffing and framing. Violations of the policy are
+// collected at ReportPath.
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoncePlaceholder is replaced, in the configured policy, by the nonce of each request.
+const NoncePlaceholder = "{nonce}"
+
+// ReportPath is the path of the endpoint collecting CSP violation reports.
+const ReportPath = "/csp-report"
+
+// reportGroup is the Reporting API endpoint name of ReportPath.
+const reportGroup = "csp-endpoint"
+
+// Config selects the security headers. It is meant to be embedded in the configuration of
+// the web client.
+type Config struct {
+	CSP            string        `config:"csp"             usage:"CSP, where {nonce} is the request nonce"`
+	CSPReportOnly  bool          `config:"csp_report_only" usage:"report violations without blocking"`
+	CSPReport      bool          `config:"csp_report"      usage:"collect violation reports"`
+	HSTSMaxAge     time.Duration `config:"hsts_max_age"    usage:"HSTS max-age, 0 to omit"`
+	FrameOptions   string        `config:"frame_options"   usage:"X-Frame-Options, empty to omit"`
+	ReferrerPolicy string        `config:"referrer_policy" usage:"Referrer-Policy, empty to omit"`
+}
+
+// DefaultConfig returns a strict configuration: same-origin resources, inline scripts and
+// styles only with the request nonce, no framing, and HSTS for a year.
+func DefaultConfig() Config {
+	return Config{
+		CSP: "default-src 'self'; " +
+			"script-src 'self' 'nonce-{nonce}'; " +
+			"style-src 'self' 'nonce-{nonce}'; " +
+			"img-src 'self' data:; " +
+			"object-src 'none'; " +
+			"base-uri 'self'; " +
+			"form-action 'self'; " +
+			"frame-ancestors 'none'",
+		CSPReport:      true,
+		HSTSMaxAge:     365 * 24 * time.Hour,
+		FrameOptions:   "DENY",
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if strings.ContainsAny(c.CSP, "\r\n") {
+		return errors.New("security.csp must fit on one line")
+	}
+	if c.CSPReportOnly && c.CSP == "" {
+		return errors.New("security.csp_report_only requires security.csp")
+	}
+	if c.HSTSMaxAge < 0 {
+		return errors.New("security.hsts_max_age must not be negative")
+	}
+	return nil
+}
+
+type nonceKey struct{}
+
+// Nonce returns the CSP nonce of the request whose context is ctx, or an empty string
+// outside of Headers. Templates get it with the cspNonce function.
+func Nonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// newNonce returns a random 128-bit nonce.
+func newNonce() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never returns an error.
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// Headers returns a middleware setting the security headers configured by c on every
+// response, with a fresh nonce in the request context and the policy.
+func Headers(c Config) func(http.Handler) http.Handler {
+	policy := c.CSP
+	if policy != "" && c.CSPReport {
+		policy += "; report-uri " + ReportPath + "; report-to " + reportGroup
+	}
+	cspHeader := "Content-Security-Policy"
+	if c.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	var hsts string
+	if c.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(c.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if c.FrameOptions != "" {
+				h.Set("X-Frame-Options", c.FrameOptions)
+			}
+			if c.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", c.ReferrerPolicy)
+			}
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			if policy == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			nonce := newNonce()
+			h.Set(cspHeader, strings.ReplaceAll(policy, NoncePlaceholder, nonce))
+			if c.CSPReport {
+				h.Set("Reporting-Endpoints", reportGroup+`="`+ReportPath+`"`)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce)))
+		})
+	}
+}
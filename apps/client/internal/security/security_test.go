@@ -0,0 +1,84 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve sends a request through Headers(c) and returns the response and the nonce seen by
+// the handler.
+func serve(c Config) (*httptest.ResponseRecorder, string) {
+	var nonce string
+	h := Headers(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = Nonce(r.Context())
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec, nonce
+}
+
+func TestHeaders(t *testing.T) {
+	rec, nonce := serve(DefaultConfig())
+	h := rec.Header()
+
+	require.NotEmpty(t, nonce, "the handler should get a nonce")
+	csp := h.Get("Content-Security-Policy")
+	assert.Contains(
+		t,
+		csp,
+		"script-src 'self' 'nonce-"+nonce+"'",
+		"the policy should carry the nonce",
+	)
+	assert.NotContains(t, csp, NoncePlaceholder, "the placeholder should be replaced")
+	assert.True(t, strings.HasSuffix(csp, "; report-uri /csp-report; report-to csp-endpoint"),
+		"the policy should report violations, got %q", csp)
+	assert.Equal(t, `csp-endpoint="/csp-report"`, h.Get("Reporting-Endpoints"),
+		"Reporting-Endpoints mismatch")
+	assert.Empty(t, h.Get("Content-Security-Policy-Report-Only"), "the policy should be enforced")
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"), "X-Content-Type-Options mismatch")
+	assert.Equal(t, "DENY", h.Get("X-Frame-Options"), "X-Frame-Options mismatch")
+	assert.Equal(t, "strict-origin-when-cross-origin", h.Get("Referrer-Policy"),
+		"Referrer-Policy mismatch")
+	assert.Equal(t, "max-age=31536000; includeSubDomains", h.Get("Strict-Transport-Security"),
+		"Strict-Transport-Security mismatch")
+
+	_, other := serve(DefaultConfig())
+	assert.NotEqual(t, nonce, other, "every request should get its own nonce")
+}
+
+func TestHeaders_ReportOnly(t *testing.T) {
+	c := DefaultConfig()
+	c.CSPReportOnly = true
+	rec, _ := serve(c)
+
+	assert.Empty(
+		t,
+		rec.Header().Get("Content-Security-Policy"),
+		"the policy should not be enforced",
+	)
+	assert.Contains(
+		t,
+		rec.Header().Get("Content-Security-Policy-Report-Only"),
+		"default-src 'self'",
+		"the policy should be reported only",
+	)
+}
+
+func TestHeaders_Disabled(t *testing.T) {
+	rec, nonce := serve(Config{})
+	h := rec.Header()
+
+	assert.Empty(t, nonce, "no policy should need no nonce")
+	for _, name := range []string{
+		"Content-Security-Policy", "Reporting-Endpoints", "X-Frame-Options",
+		"Referrer-Policy", "Strict-Transport-Security",
+	} {
+		assert.Empty(t, h.Get(name), "%s should be omitted", name)
+	}
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"), "nosniff should always be set")
+}
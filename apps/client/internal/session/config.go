@@ -0,0 +1,92 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Store names accepted by Config.Store.
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+// Config selects the session store and the cookie settings. It is meant to be embedded in
+// the configuration of the web client.
+type Config struct {
+	Secrets []string      `config:"secrets" usage:"cookie secrets, newest first, at least 32 bytes each"`
+	Store   string        `config:"store"   usage:"session store: memory or file"`
+	Dir     string        `config:"dir"     usage:"directory of the file session store"`
+	TTL     time.Duration `config:"ttl"     usage:"session lifetime after its last change"`
+	Secure  bool          `config:"secure"  usage:"restrict the session cookie to HTTPS"`
+}
+
+// DefaultConfig returns a configuration keeping sessions in memory for a day.
+func DefaultConfig() Config {
+	return Config{
+		Store:  StoreMemory,
+		Dir:    "data/sessions",
+		TTL:    24 * time.Hour,
+		Secure: true,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	for _, secret := range c.Secrets {
+		if len(secret) < MinSecretLength {
+			return errors.New("session.secrets must be at least 32 bytes long")
+		}
+	}
+	switch c.Store {
+	case StoreMemory:
+	case StoreFile:
+		if c.Dir == "" {
+			return errors.New("session.dir must not be empty with the file store")
+		}
+	default:
+		return errors.New("session.store must be one of memory or file")
+	}
+	if c.TTL <= 0 {
+		return errors.New("session.ttl must be positive")
+	}
+	return nil
+}
+
+// New returns a Manager as configured by cfg. Without secrets, cookies are sealed with a
+// random key, so sessions do not survive a restart.
+func New(cfg Config) (*Manager, error) {
+	secrets := make([][]byte, 0, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		secrets = append(secrets, []byte(secret))
+	}
+	if len(secrets) == 0 {
+		slog.Warn("No session secret configured, sessions will not survive a restart")
+		key := make([]byte, MinSecretLength)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, []byte(base64.RawURLEncoding.EncodeToString(key)))
+	}
+	codec, err := NewCodec(secrets...)
+	if err != nil {
+		slog.Error("Failed to create session codec", "error", err)
+		return nil, err
+	}
+
+	var store Store
+	switch cfg.Store {
+	case StoreFile:
+		fs, err := NewFileStore(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store = fs
+	default:
+		store = NewMemoryStore()
+	}
+	return NewManager(store, codec, WithTTL(cfg.TTL), WithSecure(cfg.Secure)), nil
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// MinSecretLength is the minimum length of a secret accepted by NewCodec.
+const MinSecretLength = 32
+
+// errInvalidCookie is returned when a cookie value cannot be authenticated with any key.
+var errInvalidCookie = errors.New("invalid session cookie")
+
+// Codec seals cookie values with AES-256-GCM: they are encrypted, so clients cannot read
+// them, and authenticated, so clients cannot forge or alter them. The cookie name is bound
+// to the value as additional data, so a value cannot be replayed under another name.
+type Codec struct {
+	aeads []cipher.AEAD
+}
+
+// NewCodec returns a Codec sealing with the first secret and opening with any of them,
+// which allows rotating secrets without logging everyone out. Each secret must be at
+// least MinSecretLength bytes long.
+func NewCodec(secrets ...[]byte) (*Codec, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("session: at least one secret is required")
+	}
+	c := &Codec{}
+	for _, secret := range secrets {
+		if len(secret) < MinSecretLength {
+			return nil, errors.New("session: secrets must be at least 32 bytes long")
+		}
+		key := sha256.Sum256(secret)
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+	return c, nil
+}
+
+// Encode seals value for the cookie name.
+func (c *Codec) Encode(name, value string) (string, error) {
+	aead := c.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode opens a value sealed by Encode for the cookie name.
+func (c *Codec) Decode(name, encoded string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errInvalidCookie
+	}
+	for _, aead := range c.aeads {
+		if len(sealed) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if plain, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", errInvalidCookie
+}
@@ -0,0 +1,286 @@
+// Package session provides server-side HTTP sessions for the web client. The session ID
+// travels in an encrypted, authenticated cookie, and the session values are kept in a
+// pluggable Store.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+type contextKey struct{}
+
+// Session holds the values of one client session. It is safe for concurrent use. Changes
+// are saved when the response headers are written.
+type Session struct {
+	mu         sync.Mutex
+	id         string
+	previousID string
+	values     map[string]string
+	modified   bool
+	destroyed  bool
+}
+
+// FromContext returns the session attached to ctx by Manager.Middleware, or nil.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(contextKey{}).(*Session)
+	return s
+}
+
+// Get returns the value stored under key, or "" if there is none. It is safe to call on a
+// nil Session.
+func (s *Session) Get(key string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores value under key.
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Renew moves the session to a new ID, keeping its values. Call it whenever the privilege
+// level changes, e.g. on login, to defeat session fixation.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.previousID == "" {
+		s.previousID = s.id
+	}
+	s.id = ""
+	s.modified = true
+	s.destroyed = false
+}
+
+// Destroy removes every value, deletes the session from the store and expires its cookie.
+// Values set afterwards start a new session.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]string)
+	s.destroyed = true
+	s.modified = false
+}
+
+// Manager loads and saves the sessions of incoming requests.
+type Manager struct {
+	store    Store
+	codec    *Codec
+	name     string
+	ttl      time.Duration
+	secure   bool
+	sameSite http.SameSite
+	now      func() time.Time
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithCookieName sets the name of the session cookie. The default is "session".
+func WithCookieName(name string) Option {
+	return func(m *Manager) { m.name = name }
+}
+
+// WithTTL sets how long a session lives after its last change. The default is 24 hours.
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) { m.ttl = ttl }
+}
+
+// WithSecure sets whether the cookie is restricted to HTTPS. The default is true, which
+// browsers also honour on http://localhost.
+func WithSecure(secure bool) Option {
+	return func(m *Manager) { m.secure = secure }
+}
+
+// WithSameSite sets the SameSite attribute of the cookie. The default is Lax.
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(m *Manager) { m.sameSite = sameSite }
+}
+
+// NewManager returns a Manager keeping sessions in store and sealing cookies with codec.
+// It panics if store or codec is nil, which is a programming error.
+func NewManager(store Store, codec *Codec, opts ...Option) *Manager {
+	if store == nil || codec == nil {
+		panic("Error: session.NewManager requires a store and a codec")
+	}
+	m := &Manager{
+		store:    store,
+		codec:    codec,
+		name:     "session",
+		ttl:      24 * time.Hour,
+		secure:   true,
+		sameSite: http.SameSiteLaxMode,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Middleware attaches the session of the request to its context, and saves it before the
+// response headers are written.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.load(r)
+		sw := &writer{ResponseWriter: w, commit: func() { m.commit(w, r, s) }}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), contextKey{}, s)))
+		sw.commitOnce()
+	})
+}
+
+// load returns the session named by the request cookie, or a new empty session.
+func (m *Manager) load(r *http.Request) *Session {
+	s := &Session{values: make(map[string]string)}
+	cookie, err := r.Cookie(m.name)
+	if err != nil {
+		return s
+	}
+	id, err := m.codec.Decode(m.name, cookie.Value)
+	if err != nil {
+		return s
+	}
+	record, err := m.store.Load(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			middleware.LoggerFromContext(r.Context()).Error("Failed to load session", "error", err)
+		}
+		return s
+	}
+	s.id = id
+	if record.Values != nil {
+		s.values = record.Values
+	}
+	return s
+}
+
+// commit saves or deletes the session and sets the matching cookie on w.
+func (m *Manager) commit(w http.ResponseWriter, r *http.Request, s *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := r.Context()
+	logger := middleware.LoggerFromContext(ctx)
+
+	if s.destroyed || s.previousID != "" {
+		for _, id := range []string{s.id, s.previousID} {
+			if id == "" {
+				continue
+			}
+			if err := m.store.Delete(ctx, id); err != nil {
+				logger.Error("Failed to delete session", "error", err)
+			}
+		}
+		s.previousID = ""
+		if s.destroyed {
+			s.id = ""
+			http.SetCookie(w, m.cookie("", -1))
+		}
+	}
+	if !s.modified {
+		return
+	}
+	if s.id == "" {
+		id, err := newID()
+		if err != nil {
+			logger.Error("Failed to generate session ID", "error", err)
+			return
+		}
+		s.id = id
+	}
+	record := Record{Values: s.values, ExpiresAt: m.now().Add(m.ttl)}
+	if err := m.store.Save(ctx, s.id, record); err != nil {
+		logger.Error("Failed to save session", "error", err)
+		return
+	}
+	value, err := m.codec.Encode(m.name, s.id)
+	if err != nil {
+		logger.Error("Failed to seal session cookie", "error", err)
+		return
+	}
+	http.SetCookie(w, m.cookie(value, int(m.ttl.Seconds())))
+}
+
+// cookie returns the session cookie carrying value. A negative maxAge deletes it.
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: m.sameSite,
+	}
+}
+
+// newID returns a random, URL-safe session ID.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// writer commits the session right before the response headers are written.
+type writer struct {
+	http.ResponseWriter
+	commit    func()
+	committed bool
+}
+
+func (w *writer) commitOnce() {
+	if !w.committed {
+		w.committed = true
+		w.commit()
+	}
+}
+
+// WriteHeader commits the session, then forwards the status code.
+func (w *writer) WriteHeader(status int) {
+	w.commitOnce()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write commits the session, then forwards b.
+func (w *writer) Write(b []byte) (int, error) {
+	w.commitOnce()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush commits the session, then flushes the underlying writer if it supports it.
+func (w *writer) Flush() {
+	w.commitOnce()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte(strings.Repeat("s", MinSecretLength))
+
+func TestCodec(t *testing.T) {
+	codec, err := NewCodec(testSecret)
+	require.NoError(t, err, "NewCodec should accept a long enough secret")
+
+	sealed, err := codec.Encode("session", "id-1")
+	require.NoError(t, err, "Encode should not fail")
+	assert.NotContains(t, sealed, "id-1", "sealed value should not reveal the plaintext")
+
+	value, err := codec.Decode("session", sealed)
+	require.NoError(t, err, "Decode should open a value sealed by Encode")
+	assert.Equal(t, "id-1", value, "decoded value mismatch")
+
+	_, err = codec.Decode("other", sealed)
+	assert.Error(t, err, "Decode should reject a value sealed for another cookie name")
+	_, err = codec.Decode("session", sealed[:len(sealed)-2]+"AA")
+	assert.Error(t, err, "Decode should reject a tampered value")
+
+	rotated, err := NewCodec([]byte(strings.Repeat("n", MinSecretLength)), testSecret)
+	require.NoError(t, err, "NewCodec should accept several secrets")
+	value, err = rotated.Decode("session", sealed)
+	require.NoError(t, err, "Decode should open values sealed with an older secret")
+	assert.Equal(t, "id-1", value, "decoded value mismatch after rotation")
+
+	_, err = NewCodec([]byte("short"))
+	assert.Error(t, err, "NewCodec should reject short secrets")
+}
+
+// newTestServer returns a handler whose session is driven by the action query parameter.
+func newTestServer(t *testing.T, store Store) http.Handler {
+	t.Helper()
+	codec, err := NewCodec(testSecret)
+	require.NoError(t, err, "NewCodec should not fail")
+	m := NewManager(store, codec)
+	return m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := FromContext(r.Context())
+		switch r.URL.Query().Get("action") {
+		case "set":
+			s.Set("user", r.URL.Query().Get("user"))
+		case "renew":
+			s.Renew()
+		case "destroy":
+			s.Destroy()
+		}
+		_, _ = w.Write([]byte(s.Get("user")))
+	}))
+}
+
+// do sends a request with the given query and cookies and returns the response.
+func do(h http.Handler, query string, cookies ...*http.Cookie) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr.Result()
+}
+
+func body(t *testing.T, res *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "reading response body")
+	return string(b)
+}
+
+func TestManager_Middleware(t *testing.T) {
+	store := NewMemoryStore()
+	h := newTestServer(t, store)
+
+	res := do(h, "")
+	assert.Empty(t, res.Cookies(), "an untouched session should not set a cookie")
+
+	res = do(h, "action=set&user=alice")
+	require.Len(t, res.Cookies(), 1, "setting a value should set the session cookie")
+	cookie := res.Cookies()[0]
+	assert.True(t, cookie.HttpOnly, "session cookie should be HttpOnly")
+	assert.True(t, cookie.Secure, "session cookie should be Secure by default")
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite, "session cookie SameSite mismatch")
+
+	assert.Equal(t, "alice", body(t, do(h, "", cookie)), "session should be loaded from the cookie")
+
+	res = do(h, "action=renew", cookie)
+	require.Len(t, res.Cookies(), 1, "renewing should set a new session cookie")
+	renewed := res.Cookies()[0]
+	assert.Equal(t, "alice", body(t, do(h, "", renewed)), "renewing should keep the values")
+	assert.Empty(t, body(t, do(h, "", cookie)), "renewing should invalidate the previous ID")
+
+	res = do(h, "action=destroy", renewed)
+	require.Len(t, res.Cookies(), 1, "destroying should expire the session cookie")
+	assert.Negative(t, res.Cookies()[0].MaxAge, "destroyed session cookie should expire")
+	assert.Empty(t, body(t, do(h, "", renewed)), "destroying should delete the session")
+
+	forged := &http.Cookie{Name: "session", Value: "forged"}
+	assert.Empty(t, body(t, do(h, "", forged)), "a forged cookie should start a new session")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		mutate        func(c *Config)
+		containsError string
+	}{
+		{name: "defaults are valid", mutate: func(c *Config) {}},
+		{
+			name:          "short secret",
+			mutate:        func(c *Config) { c.Secrets = []string{"short"} },
+			containsError: "session.secrets must be at least 32 bytes long",
+		},
+		{
+			name:          "unknown store",
+			mutate:        func(c *Config) { c.Store = "redis" },
+			containsError: "session.store must be one of memory or file",
+		},
+		{
+			name:          "file store without directory",
+			mutate:        func(c *Config) { c.Store, c.Dir = StoreFile, "" },
+			containsError: "session.dir must not be empty",
+		},
+		{
+			name:          "zero TTL",
+			mutate:        func(c *Config) { c.TTL = 0 },
+			containsError: "session.ttl must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if tt.containsError == "" {
+				assert.NoError(t, err, "Validate should accept the configuration")
+				return
+			}
+			assert.ErrorContains(t, err, tt.containsError, "Validate error mismatch")
+		})
+	}
+}
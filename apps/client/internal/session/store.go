@@ -0,0 +1,212 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Load when no live session has the given ID.
+var ErrNotFound = errors.New("session not found")
+
+// sweepInterval is how often the stores drop expired sessions while saving.
+const sweepInterval = time.Minute
+
+// Record is the server-side state of a session.
+type Record struct {
+	Values    map[string]string `json:"values"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// expired reports whether the record is past its expiry at now.
+func (r Record) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// Store persists session records by session ID. Implementations must be safe for
+// concurrent use and must not return expired records.
+type Store interface {
+	// Load returns the record of the session id, or ErrNotFound.
+	Load(ctx context.Context, id string) (Record, error)
+	// Save creates or replaces the record of the session id.
+	Save(ctx context.Context, id string, r Record) error
+	// Delete removes the session id. Deleting an unknown session is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on restart and are not
+// shared between replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record), now: time.Now}
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(_ context.Context, id string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	if r.expired(s.now()) {
+		delete(s.records, id)
+		return Record{}, ErrNotFound
+	}
+	return Record{Values: maps.Clone(r.Values), ExpiresAt: r.ExpiresAt}, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(_ context.Context, id string, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, v := range s.records {
+			if v.expired(now) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.records[id] = Record{Values: maps.Clone(r.Values), ExpiresAt: r.ExpiresAt}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+// FileStore keeps one JSON file per session in a directory, so that sessions survive
+// restarts. File names are hashes of the session IDs, which never reach the file system.
+type FileStore struct {
+	dir string
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewFileStore returns a FileStore writing to dir, which is created if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		slog.Error("Failed to create session directory", "dir", dir, "error", err)
+		return nil, err
+	}
+	return &FileStore{dir: dir, now: time.Now}, nil
+}
+
+// path returns the file holding the session id.
+func (s *FileStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load implements Store.
+func (s *FileStore) Load(_ context.Context, id string) (Record, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Record{}, err
+	}
+	if r.expired(s.now()) {
+		_ = os.Remove(s.path(id))
+		return Record{}, ErrNotFound
+	}
+	return r, nil
+}
+
+// Save implements Store. The file is replaced atomically.
+func (s *FileStore) Save(_ context.Context, id string, r Record) error {
+	s.sweep()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(id), data)
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// sweep removes the files of expired sessions, at most once per sweepInterval.
+func (s *FileStore) sweep() {
+	s.mu.Lock()
+	now := s.now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		slog.Error("Failed to list session directory", "dir", s.dir, "error", err)
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var r Record
+		if json.Unmarshal(data, &r) == nil && r.expired(now) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path, then renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Removing fails once the file is renamed, which is expected.
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			s := NewMemoryStore()
+			s.now = clock
+			return s
+		},
+		"file": func(t *testing.T) Store {
+			s, err := NewFileStore(t.TempDir())
+			require.NoError(t, err, "NewFileStore should not fail")
+			s.now = clock
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			_, err := s.Load(ctx, "missing")
+			assert.ErrorIs(t, err, ErrNotFound, "Load should report unknown sessions")
+
+			record := Record{
+				Values:    map[string]string{"user": "alice"},
+				ExpiresAt: now.Add(time.Hour),
+			}
+			require.NoError(t, s.Save(ctx, "live", record), "Save should not fail")
+			got, err := s.Load(ctx, "live")
+			require.NoError(t, err, "Load should find a saved session")
+			assert.Equal(t, "alice", got.Values["user"], "loaded values mismatch")
+			assert.True(t, record.ExpiresAt.Equal(got.ExpiresAt), "loaded expiry mismatch")
+
+			record.ExpiresAt = now
+			require.NoError(t, s.Save(ctx, "expired", record), "Save should not fail")
+			_, err = s.Load(ctx, "expired")
+			assert.ErrorIs(t, err, ErrNotFound, "Load should not return expired sessions")
+
+			require.NoError(t, s.Delete(ctx, "live"), "Delete should not fail")
+			_, err = s.Load(ctx, "live")
+			assert.ErrorIs(t, err, ErrNotFound, "Load should not return deleted sessions")
+			assert.NoError(t, s.Delete(ctx, "live"), "Delete should ignore unknown sessions")
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package templates
+
+import (
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/client/templates/components"
+)
+
+// AccountPageData defines the structure of data expected by the account template.
+type AccountPageData struct {
+	Email      string // Email of the logged-in user
+	ButtonData components.ButtonProps
+}
+
+func init() {
+	LoadTemplateFile("account", "pages/account.html", Extends(baseLayout))
+}
+
+// Account prepares the account template for rendering with the given data.
+// The data parameter should be of type AccountPageData.
+// It panics if the "account" template is not found in the registry.
+func Account(data interface{}) *TemplateRenderer {
+	renderer, err := getRenderer("account", data)
+	if err != nil {
+		slog.Error("failed to get renderer for account template", "error", err)
+		panic("Failed to get renderer for account template: " + err.Error())
+	}
+	return renderer
+}
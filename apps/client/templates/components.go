@@ -0,0 +1,5 @@
+package templates
+
+// componentNames are the names of the templates defined by the component files, which pages
+// and layouts reference with {{template "name" .}}.
+var componentNames = LoadComponentFiles("components/*.html")
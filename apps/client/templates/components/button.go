@@ -64,12 +64,3 @@ func (p ButtonProps) GetButtonClasses() string {
 		fmt.Sprintf("%s %s %s %s", baseClasses, variantClasses, sizeClasses, p.ExtraClasses),
 	)
 }
-
-const ButtonTmplString string = `
-{{define "button"}}
-    <button
-        class="{{.GetButtonClasses}}" {{/* Call the new method */}}>
-        {{.Text}}
-    </button>
-{{end}}
-`
@@ -0,0 +1,19 @@
+package components
+
+// FormFieldProps describes a labelled input of a form.
+type FormFieldProps struct {
+	Label        string // Text of the label
+	Name         string // Name and ID of the input
+	Type         string // e.g., "text", "email", "password" (defaults to "text")
+	Value        string // Initial value of the input
+	Autocomplete string // Autocomplete hint, e.g. "email", "current-password"
+	Required     bool   // Whether the field must be filled in
+}
+
+// GetType returns the input type, defaulting to "text".
+func (p FormFieldProps) GetType() string {
+	if p.Type == "" {
+		return "text"
+	}
+	return p.Type
+}
@@ -0,0 +1,31 @@
+package components
+
+import "fmt"
+
+// HealthCheckProps describes the result of a single API health check.
+type HealthCheckProps struct {
+	Name      string  // Name of the check, e.g. "database"
+	Status    string  // "pass" or "fail"
+	Critical  bool    // Whether a failure of this check takes the API down
+	LatencyMS float64 // How long the check took, in milliseconds
+	Error     string  // Error reported by the check, if any
+}
+
+// GetStatusClasses returns the CSS classes of the status badge.
+// A failing non-critical check is shown as a warning since it does not take the API down.
+func (p HealthCheckProps) GetStatusClasses() string {
+	base := "rounded-md px-2 py-0.5 text-xs font-medium text-white"
+	switch {
+	case p.Status == "pass":
+		return base + " bg-green-500"
+	case !p.Critical:
+		return base + " bg-yellow-500"
+	default:
+		return base + " bg-red-500"
+	}
+}
+
+// GetLatency returns the check latency formatted for display.
+func (p HealthCheckProps) GetLatency() string {
+	return fmt.Sprintf("%.1f ms", p.LatencyMS)
+}
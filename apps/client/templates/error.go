@@ -0,0 +1,16 @@
+package templates
+
+// ErrorPage is the name of the page written by TemplateRenderer.Respond instead of a page
+// that fails to render.
+const ErrorPage = "error"
+
+// ErrorPageData defines the structure of data expected by the error template.
+type ErrorPageData struct {
+	Status     int    // HTTP status code of the response
+	StatusText string // e.g. "Internal Server Error"
+	RequestID  string // ID of the request, for users to report it, if any
+}
+
+func init() {
+	LoadTemplateFile(ErrorPage, "pages/error.html", Extends(baseLayout))
+}
@@ -0,0 +1,9 @@
+package templates
+
+import "embed"
+
+// files are the template files of the pages, layouts and components, embedded in the
+// binary. Their paths are relative to this directory, e.g. "pages/home.html".
+//
+//go:embed layouts/*.html pages/*.html components/*.html
+var files embed.FS
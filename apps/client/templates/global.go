@@ -0,0 +1,73 @@
+package templates
+
+// globalRegistry is the registry of the pages of the web client, loaded by the init
+// functions of this package from the embedded template files.
+var globalRegistry = NewRegistry(files)
+
+// must returns v, or panics with err if it is not nil: the templates of the global
+// registry are loaded at start-up, where an error is a programming error.
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic("Error: " + err.Error())
+	}
+	return v
+}
+
+// LoadLayout parses a layout template string and registers it under the given name in
+// the global registry. Layouts are meant to be loaded in package-level variables, so that
+// they are ready before the init functions loading the pages that extend them.
+// It panics if the layout cannot be loaded, see Registry.LoadLayout.
+func LoadLayout(name string, layoutTmplString string, opts ...Option) *Layout {
+	return must(globalRegistry.LoadLayout(name, Text(layoutTmplString), opts...))
+}
+
+// LoadLayoutFile is like LoadLayout, with the layout read from the template file at path.
+func LoadLayoutFile(name string, path string, opts ...Option) *Layout {
+	return must(globalRegistry.LoadLayout(name, File(path), opts...))
+}
+
+// LoadComponent parses a component template string and registers each template it
+// {{define}}s in the global registry, under its name.
+// It panics if the component cannot be loaded, see Registry.LoadComponent.
+func LoadComponent(componentTmplString string) {
+	must(globalRegistry.LoadComponent(Text(componentTmplString)))
+}
+
+// LoadComponentFiles is like LoadComponent, for each template file matching pattern, in
+// the syntax of fs.Glob. Components are meant to be loaded in a package-level variable,
+// so that they are registered before the init functions loading the pages that use them.
+// It returns the names of the registered templates.
+func LoadComponentFiles(pattern string) []string {
+	return must(globalRegistry.LoadComponentFiles(pattern))
+}
+
+// LoadTemplate parses a page template string, storing the resulting composite template
+// with the given name in the global registry.
+// It panics if the page cannot be loaded, see Registry.Load.
+func LoadTemplate(name string, pageTmplString string, opts ...Option) {
+	globalRegistry.MustLoad(name, Text(pageTmplString), opts...)
+}
+
+// LoadTemplateFile is like LoadTemplate, with the page read from the template file at
+// path, e.g. "pages/home.html".
+func LoadTemplateFile(name string, path string, opts ...Option) {
+	globalRegistry.MustLoad(name, File(path), opts...)
+}
+
+// UseDir makes the global registry read the template files from dir, see
+// Registry.UseDir.
+func UseDir(dir string) error {
+	return globalRegistry.UseDir(dir)
+}
+
+// Freeze ends the loading of templates into the global registry, see Registry.Freeze. It
+// is meant to be called once the application has started.
+func Freeze() {
+	globalRegistry.Freeze()
+}
+
+// getRenderer retrieves a parsed template by name from the global registry and prepares it
+// for rendering with the given data, see Registry.Lookup.
+func getRenderer(name string, data interface{}) (*TemplateRenderer, error) {
+	return globalRegistry.Lookup(name, data)
+}
@@ -0,0 +1,19 @@
+package templates
+
+import "log/slog"
+
+func init() {
+	LoadTemplateFile("health_status", "pages/health_status.html")
+}
+
+// HealthStatus prepares the health status fragment for rendering with the given data.
+// The data parameter should be of type components.ButtonProps.
+// It panics if the "health_status" template is not found in the registry.
+func HealthStatus(data interface{}) *TemplateRenderer {
+	renderer, err := getRenderer("health_status", data)
+	if err != nil {
+		slog.Error("failed to get renderer for health_status template", "error", err)
+		panic("Failed to get renderer for health_status template: " + err.Error())
+	}
+	return renderer
+}
@@ -9,34 +9,17 @@ import (
 // HomePageData defines the structure of data expected by the home template.
 type HomePageData struct {
 	ButtonData components.ButtonProps
+	Checks     []components.HealthCheckProps
+	// HealthEventsURL streams the status button when the API status changes; the button
+	// stays as rendered when it is empty.
+	HealthEventsURL string
 	// Add other fields specific to the home page here
 }
 
-const tmplString string = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <title>Home Page</title>
-    <link rel="stylesheet" href="/static/css/global.css">
-</head>
-<body class="min-h-screen flex flex-col items-center justify-center p-8">
-    <h1 class="text-4xl font-bold mb-8">Health Check</h1>
-    {{template "button" .ButtonData}} {{/* Pass button-specific data to button template */}}
-</body>
-</html>
-`
-
 func init() {
-	// Define the components this page template uses
-	componentStrings := map[string]string{
-		"button": components.ButtonTmplString,
-		// Add other components here:
-		// "anotherComponent": components.AnotherComponentTmplString,
-	}
-
-	// Load the "home" template, providing its own string and the component strings
-	LoadTemplate("home", tmplString, componentStrings)
+	// Load the "home" template from its file, within the base layout. The components it
+	// uses, such as "button", are resolved from its {{template}} references.
+	LoadTemplateFile("home", "pages/home.html", Extends(baseLayout))
 }
 
 // Home prepares the home template for rendering with the given data.
@@ -0,0 +1,10 @@
+package templates
+
+// baseLayout is the HTML document shared by the pages, in layouts/base.html. Its sections
+// are:
+//
+//   - title, the title of the page;
+//   - head, extra elements of <head>, such as stylesheets;
+//   - content, the body of the page;
+//   - scripts, the scripts run once the content is loaded.
+var baseLayout = LoadLayoutFile("base", "layouts/base.html")
@@ -0,0 +1,31 @@
+package templates
+
+import (
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/client/templates/components"
+)
+
+// LoginPageData defines the structure of data expected by the login template.
+type LoginPageData struct {
+	Fields     []components.FormFieldProps
+	ButtonData components.ButtonProps
+	Next       string // Path to go back to once logged in
+	Error      string // Error of the previous attempt, if any
+}
+
+func init() {
+	LoadTemplateFile("login", "pages/login.html", Extends(baseLayout))
+}
+
+// Login prepares the login template for rendering with the given data.
+// The data parameter should be of type LoginPageData.
+// It panics if the "login" template is not found in the registry.
+func Login(data interface{}) *TemplateRenderer {
+	renderer, err := getRenderer("login", data)
+	if err != nil {
+		slog.Error("failed to get renderer for login template", "error", err)
+		panic("Failed to get renderer for login template: " + err.Error())
+	}
+	return renderer
+}
@@ -1,102 +1,629 @@
 package templates
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
 	"errors"
 	"html/template"
 	"io"
+	"io/fs"
 	"log/slog"
+	"os"
 	"sync"
+	"sync/atomic"
+	"text/template/parse"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/client/internal/security"
+	"github.com/supergeoff/go-starter/pkg/tracing"
 )
 
+// funcs are the functions available to every template:
+//
+//   - cspNonce returns the Content-Security-Policy nonce of the request, for inline
+//     <script nonce="{{cspNonce}}"> and <style> tags.
+var funcs = template.FuncMap{
+	"cspNonce": func() string { return noncePlaceholder },
+}
+
+// noncePlaceholder is what cspNonce renders, replaced with the nonce of the request by
+// RenderContext as the output is written: binding a function to a request would escape the
+// template again on every render. It is random, so that page data cannot forge it.
+var noncePlaceholder = "csp-nonce-" + rand.Text()
+
+// nonceWriter writes to w, replacing noncePlaceholder with nonce. Templates write the output
+// of an action at once, so that the placeholder is never split across writes.
+type nonceWriter struct {
+	w     io.Writer
+	nonce string
+}
+
+func (nw nonceWriter) Write(p []byte) (int, error) {
+	if !bytes.Contains(p, []byte(noncePlaceholder)) {
+		return nw.w.Write(p)
+	}
+	out := bytes.ReplaceAll(p, []byte(noncePlaceholder), []byte(nw.nonce))
+	if _, err := nw.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // TemplateRenderer is a struct that holds a specific template and data for rendering.
 type TemplateRenderer struct {
+	name     string
 	template *template.Template
 	data     interface{}
+	// err is why the template could not be reloaded, returned by Render instead of
+	// rendering it.
+	err error
+	// registry is the registry the template was looked up in, for Respond to render its
+	// error page.
+	registry *Registry
 }
 
 // Render executes the template with the associated data and writes to w.
 // It returns an error if the template execution fails.
 func (tr *TemplateRenderer) Render(w io.Writer) error {
+	return tr.RenderContext(context.Background(), w)
+}
+
+// RenderContext is like Render, but records a "template.render" span as a child of the
+// span carried by ctx, if any.
+func (tr *TemplateRenderer) RenderContext(ctx context.Context, w io.Writer) error {
+	_, span := tracing.StartSpan(ctx, "template.render",
+		tracing.WithAttributes(tracing.String("template.name", tr.name)))
+	defer span.End()
+
+	if tr.err != nil {
+		span.SetError(tr.err)
+		return tr.err
+	}
 	if tr.template == nil {
 		// This should ideally not be reached if template loading and retrieval are correct.
 		slog.Error("template is not initialized for renderer")
-		return errors.New("template is not initialized for renderer")
+		err := errors.New("template is not initialized for renderer")
+		span.SetError(err)
+		return err
+	}
+	err := tr.template.Execute(nonceWriter{w: w, nonce: security.Nonce(ctx)}, tr.data)
+	span.SetError(err)
+	return err
+}
+
+// ErrFrozen is returned when loading templates into a registry after Freeze, or switching
+// it to other template files.
+var ErrFrozen = errors.New("the template registry is frozen")
+
+// Registry manages named page templates, composed from their page, layout and component
+// templates. It is safe for concurrent use.
+//
+// Templates are loaded at start-up, then the registry is frozen with Freeze: lookups no
+// longer take a lock, unless the templates are reloaded from a directory, see UseDir.
+type Registry struct {
+	mu     sync.RWMutex
+	frozen atomic.Bool
+
+	layouts map[string]*Layout
+	// components are the sources of the component templates, by name.
+	components map[string]Source
+	// pages are the page templates, by name, and how they were composed, to compose them
+	// again when their files change.
+	pages map[string]*page
+	// fsys holds the template files, and reload is set when they are read from disk and
+	// may change while the application runs.
+	fsys   fs.FS
+	reload bool
+}
+
+// NewRegistry returns an empty Registry reading the template files from fsys, which may be
+// nil if all templates are strings.
+func NewRegistry(fsys fs.FS) *Registry {
+	return &Registry{
+		layouts:    make(map[string]*Layout),
+		components: make(map[string]Source),
+		pages:      make(map[string]*page),
+		fsys:       fsys,
 	}
-	return tr.template.Execute(w, tr.data)
 }
 
-// registry manages named templates.
-// It's an unexported struct as we'll use a global instance.
-type registry struct {
-	mu        sync.RWMutex
-	templates map[string]*template.Template
+// Source is the text of a page, component or layout: either the template string itself,
+// or the path of a template file.
+type Source struct {
+	text string
+	file bool
 }
 
-// globalRegistry is the single, global instance of our template registry.
-var globalRegistry = &registry{
-	templates: make(map[string]*template.Template),
+// Text returns the source of a template string.
+func Text(tmplString string) Source {
+	return Source{text: tmplString}
 }
 
-// LoadTemplate parses a page template string and any provided component template strings,
-// storing the resulting composite template with the given name in the global registry.
-// It panics if parsing fails or if the template name is already registered.
-func LoadTemplate(name string, pageTmplString string, componentTmplStrings map[string]string) {
-	globalRegistry.mu.Lock()
-	defer globalRegistry.mu.Unlock()
+// File returns the source of the template file at path, e.g. "pages/home.html", in the
+// files of the registry.
+func File(path string) Source {
+	return Source{text: path, file: true}
+}
+
+// read returns the template string of s, and the modification time of its file, if any.
+func (s Source) read(fsys fs.FS) (string, time.Time, error) {
+	if !s.file {
+		return s.text, time.Time{}, nil
+	}
+	if fsys == nil {
+		return "", time.Time{}, errors.New("no template files to read " + s.text + " from")
+	}
+	info, err := fs.Stat(fsys, s.text)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	b, err := fs.ReadFile(fsys, s.text)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return string(b), info.ModTime(), nil
+}
+
+// modTime returns the modification time of the file of s, or the zero time.
+func (s Source) modTime(fsys fs.FS) time.Time {
+	if !s.file {
+		return time.Time{}
+	}
+	info, err := fs.Stat(fsys, s.text)
+	if err != nil {
+		// Missing files are reported by the reload.
+		return time.Now()
+	}
+	return info.ModTime()
+}
+
+// page is a page template, as composed by Load.
+type page struct {
+	name   string
+	source Source
+	layout *Layout
+	// tmpl is the template it was last composed into, with components.
+	tmpl       *template.Template
+	components []component
+	// loadedAt is the modification time of the most recent of its files when it was last
+	// composed.
+	loadedAt time.Time
+}
+
+// sources returns the sources of p.
+func (p *page) sources() []Source {
+	var sources []Source
+	for _, c := range p.components {
+		sources = append(sources, c.source)
+	}
+	for _, layout := range p.layout.chain() {
+		sources = append(sources, layout.source)
+	}
+	return append(sources, p.source)
+}
 
-	if _, ok := globalRegistry.templates[name]; ok {
+// stale reports whether a file of p changed since it was composed.
+func (p *page) stale(fsys fs.FS) bool {
+	for _, s := range p.sources() {
+		if s.modTime(fsys).After(p.loadedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesFiles reports whether p is composed from a template file.
+func (p *page) usesFiles() bool {
+	for _, s := range p.sources() {
+		if s.file {
+			return true
+		}
+	}
+	return false
+}
+
+// Layout is a template shared by several pages, such as the document shell, which leaves
+// named sections to them with {{block "name" .}}default{{end}}. A page extending it
+// overrides the sections it needs with {{define "name"}}...{{end}}, and the page renders
+// as the layout with those sections.
+//
+// A layout may extend another one, e.g. a settings section inside the app shell. It then
+// only defines sections of its parent, in which it may declare sections of its own.
+type Layout struct {
+	name   string
+	source Source
+	parent *Layout
+}
+
+// Option configures a template or layout loaded by Load or LoadLayout.
+type Option func(*options)
+
+type options struct {
+	layout *Layout
+}
+
+// Extends makes the template or layout extend the layout l.
+func Extends(l *Layout) Option {
+	return func(o *options) { o.layout = l }
+}
+
+// chain returns l and the layouts it extends, from the outermost one.
+func (l *Layout) chain() []*Layout {
+	var layouts []*Layout
+	for ; l != nil; l = l.parent {
+		layouts = append([]*Layout{l}, layouts...)
+	}
+	return layouts
+}
+
+// LoadLayout parses a layout and registers it under the given name, for pages to extend.
+// It returns an error if parsing fails, if the layout name is already registered, or if
+// the layout extends another one and has content outside of {{define}}.
+func (r *Registry) LoadLayout(name string, src Source, opts ...Option) (*Layout, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.frozen.Load() {
+		slog.Error("layout loaded after the registry was frozen", "layout", name)
+		return nil, ErrFrozen
+	}
+	if _, ok := r.layouts[name]; ok {
+		slog.Error("layout with that name already exists", "layout", name)
+		return nil, errors.New("layout with that name already exists: " + name)
+	}
+	text, _, err := src.read(r.fsys)
+	if err == nil {
+		err = checkExtension(name, text, o.layout)
+	}
+	if err != nil {
+		slog.Error("failed to parse layout", "layout", name, "error", err)
+		return nil, errors.New("failed to parse layout '" + name + "': " + err.Error())
+	}
+
+	l := &Layout{name: name, source: src, parent: o.layout}
+	r.layouts[name] = l
+	slog.Info("layout loaded", "layout", name)
+	return l, nil
+}
+
+// checkExtension parses the template string of name on its own, and checks that it only
+// defines sections if it extends layout: content outside of {{define}} would replace the
+// document of the layout.
+func checkExtension(name, tmplString string, layout *Layout) error {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(tmplString)
+	if err != nil {
+		return err
+	}
+	if layout != nil && tmpl.Tree != nil && !parse.IsEmptyTree(tmpl.Tree.Root) {
+		return errors.New(
+			"it extends layout '" + layout.name + "' and must only {{define}} its sections",
+		)
+	}
+	return nil
+}
+
+// LoadComponent parses a component and registers each template it {{define}}s under its
+// name. Pages and layouts referencing one of them with {{template "name" .}} are composed
+// with it, and with the components it references in turn. It returns the names of the
+// registered templates.
+// It returns an error if parsing fails, if the component has content outside of
+// {{define}}, or if one of its templates is already registered.
+func (r *Registry) LoadComponent(src Source) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadComponent(src)
+}
+
+// LoadComponentFiles is like LoadComponent, for each template file matching pattern, in
+// the syntax of fs.Glob.
+func (r *Registry) LoadComponentFiles(pattern string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.frozen.Load() {
+		slog.Error("components loaded after the registry was frozen", "pattern", pattern)
+		return nil, ErrFrozen
+	}
+	if r.fsys == nil {
+		slog.Error("no template files to load components from", "pattern", pattern)
+		return nil, errors.New("no template files to load components " + pattern + " from")
+	}
+	paths, err := fs.Glob(r.fsys, pattern)
+	if err != nil {
+		slog.Error("invalid component files pattern", "pattern", pattern, "error", err)
+		return nil, errors.New(
+			"invalid component files pattern '" + pattern + "': " + err.Error(),
+		)
+	}
+	var names []string
+	for _, path := range paths {
+		loaded, err := r.loadComponent(File(path))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, loaded...)
+	}
+	return names, nil
+}
+
+// loadComponent registers the templates defined by src and returns their names. r.mu must
+// be held.
+func (r *Registry) loadComponent(src Source) ([]string, error) {
+	if r.frozen.Load() {
+		slog.Error("component loaded after the registry was frozen")
+		return nil, ErrFrozen
+	}
+	text, _, err := src.read(r.fsys)
+	var tmpl *template.Template
+	if err == nil {
+		tmpl, err = template.New("").Funcs(funcs).Parse(text)
+	}
+	var names []string
+	if err == nil {
+		names = defined(tmpl)
+		if tmpl.Tree != nil && !parse.IsEmptyTree(tmpl.Tree.Root) {
+			err = errors.New("it must only {{define}} templates")
+		} else if len(names) == 0 {
+			err = errors.New("it does not {{define}} any template")
+		}
+	}
+	if err != nil {
+		what := "component"
+		if src.file {
+			what += " '" + src.text + "'"
+		}
+		slog.Error("failed to parse component", "error", err)
+		return nil, errors.New("failed to parse " + what + ": " + err.Error())
+	}
+
+	for _, name := range names {
+		if _, ok := r.components[name]; ok {
+			slog.Error("component with that name already exists", "component", name)
+			return nil, errors.New("component with that name already exists: " + name)
+		}
+	}
+	for _, name := range names {
+		r.components[name] = src
+		slog.Info("component loaded", "component", name)
+	}
+	return names, nil
+}
+
+// Load parses a page, storing the resulting composite template under the given name. The
+// page is composed with the components it references, see LoadComponent, and if it extends
+// a layout, see Extends, with the layouts it extends.
+// It returns an error if parsing fails, if a referenced template is neither defined by the
+// page or its layouts nor a component, if components reference each other in a cycle, or
+// if the template name is already registered.
+func (r *Registry) Load(name string, src Source, opts ...Option) error {
+	p := &page{name: name, source: src}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p.layout = o.layout
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.frozen.Load() {
+		slog.Error("page template loaded after the registry was frozen", "template", name)
+		return ErrFrozen
+	}
+	if _, ok := r.pages[name]; ok {
 		slog.Error("page template with that name already exists", "template", name)
-		panic("Error: page template with that name already exists: " + name)
+		return errors.New("page template with that name already exists: " + name)
+	}
+	if err := r.compose(p); err != nil {
+		return err
+	}
+
+	r.pages[name] = p
+	slog.Info("page template loaded with components", "template", name)
+	return nil
+}
+
+// MustLoad is like Load, but panics if the page cannot be loaded. It is meant for pages
+// loaded at start-up, when an error is a programming error.
+func (r *Registry) MustLoad(name string, src Source, opts ...Option) {
+	if err := r.Load(name, src, opts...); err != nil {
+		panic("Error: " + err.Error())
+	}
+}
+
+// compose parses the components, layouts and page of p into a single template set, reading
+// their files from r.fsys, and records it in p with when it was composed and with which
+// components. r.mu must be held.
+func (r *Registry) compose(p *page) error {
+	var loadedAt time.Time
+	read := func(s Source) (string, error) {
+		text, modTime, err := s.read(r.fsys)
+		if modTime.After(loadedAt) {
+			loadedAt = modTime
+		}
+		return text, err
+	}
+
+	pageText, err := read(p.source)
+	if err == nil {
+		err = checkExtension(p.name, pageText, p.layout)
+	}
+	if err != nil {
+		slog.Error("failed to parse main page template string", "template", p.name, "error", err)
+		return errors.New("failed to parse main page template '" + p.name + "': " + err.Error())
+	}
+	layoutTexts := make([]string, 0, len(p.layout.chain()))
+	for _, layout := range p.layout.chain() {
+		text, err := read(layout.source)
+		if err != nil {
+			slog.Error("failed to read layout", "page", p.name, "layout", layout.name, "error", err)
+			return errors.New(
+				"failed to parse layout '" + layout.name + "' for page '" + p.name + "': " + err.Error(),
+			)
+		}
+		layoutTexts = append(layoutTexts, text)
+	}
+
+	// The components are those referenced by the page and its layouts, and by these
+	// components in turn.
+	components, err := r.resolve(p.name, append(layoutTexts, pageText), read)
+	if err != nil {
+		slog.Error("failed to resolve components", "page", p.name, "error", err)
+		return errors.New("failed to resolve components of page '" + p.name + "': " + err.Error())
 	}
 
 	// Create a new template. This will be the container for the page and its components.
-	tmpl := template.New(name)
-
-	// Parse all provided component template strings into this page's template set.
-	for componentName, componentStr := range componentTmplStrings {
-		// The Parse method adds the definitions from componentStr to tmpl.
-		// If componentStr contains {{define "compName"}}, "compName" becomes available.
-		var err error
-		tmpl, err = tmpl.Parse(componentStr) // Assign back to tmpl to ensure chaining
+	tmpl := template.New(p.name).Funcs(funcs)
+
+	// Parse the components into this page's template set.
+	for _, component := range components {
+		// The Parse method adds the definitions from the component to tmpl.
+		// If it contains {{define "compName"}}, "compName" becomes available.
+		text, err := read(component.source)
+		if err == nil {
+			tmpl, err = tmpl.Parse(text) // Assign back to tmpl to ensure chaining
+		}
 		if err != nil {
 			slog.Error("failed to parse component template into page template",
-				"page", name, "component", componentName, "error", err)
-			panic(
-				"Error: failed to parse component template '" + componentName + "' for page '" + name + "': " + err.Error(),
+				"page", p.name, "component", component.name, "error", err)
+			return errors.New(
+				"failed to parse component template '" + component.name + "' for page '" + p.name + "': " + err.Error(),
+			)
+		}
+		slog.Debug("parsed component into page template",
+			"page", p.name, "component", component.name)
+	}
+
+	// Then the layouts, from the outermost one: its document becomes the body of the
+	// template, and each layout redefines the sections of the one it extends.
+	for i, layout := range p.layout.chain() {
+		tmpl, err = tmpl.Parse(layoutTexts[i])
+		if err != nil {
+			slog.Error("failed to parse layout into page template",
+				"page", p.name, "layout", layout.name, "error", err)
+			return errors.New(
+				"failed to parse layout '" + layout.name + "' for page '" + p.name + "': " + err.Error(),
 			)
 		}
-		slog.Info(
-			"parsed component into page template",
-			"page",
-			name,
-			"component",
-			componentName,
-		)
 	}
 
 	// Now parse the main page template string itself into the same template set.
-	// This template (e.g., "home") can now refer to the components (e.g., "button").
-	var err error
-	tmpl, err = tmpl.Parse(pageTmplString) // Assign back to tmpl
+	// This template (e.g., "home") can now refer to the components (e.g., "button"), and
+	// overrides the sections of its layout.
+	tmpl, err = tmpl.Parse(pageText) // Assign back to tmpl
 	if err != nil {
-		slog.Error("failed to parse main page template string", "template", name, "error", err)
-		panic("Error: failed to parse main page template '" + name + "': " + err.Error())
+		slog.Error("failed to parse main page template string", "template", p.name, "error", err)
+		return errors.New("failed to parse main page template '" + p.name + "': " + err.Error())
+	}
+	p.tmpl, p.components, p.loadedAt = tmpl, components, loadedAt
+	return nil
+}
+
+// UseDir makes the registry read the template files from dir, e.g. the templates directory
+// of the sources during development, instead of the files it was created with. Templates
+// are composed again from dir, and later on whenever one of their files changes, on the
+// next lookup. It returns an error if a template cannot be composed, or ErrFrozen.
+func (r *Registry) UseDir(dir string) error {
+	return r.useFS(os.DirFS(dir), true)
+}
+
+// useFS makes r read the template files from fsys, checking them for changes on lookups if
+// reload is set, and composes again the templates that use files.
+func (r *Registry) useFS(fsys fs.FS, reload bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen.Load() {
+		slog.Error("template files changed after the registry was frozen")
+		return ErrFrozen
 	}
+	r.fsys, r.reload = fsys, reload
 
-	globalRegistry.templates[name] = tmpl
-	slog.Info("page template loaded with components", "template", name)
+	for _, p := range r.pages {
+		if !p.usesFiles() {
+			continue
+		}
+		if err := r.compose(p); err != nil {
+			return err
+		}
+	}
+	slog.Info("template files loaded", "reload", reload)
+	return nil
+}
+
+// Freeze ends the loading of templates: Load and the other loading methods return
+// ErrFrozen from then on, and lookups no longer take a lock, unless the templates are
+// reloaded from a directory.
+func (r *Registry) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen.Store(true)
 }
 
-// getRenderer retrieves a parsed template by name and prepares it for rendering with the given data.
-// This is an internal helper function.
-func getRenderer(name string, data interface{}) (*TemplateRenderer, error) {
-	globalRegistry.mu.RLock()
-	tmpl, ok := globalRegistry.templates[name]
-	globalRegistry.mu.RUnlock()
+// Lookup returns a renderer of the page template name with data. When the template files
+// may change, the template is composed again if one of its files did; if that fails, the
+// renderer returns the error instead of rendering.
+// It returns an error if no page template is registered under name.
+func (r *Registry) Lookup(name string, data interface{}) (*TemplateRenderer, error) {
+	// Once frozen, pages are neither added nor, without reloads, composed again.
+	if r.frozen.Load() && !r.reload {
+		p, ok := r.pages[name]
+		if !ok {
+			slog.Error("template not found in registry", "template", name)
+			return nil, errors.New("error: template not found in registry: " + name)
+		}
+		return &TemplateRenderer{name: name, template: p.tmpl, data: data, registry: r}, nil
+	}
+
+	r.mu.RLock()
+	p, ok := r.pages[name]
+	var tmpl *template.Template
+	stale := false
+	if ok {
+		tmpl, stale = p.tmpl, r.reload && p.stale(r.fsys)
+	}
+	r.mu.RUnlock()
 
 	if !ok {
 		slog.Error("template not found in registry", "template", name)
 		return nil, errors.New("error: template not found in registry: " + name)
 	}
-	return &TemplateRenderer{template: tmpl, data: data}, nil
+	if stale {
+		tmpl, err := r.recompose(p)
+		if err != nil {
+			return &TemplateRenderer{name: name, data: data, err: err, registry: r}, nil
+		}
+		return &TemplateRenderer{name: name, template: tmpl, data: data, registry: r}, nil
+	}
+	return &TemplateRenderer{name: name, template: tmpl, data: data, registry: r}, nil
+}
+
+// recompose composes p again if one of its files changed since, e.g. while another request
+// waited for the lock, and returns its template.
+func (r *Registry) recompose(p *page) (*template.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !p.stale(r.fsys) {
+		return p.tmpl, nil
+	}
+	if err := r.compose(p); err != nil {
+		return nil, err
+	}
+	slog.Info("template reloaded", "template", p.name)
+	return p.tmpl, nil
+}
+
+// Render renders the page template name with data to w, recording a span as a child of
+// the span carried by ctx, see TemplateRenderer.RenderContext.
+func (r *Registry) Render(ctx context.Context, w io.Writer, name string, data interface{}) error {
+	tr, err := r.Lookup(name, data)
+	if err != nil {
+		return err
+	}
+	return tr.RenderContext(ctx, w)
 }
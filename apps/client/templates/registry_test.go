@@ -2,13 +2,24 @@ package templates
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"html/template"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/client/internal/security"
+	"github.com/supergeoff/go-starter/apps/client/templates/components"
+	"github.com/supergeoff/go-starter/pkg/tracing"
 )
 
 // mockErrorWriter is an io.Writer that returns an error on Write.
@@ -18,19 +29,16 @@ func (mew *mockErrorWriter) Write(p []byte) (n int, err error) {
 	return 0, errors.New("mock writer error")
 }
 
-// resetGlobalRegistry is a helper function to clean up the global registry for tests.
-// This is important because LoadTemplate modifies global state and panics on duplicate names.
-func resetGlobalRegistryForTest() {
-	globalRegistry.mu.Lock()
-	defer globalRegistry.mu.Unlock()
-	globalRegistry.templates = make(map[string]*template.Template)
+// mustLookup returns a renderer of the page template name of r with data.
+func mustLookup(t *testing.T, r *Registry, name string, data interface{}) *TemplateRenderer {
+	t.Helper()
+	renderer, err := r.Lookup(name, data)
+	require.NoError(t, err, "Lookup should not fail")
+	require.NotNil(t, renderer, "Lookup should return a renderer")
+	return renderer
 }
 
 func TestTemplateRenderer_Render(t *testing.T) {
-	// Ensure a clean slate before and after this test suite if other tests modify globalRegistry.
-	// However, individual test cases will manage their own state.
-	// t.Cleanup(resetGlobalRegistryForTest) // Optional: for overall suite cleanup if needed.
-
 	tests := []struct {
 		name           string
 		setupRenderer  func(t *testing.T) *TemplateRenderer
@@ -38,44 +46,34 @@ func TestTemplateRenderer_Render(t *testing.T) {
 		expectedError  string
 		containsError  string // For errors where exact match is hard (like template execution errors)
 		expectedOutput string
-		cleanup        func() // Specific cleanup for the test case
 	}{
 		{
 			name: "successful render",
 			setupRenderer: func(t *testing.T) *TemplateRenderer {
-				resetGlobalRegistryForTest() // Clean before this test's setup
-				// Pass an empty map for componentTmplStrings as this test doesn't involve components
-				LoadTemplate("success_render_test", "Hello {{.Name}}", nil)
-				renderer, err := getRenderer(
+				r := NewRegistry(nil)
+				r.MustLoad("success_render_test", Text("Hello {{.Name}}"))
+				return mustLookup(
+					t,
+					r,
 					"success_render_test",
 					map[string]string{"Name": "RenderTest"},
 				)
-				require.NoError(t, err, "Setup: getRenderer should not fail")
-				require.NotNil(t, renderer, "Setup: renderer should not be nil")
-				return renderer
 			},
 			writer:         &bytes.Buffer{},
 			expectedOutput: "Hello RenderTest",
-			cleanup:        resetGlobalRegistryForTest, // Clean after this test
 		},
 		{
 			name: "render with component",
 			setupRenderer: func(t *testing.T) *TemplateRenderer {
-				resetGlobalRegistryForTest()
-				componentTmpl := `{{define "comp"}}Component: {{.Value}}{{end}}`
+				r := NewRegistry(nil)
+				_, err := r.LoadComponent(Text(`{{define "comp"}}Component: {{.Value}}{{end}}`))
+				require.NoError(t, err, "Setup: LoadComponent should not fail")
 				pageTmpl := `Page content. {{template "comp" .}}`
-				LoadTemplate("page_with_comp", pageTmpl, map[string]string{"comp": componentTmpl})
-				renderer, err := getRenderer(
-					"page_with_comp",
-					map[string]string{"Value": "TestValue"},
-				)
-				require.NoError(t, err, "Setup: getRenderer should not fail for component test")
-				require.NotNil(t, renderer, "Setup: renderer should not be nil for component test")
-				return renderer
+				r.MustLoad("page_with_comp", Text(pageTmpl))
+				return mustLookup(t, r, "page_with_comp", map[string]string{"Value": "TestValue"})
 			},
 			writer:         &bytes.Buffer{},
 			expectedOutput: "Page content. Component: TestValue",
-			cleanup:        resetGlobalRegistryForTest,
 		},
 		{
 			name: "nil template in renderer",
@@ -85,31 +83,21 @@ func TestTemplateRenderer_Render(t *testing.T) {
 			},
 			writer:        &bytes.Buffer{},
 			expectedError: "template is not initialized for renderer",
-			cleanup:       nil, // No global state modified that needs reset for this specific case
 		},
 		{
 			name: "template execution error due to writer error",
 			setupRenderer: func(t *testing.T) *TemplateRenderer {
-				resetGlobalRegistryForTest()
-				// Pass an empty map for componentTmplStrings as this test doesn't involve components
-				LoadTemplate("writer_error_render_test", "Content", nil)
-				renderer, err := getRenderer("writer_error_render_test", nil)
-				require.NoError(t, err, "Setup: getRenderer should not fail for writer error test")
-				require.NotNil(
-					t,
-					renderer,
-					"Setup: renderer should not be nil for writer error test",
-				)
-				return renderer
+				r := NewRegistry(nil)
+				r.MustLoad("writer_error_render_test", Text("Content"))
+				return mustLookup(t, r, "writer_error_render_test", nil)
 			},
 			writer:        &mockErrorWriter{},
 			containsError: "mock writer error", // This error comes from template.Execute when the writer fails
-			cleanup:       resetGlobalRegistryForTest,
 		},
 		{
 			name: "template execution error due to bad template action",
 			setupRenderer: func(t *testing.T) *TemplateRenderer {
-				// This test does not use the global registry, it creates a template directly.
+				// This test does not use a registry, it creates a template directly.
 				tmpl, err := template.New("bad_action_render_test").
 					Parse("Hello {{.NonExistentField}}")
 				require.NoError(
@@ -124,17 +112,11 @@ func TestTemplateRenderer_Render(t *testing.T) {
 			// The exact error message can be complex and might include line numbers,
 			// so we check for a key part of the error.
 			containsError: `executing "bad_action_render_test" at <.NonExistentField>: can't evaluate field NonExistentField`,
-			cleanup:       nil, // No global state modified
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			if tc.cleanup != nil {
-				// Defer cleanup to run after the test case finishes
-				defer tc.cleanup()
-			}
-
 			renderer := tc.setupRenderer(t)
 			require.NotNil(t, renderer, "Renderer should not be nil after setup")
 
@@ -155,3 +137,399 @@ func TestTemplateRenderer_Render(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateRenderer_RenderContextRecordsSpan(t *testing.T) {
+	r := NewRegistry(nil)
+	r.MustLoad("traced_render_test", Text("Hello {{.}}"))
+	renderer := mustLookup(t, r, "traced_render_test", "Span")
+
+	var spans bytes.Buffer
+	tracer := tracing.NewTracer("web", tracing.NewStdoutExporter(&spans))
+	ctx, parent := tracer.Start(context.Background(), "GET /")
+
+	var out bytes.Buffer
+	require.NoError(t, renderer.RenderContext(ctx, &out), "RenderContext should not fail")
+	parent.End()
+	require.NoError(t, tracer.Shutdown(context.Background()), "flushing spans")
+
+	assert.Equal(t, "Hello Span", out.String(), "Rendered output mismatch")
+	assert.Contains(t, spans.String(), `"name":"template.render"`, "render span should be exported")
+	assert.Contains(t, spans.String(), `"template.name":"traced_render_test"`,
+		"render span should name the template")
+	assert.Contains(
+		t,
+		spans.String(),
+		`"parent_span_id":"`+parent.SpanContext().SpanID.String()+`"`,
+		"render span should be a child of the request span",
+	)
+}
+
+func TestTemplateRenderer_CSPNonce(t *testing.T) {
+	r := NewRegistry(nil)
+	r.MustLoad("nonce_render_test", Text(`<script nonce="{{cspNonce}}">go()</script>`))
+
+	// Each request renders the template with its own nonce.
+	h := security.Headers(security.DefaultConfig())(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			require.NoError(t, r.Render(req.Context(), w, "nonce_render_test", nil),
+				"Render should not fail")
+		}),
+	)
+	var nonces []string
+	for range 2 {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var nonce string
+		for directive := range strings.SplitSeq(rec.Header().Get("Content-Security-Policy"), ";") {
+			if after, ok := strings.CutPrefix(strings.TrimSpace(directive), "script-src 'self' 'nonce-"); ok {
+				nonce = strings.TrimSuffix(after, "'")
+			}
+		}
+		require.NotEmpty(t, nonce, "the policy should carry a nonce")
+		assert.Equal(t, `<script nonce="`+nonce+`">go()</script>`, rec.Body.String(),
+			"the script should carry the nonce of the policy")
+		nonces = append(nonces, nonce)
+	}
+	assert.NotEqual(t, nonces[0], nonces[1], "requests should not share a nonce")
+
+	var out bytes.Buffer
+	require.NoError(t, mustLookup(t, r, "nonce_render_test", nil).Render(&out),
+		"Render should not fail")
+	assert.Equal(t, `<script nonce="">go()</script>`, out.String(),
+		"outside of a request, the nonce should be empty")
+}
+
+func TestRegistry_Layouts(t *testing.T) {
+	r := NewRegistry(nil)
+	shell, err := r.LoadLayout("shell", Text(
+		`<title>{{block "title" .}}App{{end}}</title><main>{{block "content" .}}{{end}}</main>`))
+	require.NoError(t, err, "Setup: LoadLayout should not fail")
+	settings, err := r.LoadLayout("settings", Text(
+		`{{define "content"}}<nav>{{template "button" .}}</nav>{{block "panel" .}}{{end}}{{end}}`),
+		Extends(shell))
+	require.NoError(t, err, "Setup: LoadLayout should not fail")
+	_, err = r.LoadComponent(Text(`{{define "button"}}<button>{{.}}</button>{{end}}`))
+	require.NoError(t, err, "Setup: LoadComponent should not fail")
+
+	tests := []struct {
+		name           string
+		page           string
+		opts           []Option
+		expectedOutput string
+	}{
+		{
+			name:           "sections overridden",
+			page:           `{{define "title"}}Home{{end}}{{define "content"}}<p>{{.}}</p>{{end}}`,
+			opts:           []Option{Extends(shell)},
+			expectedOutput: `<title>Home</title><main><p>Hi</p></main>`,
+		},
+		{
+			name:           "default section",
+			page:           `{{define "content"}}{{template "button" .}}{{end}}`,
+			opts:           []Option{Extends(shell)},
+			expectedOutput: `<title>App</title><main><button>Hi</button></main>`,
+		},
+		{
+			name:           "nested layouts",
+			page:           `{{define "title"}}Settings{{end}}{{define "panel"}}<form>{{.}}</form>{{end}}`,
+			opts:           []Option{Extends(settings)},
+			expectedOutput: `<title>Settings</title><main><nav><button>Hi</button></nav><form>Hi</form></main>`,
+		},
+		{
+			name:           "no layout",
+			page:           `<p>{{.}}</p>`,
+			expectedOutput: `<p>Hi</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, r.Load(tt.name, Text(tt.page), tt.opts...), "Load should not fail")
+			var out bytes.Buffer
+			require.NoError(t, r.Render(context.Background(), &out, tt.name, "Hi"),
+				"Render should not fail")
+			assert.Equal(t, tt.expectedOutput, out.String(), "Rendered output mismatch")
+		})
+	}
+}
+
+func TestRegistry_LayoutErrors(t *testing.T) {
+	r := NewRegistry(nil)
+	shell, err := r.LoadLayout("shell", Text(`<main>{{block "content" .}}{{end}}</main>`))
+	require.NoError(t, err, "Setup: LoadLayout should not fail")
+
+	_, err = r.LoadLayout("shell", Text(`<main></main>`))
+	assert.EqualError(t, err, "layout with that name already exists: shell",
+		"layout names should be unique")
+	_, err = r.LoadLayout("broken", Text(`{{block "content" .}}`))
+	assert.ErrorContains(t, err, "failed to parse layout 'broken'",
+		"invalid layouts should be refused")
+	assert.EqualError(t,
+		r.Load("stray_content", Text(`<p>lost</p>{{define "content"}}{{end}}`), Extends(shell)),
+		"failed to parse main page template 'stray_content': it extends layout 'shell' and "+
+			"must only {{define}} its sections",
+		"pages extending a layout should not have content outside of their sections")
+	_, err = r.LoadLayout("stray", Text(`<aside></aside>`), Extends(shell))
+	assert.ErrorContains(t, err, "must only {{define}} its sections",
+		"layouts extending a layout should not have content outside of their sections")
+}
+
+// loadComponents loads the component template strings into r.
+func loadComponents(t *testing.T, r *Registry, componentTmplStrings ...string) {
+	t.Helper()
+	for _, componentTmplString := range componentTmplStrings {
+		_, err := r.LoadComponent(Text(componentTmplString))
+		require.NoError(t, err, "Setup: LoadComponent should not fail")
+	}
+}
+
+func TestRegistry_Components(t *testing.T) {
+	r := NewRegistry(nil)
+	loadComponents(t, r,
+		`{{define "card"}}<div>{{template "title" .}}{{template "body" .}}</div>{{end}}`+
+			`{{define "body"}}<p>{{.}}</p>{{end}}`,
+		`{{define "title"}}<h2>{{.}}</h2>{{end}}`,
+		`{{define "list"}}{{range .}}{{template "item" .}}{{end}}{{end}}`,
+		`{{define "item"}}<li>{{if .}}{{template "badge" .}}{{end}}</li>{{end}}`,
+		`{{define "badge"}}<b>{{.}}</b>{{end}}`,
+		`{{define "unused"}}{{template "missing" .}}{{end}}`,
+	)
+
+	tests := []struct {
+		name           string
+		page           string
+		data           any
+		expectedOutput string
+	}{
+		{
+			name:           "transitive",
+			page:           `{{template "card" .}}`,
+			data:           "Hi",
+			expectedOutput: `<div><h2>Hi</h2><p>Hi</p></div>`,
+		},
+		{
+			name:           "nested actions",
+			page:           `<ul>{{with .}}{{template "list" .}}{{end}}</ul>`,
+			data:           []string{"a"},
+			expectedOutput: `<ul><li><b>a</b></li></ul>`,
+		},
+		{
+			name:           "defined by the page",
+			page:           `{{define "title"}}<h1>{{.}}</h1>{{end}}{{template "card" .}}`,
+			data:           "Hi",
+			expectedOutput: `<div><h1>Hi</h1><p>Hi</p></div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, r.Load(tt.name, Text(tt.page)), "Load should not fail")
+			var out bytes.Buffer
+			require.NoError(t, r.Render(context.Background(), &out, tt.name, tt.data),
+				"Render should not fail")
+			assert.Equal(t, tt.expectedOutput, out.String(), "Rendered output mismatch")
+		})
+	}
+
+	t.Run("only referenced components", func(t *testing.T) {
+		r.MustLoad("only", Text(`{{template "title" .}}`))
+		renderer := mustLookup(t, r, "only", nil)
+		assert.Nil(
+			t,
+			renderer.template.Lookup("card"),
+			"unreferenced components should be left out",
+		)
+	})
+}
+
+func TestRegistry_ComponentErrors(t *testing.T) {
+	r := NewRegistry(nil)
+	loadComponents(t, r,
+		`{{define "ping"}}{{template "pong" .}}{{end}}`,
+		`{{define "pong"}}{{template "ping" .}}{{end}}`,
+		`{{define "tree"}}{{range .}}{{template "tree" .}}{{end}}{{end}}`,
+		`{{define "broken"}}{{template "nowhere" .}}{{end}}`,
+	)
+	loadComponent := func(componentTmplString string) error {
+		_, err := r.LoadComponent(Text(componentTmplString))
+		return err
+	}
+
+	tests := []struct {
+		name          string
+		load          func() error
+		expectedError string
+	}{
+		{
+			name: "unknown reference",
+			load: func() error { return r.Load("unknown_ref", Text(`{{template "nowhere" .}}`)) },
+			expectedError: "failed to resolve components of page 'unknown_ref': " +
+				"unknown template 'nowhere' referenced by 'unknown_ref'",
+		},
+		{
+			name: "unknown reference of a component",
+			load: func() error { return r.Load("unknown_dep", Text(`{{template "broken" .}}`)) },
+			expectedError: "failed to resolve components of page 'unknown_dep': " +
+				"unknown template 'nowhere' referenced by 'broken'",
+		},
+		{
+			name: "circular reference",
+			load: func() error { return r.Load("cycle", Text(`{{template "ping" .}}`)) },
+			expectedError: "failed to resolve components of page 'cycle': " +
+				"circular reference between components: ping -> pong -> ping",
+		},
+		{
+			name:          "duplicate component",
+			load:          func() error { return loadComponent(`{{define "ping"}}{{end}}`) },
+			expectedError: "component with that name already exists: ping",
+		},
+		{
+			name:          "content outside of define",
+			load:          func() error { return loadComponent(`<p>{{define "stray"}}{{end}}`) },
+			expectedError: "failed to parse component: it must only {{define}} templates",
+		},
+		{
+			name:          "no template",
+			load:          func() error { return loadComponent(`{{/* nothing */}}`) },
+			expectedError: "failed to parse component: it does not {{define}} any template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.load(), tt.expectedError, "load error mismatch")
+		})
+	}
+
+	t.Run("recursive component", func(t *testing.T) {
+		assert.NoError(t, r.Load("recursive", Text(`{{template "tree" .}}`)),
+			"components should be allowed to reference themselves")
+	})
+}
+
+func TestRegistry_LoadFile(t *testing.T) {
+	r := NewRegistry(fstest.MapFS{
+		"layouts/shell.html": {Data: []byte(`<main>{{block "content" .}}{{end}}</main>`)},
+		"pages/hello.html": {
+			Data: []byte(`{{define "content"}}{{template "greeting" .}}{{end}}`),
+		},
+		"components/greet.html": {Data: []byte(`{{define "greeting"}}Hello {{.}}{{end}}`)},
+	})
+
+	shell, err := r.LoadLayout("shell", File("layouts/shell.html"))
+	require.NoError(t, err, "LoadLayout should not fail")
+	names, err := r.LoadComponentFiles("components/*.html")
+	require.NoError(t, err, "LoadComponentFiles should not fail")
+	assert.Equal(t, []string{"greeting"}, names,
+		"templates of the component files should be registered")
+	require.NoError(t, r.Load("hello", File("pages/hello.html"), Extends(shell)),
+		"Load should not fail")
+	var out bytes.Buffer
+	require.NoError(t, r.Render(context.Background(), &out, "hello", "files"),
+		"Render should not fail")
+	assert.Equal(t, "<main>Hello files</main>", out.String(), "Rendered output mismatch")
+
+	assert.EqualError(t, r.Load("missing", File("pages/missing.html")),
+		"failed to parse main page template 'missing': open pages/missing.html: "+
+			"file does not exist",
+		"missing files should be refused")
+	assert.ErrorContains(t, NewRegistry(nil).Load("no_files", File("pages/hello.html")),
+		"no template files", "registries without files should refuse them")
+}
+
+func TestRegistry_UseDir(t *testing.T) {
+	r := NewRegistry(fstest.MapFS{
+		"pages/hello.html": {Data: []byte(`embedded {{template "greeting" .}}`)},
+		"greet.html":       {Data: []byte(`{{define "greeting"}}Hello {{.}}{{end}}`)},
+	})
+	_, err := r.LoadComponentFiles("greet.html")
+	require.NoError(t, err, "Setup: LoadComponentFiles should not fail")
+	r.MustLoad("reload", File("pages/hello.html"))
+	r.MustLoad("string", Text("string"))
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "pages"), 0o755), "Setup: Mkdir should not fail")
+	modTime := time.Now().Add(-time.Hour)
+	write := func(path, text string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		require.NoError(
+			t,
+			os.WriteFile(path, []byte(text), 0o600),
+			"Setup: WriteFile should not fail",
+		)
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(path, modTime, modTime), "Setup: Chtimes should not fail")
+	}
+	render := func(name string) (string, error) {
+		t.Helper()
+		var out bytes.Buffer
+		err := r.Render(context.Background(), &out, name, "disk")
+		return out.String(), err
+	}
+	write("pages/hello.html", `on disk {{template "greeting" .}}`)
+	write("greet.html", `{{define "greeting"}}Hello {{.}}{{end}}`)
+
+	require.NoError(t, r.UseDir(dir), "UseDir should not fail")
+	// Reloads are checked for on lookups, which keep taking the lock once frozen.
+	r.Freeze()
+	out, err := render("reload")
+	require.NoError(t, err, "Render should not fail")
+	assert.Equal(t, "on disk Hello disk", out, "templates should be read from the directory")
+	out, err = render("string")
+	require.NoError(t, err, "Render should not fail")
+	assert.Equal(t, "string", out, "templates from strings should be kept")
+
+	write("greet.html", `{{define "greeting"}}Bye {{.}}{{end}}`)
+	out, err = render("reload")
+	require.NoError(t, err, "Render should not fail")
+	assert.Equal(t, "on disk Bye disk", out, "changed components should be reloaded")
+
+	write("pages/hello.html", `{{template "greeting" .}`)
+	_, err = render("reload")
+	assert.ErrorContains(t, err, "failed to parse main page template 'reload'",
+		"reload errors should be returned by Render")
+
+	write("pages/hello.html", `fixed {{template "greeting" .}}`)
+	out, err = render("reload")
+	require.NoError(t, err, "Render should not fail")
+	assert.Equal(t, "fixed Bye disk", out, "fixed templates should be reloaded")
+
+	other := NewRegistry(fstest.MapFS{"pages/hello.html": {Data: []byte("hello")}})
+	other.MustLoad("hello", File("pages/hello.html"))
+	assert.Error(t, other.UseDir(t.TempDir()), "UseDir should fail without the template files")
+}
+
+func TestRegistry_Freeze(t *testing.T) {
+	r := NewRegistry(fstest.MapFS{"greet.html": {Data: []byte(`{{define "greeting"}}{{end}}`)}})
+	r.MustLoad("hello", Text("Hello {{.}}"))
+	r.Freeze()
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(context.Background(), &out, "hello", "frozen"),
+		"Render should not fail")
+	assert.Equal(t, "Hello frozen", out.String(), "Rendered output mismatch")
+	_, err := r.Lookup("missing", nil)
+	assert.EqualError(t, err, "error: template not found in registry: missing",
+		"unknown templates should be reported")
+
+	assert.ErrorIs(t, r.Load("late", Text("late")), ErrFrozen, "Load should be refused")
+	_, err = r.LoadLayout("late", Text("late"))
+	assert.ErrorIs(t, err, ErrFrozen, "LoadLayout should be refused")
+	_, err = r.LoadComponentFiles("*.html")
+	assert.ErrorIs(t, err, ErrFrozen, "LoadComponentFiles should be refused")
+	assert.ErrorIs(t, r.UseDir(t.TempDir()), ErrFrozen, "UseDir should be refused")
+	assert.PanicsWithValue(t, "Error: "+ErrFrozen.Error(),
+		func() { r.MustLoad("late", Text("late")) }, "MustLoad should panic")
+}
+
+func TestGlobalRegistry(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, HealthStatus(components.ButtonProps{Text: "OK"}).Render(&out),
+		"Render should not fail")
+	assert.Contains(t, out.String(), "OK", "pages should be loaded from the embedded files")
+
+	assert.PanicsWithValue(t, "Error: page template with that name already exists: home",
+		func() { LoadTemplateFile("home", "pages/home.html") },
+		"the helpers should panic on errors")
+}
@@ -0,0 +1,158 @@
+package templates
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"text/template/parse"
+)
+
+// component is a component a page is composed with, by the name of the first of its
+// templates referenced.
+type component struct {
+	name   string
+	source Source
+}
+
+// resolve returns the components referenced by the template strings of name, and by these
+// components in turn, in the order they are first referenced. Templates defined by the
+// strings themselves take precedence over components. It reads the components with read.
+//
+// It returns an error if a referenced template is neither defined nor a component, or if
+// components reference each other in a cycle. r.mu must be held.
+func (r *Registry) resolve(
+	name string,
+	tmplStrings []string,
+	read func(Source) (string, error),
+) ([]component, error) {
+	res := resolver{
+		registry: r,
+		read:     read,
+		local:    make(map[string]bool),
+		done:     make(map[string]bool),
+		included: make(map[Source]bool),
+	}
+	var refs []string
+	for _, text := range tmplStrings {
+		tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		for _, defined := range defined(tmpl) {
+			res.local[defined] = true
+		}
+		refs = append(refs, references(tmpl)...)
+	}
+	for _, ref := range refs {
+		if err := res.visit(name, ref); err != nil {
+			return nil, err
+		}
+	}
+	return res.components, nil
+}
+
+// resolver walks the components referenced by a page, depth first.
+type resolver struct {
+	registry *Registry
+	read     func(Source) (string, error)
+	// local are the templates defined by the page and its layouts.
+	local map[string]bool
+	// path are the components being visited, from the first one referenced by the page.
+	path []string
+	done map[string]bool
+	// included are the sources of components, which may define several of them.
+	included   map[Source]bool
+	components []component
+}
+
+// visit resolves the template name referenced by the template from, and the components it
+// references in turn.
+func (res *resolver) visit(from, name string) error {
+	if res.local[name] || res.done[name] {
+		return nil
+	}
+	for i, visiting := range res.path {
+		if visiting == name {
+			cycle := append(res.path[i:len(res.path):len(res.path)], name)
+			return errors.New("circular reference between components: " +
+				strings.Join(cycle, " -> "))
+		}
+	}
+	src, ok := res.registry.components[name]
+	if !ok {
+		return errors.New("unknown template '" + name + "' referenced by '" + from + "'")
+	}
+
+	text, err := res.read(src)
+	var tmpl *template.Template
+	if err == nil {
+		tmpl, err = template.New(name).Funcs(funcs).Parse(text)
+	}
+	if err != nil {
+		return errors.New("failed to parse component '" + name + "': " + err.Error())
+	}
+	res.path = append(res.path, name)
+	for _, ref := range references(tmpl) {
+		// Templates of the same component may reference each other.
+		if tmpl.Lookup(ref) != nil {
+			continue
+		}
+		if err := res.visit(name, ref); err != nil {
+			return err
+		}
+	}
+	res.path = res.path[:len(res.path)-1]
+
+	res.done[name] = true
+	if !res.included[src] {
+		res.included[src] = true
+		res.components = append(res.components, component{name: name, source: src})
+	}
+	return nil
+}
+
+// defined returns the names of the templates {{define}}d in tmpl, other than tmpl itself.
+func defined(tmpl *template.Template) []string {
+	var names []string
+	for _, t := range tmpl.Templates() {
+		if t.Name() != tmpl.Name() {
+			names = append(names, t.Name())
+		}
+	}
+	return names
+}
+
+// references returns the names of the templates invoked with {{template}} by the templates
+// of tmpl, including the default content of its {{block}}s.
+func references(tmpl *template.Template) []string {
+	var names []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			names = appendReferences(names, t.Tree.Root)
+		}
+	}
+	return names
+}
+
+// appendReferences appends the names of the templates invoked by node and its children to
+// names.
+func appendReferences(names []string, node parse.Node) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return names
+		}
+		for _, child := range n.Nodes {
+			names = appendReferences(names, child)
+		}
+	case *parse.TemplateNode:
+		names = append(names, n.Name)
+	case *parse.IfNode:
+		names = appendReferences(appendReferences(names, n.List), n.ElseList)
+	case *parse.RangeNode:
+		names = appendReferences(appendReferences(names, n.List), n.ElseList)
+	case *parse.WithNode:
+		names = appendReferences(appendReferences(names, n.List), n.ElseList)
+	}
+	return names
+}
@@ -0,0 +1,74 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// maxPooledBuffer is the capacity above which a buffer is not put back into bufferPool,
+// so that a single large page does not keep its memory for good.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds the buffers pages are rendered into by Respond.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Respond writes the template rendered with its data as the HTML response to r, with
+// status. The template is rendered into a buffer first, so that nothing is written if it
+// fails: the error page of the registry, see ErrorPage, is written instead with a 500
+// status, or a plain text error if that fails too. Errors are logged with the logger of
+// the request.
+func (tr *TemplateRenderer) Respond(w http.ResponseWriter, r *http.Request, status int) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	logger := middleware.LoggerFromContext(r.Context())
+	err := tr.RenderContext(r.Context(), buf)
+	if err == nil {
+		writeHTML(w, status, buf)
+		return
+	}
+	logger.Error("Error rendering template", "template", tr.name, "error", err)
+
+	buf.Reset()
+	status = http.StatusInternalServerError
+	if err := tr.renderErrorPage(r, status, buf); err != nil {
+		logger.Error("Error rendering error page", "error", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	writeHTML(w, status, buf)
+}
+
+// renderErrorPage renders the error page of the registry of tr to buf, for a response to
+// r with status.
+func (tr *TemplateRenderer) renderErrorPage(r *http.Request, status int, buf *bytes.Buffer) error {
+	if tr.registry == nil {
+		return errors.New("template " + tr.name + " does not belong to a registry")
+	}
+	return tr.registry.Render(r.Context(), buf, ErrorPage, ErrorPageData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		RequestID:  middleware.GetRequestID(r.Context()),
+	})
+}
+
+// writeHTML writes buf as the HTML response, with status.
+func writeHTML(w http.ResponseWriter, status int, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	// The client may be gone, there is nothing left to do about it.
+	_, _ = w.Write(buf.Bytes())
+}
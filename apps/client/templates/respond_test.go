@@ -0,0 +1,126 @@
+package templates
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// failing is page data whose Fail method makes the template fail halfway.
+type failing struct{}
+
+func (failing) Fail() (string, error) { return "", errors.New("boom") }
+
+func TestTemplateRenderer_Respond(t *testing.T) {
+	tests := []struct {
+		name              string
+		errorPage         string
+		page              string
+		data              any
+		status            int
+		expectedStatus    int
+		expectedType      string
+		expectedBody      string
+		expectedNotInBody string
+	}{
+		{
+			name:           "rendered",
+			page:           `<p>{{.}}</p>`,
+			data:           "Created",
+			status:         http.StatusCreated,
+			expectedStatus: http.StatusCreated,
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody:   "<p>Created</p>",
+		},
+		{
+			name:              "error page",
+			errorPage:         `{{.Status}} {{.StatusText}} {{.RequestID}}`,
+			page:              `<p>partial {{.Fail}}</p>`,
+			data:              failing{},
+			status:            http.StatusOK,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedType:      "text/html; charset=utf-8",
+			expectedBody:      "500 Internal Server Error req-1",
+			expectedNotInBody: "partial",
+		},
+		{
+			name:              "no error page",
+			page:              `<p>partial {{.Fail}}</p>`,
+			data:              failing{},
+			status:            http.StatusOK,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedType:      "text/plain; charset=utf-8",
+			expectedBody:      "Internal Server Error\n",
+			expectedNotInBody: "partial",
+		},
+		{
+			name:              "failing error page",
+			errorPage:         `error {{.Missing}}`,
+			page:              `<p>partial {{.Fail}}</p>`,
+			data:              failing{},
+			status:            http.StatusOK,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedType:      "text/plain; charset=utf-8",
+			expectedBody:      "Internal Server Error\n",
+			expectedNotInBody: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(nil)
+			if tt.errorPage != "" {
+				r.MustLoad(ErrorPage, Text(tt.errorPage))
+			}
+			r.MustLoad("page", Text(tt.page))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(middleware.WithRequestID(req.Context(), "req-1"))
+			rec := httptest.NewRecorder()
+
+			mustLookup(t, r, "page", tt.data).Respond(rec, req, tt.status)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "status mismatch")
+			assert.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"),
+				"Content-Type mismatch")
+			assert.Equal(t, tt.expectedBody, rec.Body.String(), "body mismatch")
+			if tt.expectedNotInBody != "" {
+				assert.NotContains(t, rec.Body.String(), tt.expectedNotInBody,
+					"the failed page should not be written")
+			}
+		})
+	}
+}
+
+func TestTemplateRenderer_RespondContentLength(t *testing.T) {
+	r := NewRegistry(nil)
+	r.MustLoad("large", Text(`{{.}}`))
+	large := strings.Repeat("a", 2*maxPooledBuffer)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		mustLookup(t, r, "large", large).Respond(rec, req, http.StatusOK)
+		require.Equal(t, http.StatusOK, rec.Code, "status mismatch")
+		assert.Equal(t, large, rec.Body.String(), "body mismatch")
+		assert.Equal(t, strconv.Itoa(len(large)), rec.Header().Get("Content-Length"),
+			"Content-Length should be the size of the page")
+	}
+}
+
+func TestErrorPage(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, globalRegistry.Render(t.Context(), &out, ErrorPage, ErrorPageData{
+		Status:     http.StatusInternalServerError,
+		StatusText: http.StatusText(http.StatusInternalServerError),
+		RequestID:  "req-1",
+	}), "the error page should render")
+	assert.Contains(t, out.String(), "500 Internal Server Error", "status should be shown")
+	assert.Contains(t, out.String(), "req-1", "the request ID should be shown")
+}
@@ -0,0 +1,30 @@
+package templates
+
+import (
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/client/templates/components"
+)
+
+// SignupPageData defines the structure of data expected by the signup template.
+type SignupPageData struct {
+	Fields     []components.FormFieldProps
+	ButtonData components.ButtonProps
+	Error      string // Error of the previous attempt, if any
+}
+
+func init() {
+	LoadTemplateFile("signup", "pages/signup.html", Extends(baseLayout))
+}
+
+// Signup prepares the signup template for rendering with the given data.
+// The data parameter should be of type SignupPageData.
+// It panics if the "signup" template is not found in the registry.
+func Signup(data interface{}) *TemplateRenderer {
+	renderer, err := getRenderer("signup", data)
+	if err != nil {
+		slog.Error("failed to get renderer for signup template", "error", err)
+		panic("Failed to get renderer for signup template: " + err.Error())
+	}
+	return renderer
+}
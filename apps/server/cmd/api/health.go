@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/sse"
+)
+
+// healthEventsInterval is how often the readiness checks run while clients follow the
+// health events.
+const healthEventsInterval = 2 * time.Second
+
+// maxGoroutines is the goroutine count above which the server is considered leaking
+// and in need of a restart.
+const maxGoroutines = 10000
+
+// newHealthRegistry returns the registry holding the server's liveness and readiness checks.
+// The database, when given, is a critical readiness dependency.
+func newHealthRegistry(db *database.DB) *health.Registry {
+	checks := health.NewRegistry()
+	checks.MustRegister(health.Liveness, health.Check{
+		Name:     "goroutines",
+		Critical: true,
+		Func:     goroutineCheck(maxGoroutines),
+	})
+	checks.MustRegister(health.Readiness, health.Check{
+		Name:     "goroutines",
+		Critical: true,
+		Func:     goroutineCheck(maxGoroutines),
+	})
+	if db != nil {
+		checks.MustRegister(health.Readiness, health.Check{
+			Name:     "database",
+			Critical: true,
+			Timeout:  time.Second,
+			Func:     db.Check,
+		})
+	}
+	return checks
+}
+
+// goroutineCheck fails when more than limit goroutines are running.
+func goroutineCheck(limit int) health.CheckFunc {
+	return func(context.Context) error {
+		if n := runtime.NumGoroutine(); n > limit {
+			return fmt.Errorf("%d goroutines running, limit is %d", n, limit)
+		}
+		return nil
+	}
+}
+
+// probeEndpoint returns the handler of probe, described for the API documentation.
+func probeEndpoint(checks *health.Registry, probe health.Probe, summary string) *handlers.Endpoint {
+	report := reflect.TypeFor[contract.HealthReport]()
+	return handlers.Describe(checks.Handler(probe), handlers.Description{
+		Summary: summary,
+		Responses: []handlers.Response{
+			{Status: http.StatusOK, Type: report},
+			{Status: http.StatusServiceUnavailable, Type: report},
+		},
+	})
+}
+
+// healthEventsEndpoint returns the handler streaming the readiness reports of checks when
+// their status changes. The checks only run while clients follow the stream.
+func healthEventsEndpoint(checks *health.Registry) *handlers.Endpoint {
+	events := sse.NewBroker(
+		sse.WithRetry(time.Second),
+		sse.WithSource(func(ctx context.Context, b *sse.Broker) {
+			checks.Watch(ctx, health.Readiness, healthEventsInterval, func(report health.Report) {
+				data, err := json.Marshal(report)
+				if err != nil {
+					slog.Error("failed to encode health report", "error", err)
+					return
+				}
+				b.Publish(contract.EventHealth, string(data))
+			})
+		}),
+	)
+	return handlers.Describe(events, handlers.Description{
+		Summary: "Readiness reports, streamed when the status changes",
+		Responses: []handlers.Response{{
+			Status:      http.StatusOK,
+			Type:        reflect.TypeFor[contract.HealthReport](),
+			ContentType: sse.ContentType,
+		}},
+	})
+}
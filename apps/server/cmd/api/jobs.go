@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+	"github.com/supergeoff/go-starter/apps/server/internal/jobs"
+	"github.com/supergeoff/go-starter/contract"
+)
+
+// newQueue returns the background job queue, keeping jobs in db when it is not nil and in
+// memory otherwise. Job handlers are registered here, before the queue starts.
+func newQueue(cfg jobs.Config, db *database.DB) (*jobs.Queue, error) {
+	var store jobs.Store = jobs.NewMemoryStore()
+	if db != nil {
+		store = database.NewJobRepository(db)
+	}
+	q, err := jobs.New(cfg, store)
+	if err != nil {
+		slog.Error("Failed to create the job queue", "error", err)
+		return nil, err
+	}
+	return q, nil
+}
+
+// jobsEndpoint returns the handler reporting the state of q.
+func jobsEndpoint(q *jobs.Queue) *handlers.Endpoint {
+	return handlers.Handle(
+		func(ctx context.Context, _ struct{}) (contract.JobQueueState, error) {
+			stats, err := q.Stats(ctx)
+			if err != nil {
+				return contract.JobQueueState{}, err
+			}
+			state := contract.JobQueueState{
+				Counts:   make(map[string]int, len(stats.Counts)),
+				Workers:  stats.Workers,
+				Busy:     stats.Busy,
+				DeadJobs: make([]contract.DeadJob, 0, len(stats.Dead)),
+			}
+			for s, n := range stats.Counts {
+				state.Counts[string(s)] = n
+			}
+			for _, j := range stats.Dead {
+				state.DeadJobs = append(state.DeadJobs, contract.DeadJob{
+					ID:        j.ID,
+					Kind:      j.Kind,
+					Attempts:  j.Attempts,
+					LastError: j.LastError,
+					FailedAt:  j.UpdatedAt,
+				})
+			}
+			return state, nil
+		},
+		handlers.WithSummary("State of the background job queue"),
+	)
+}
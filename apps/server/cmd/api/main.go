@@ -1,26 +1,198 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/config"
+	"github.com/supergeoff/go-starter/apps/server/internal/cors"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
 	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+	"github.com/supergeoff/go-starter/apps/server/internal/hub"
+	"github.com/supergeoff/go-starter/apps/server/internal/jobs"
+	"github.com/supergeoff/go-starter/apps/server/internal/openapi"
+	"github.com/supergeoff/go-starter/apps/server/internal/ratelimit"
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+	"github.com/supergeoff/go-starter/pkg/metrics"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+	"github.com/supergeoff/go-starter/pkg/tracing"
 )
 
-// setupRouter configures and returns the chi router.
-func setupRouter() *chi.Mux {
+// setupRouter configures and returns the chi router for the given configuration.
+// A nil tracer disables tracing, a nil db leaves the database out of readiness, a nil
+// authn refuses every request to the protected routes, and a nil queue leaves out the
+// job queue endpoint.
+func setupRouter(
+	cfg config.Config,
+	tracer *tracing.Tracer,
+	db *database.DB,
+	authn *auth.Authenticator,
+	queue *jobs.Queue,
+) *chi.Mux {
+	limiter, err := ratelimit.New(cfg.RateLimit)
+	if err != nil {
+		panic("Error: invalid rate limit configuration: " + err.Error())
+	}
+	preAuthLimiter, err := ratelimit.NewPreAuth(cfg.RateLimit)
+	if err != nil {
+		panic("Error: invalid rate limit configuration: " + err.Error())
+	}
+	// The API document is public, so that tools served from anywhere can read it.
+	sharing, err := cors.New(cfg.CORS, cors.WithRoute("/openapi.json", cors.Config{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet},
+	}))
+	if err != nil {
+		panic("Error: invalid CORS configuration: " + err.Error())
+	}
+	// Browsers allowed to call the API may also open WebSocket connections to it.
+	realtime, err := hub.New(cfg.WebSocket, hub.WithOriginCheck(sharing.AllowOrigin))
+	if err != nil {
+		panic("Error: invalid WebSocket configuration: " + err.Error())
+	}
 	r := chi.NewRouter()
-	r.Get("/api", handlers.ApiHandler) // handler.ApiHandler is already tested separately
+	reg := metrics.NewRegistry()
+	metrics.RegisterRuntimeMetrics(reg)
+	r.Use(
+		middleware.RequestID,
+		tracing.Middleware(tracer),
+		middleware.Logger(slog.Default()),
+		metrics.Middleware(reg),
+		// Before the middlewares that may refuse requests, so that browsers can read why.
+		sharing.Middleware,
+		// Limited by address first, so that invalid credentials cannot cause unlimited API
+		// key lookups and signature checks, then identified, so that clients are limited by
+		// API key rather than address.
+		preAuthLimiter.Middleware,
+		authn.Identify,
+		limiter.Middleware,
+	)
+	r.Method(http.MethodGet, "/metrics", reg.Handler())
+	checks := newHealthRegistry(db)
+	r.Method(http.MethodGet, contract.PathLiveness,
+		probeEndpoint(checks, health.Liveness, "Liveness probe"))
+	r.Method(http.MethodGet, contract.PathReadiness,
+		probeEndpoint(checks, health.Readiness, "Readiness probe"))
+	r.Method(http.MethodGet, contract.PathHealthEvents, healthEventsEndpoint(checks))
+	// handler.ApiHandler is already tested separately
+	r.Method(http.MethodGet, contract.PathCheck, authn.Protect(handlers.ApiHandler))
+	r.Method(http.MethodGet, contract.PathWebSocket,
+		authn.Protect(handlers.Describe(realtime, handlers.Description{
+			Summary:   "WebSocket connection to the real-time hub",
+			Responses: []handlers.Response{{Status: http.StatusSwitchingProtocols}},
+			Problems:  true,
+		})))
+	if queue != nil {
+		r.Method(http.MethodGet, contract.PathJobs, authn.Protect(jobsEndpoint(queue)))
+	}
+
+	// Registered last, so that the document covers every route above.
+	doc, err := openapi.Generate(r, apiInfo)
+	if err != nil {
+		panic("Error: failed to generate the OpenAPI document: " + err.Error())
+	}
+	docHandler, err := openapi.Handler(doc)
+	if err != nil {
+		panic("Error: failed to encode the OpenAPI document: " + err.Error())
+	}
+	r.Method(http.MethodGet, "/openapi.json", docHandler)
 	return r
 }
 
 func main() {
-	r := setupRouter()
-	slog.Info("Server starting on :3000") // Added a log message
-	err := http.ListenAndServe(":3000", r)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run loads the configuration, serves until a stop signal is received and returns the
+// process exit code. With "openapi" as first argument, it writes the OpenAPI document
+// of the API to stdout instead.
+func run(args []string) int {
+	if len(args) > 0 && args[0] == "openapi" {
+		return writeOpenAPI(os.Stdout, args[1:])
+	}
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return lifecycle.ExitOK
+	}
 	if err != nil {
-		slog.Error("Server failed to start", "error", err)
-		panic("Server failed to start")
+		slog.Error("Failed to load configuration", "error", err)
+		return lifecycle.ExitUsage
+	}
+
+	db, err := openDatabase(context.Background(), cfg.Database)
+	if err != nil {
+		return lifecycle.ExitServeError
+	}
+
+	authn, err := newAuthenticator(cfg.Auth, db)
+	if err != nil {
+		_ = db.Close()
+		return lifecycle.ExitUsage
+	}
+
+	queue, err := newQueue(cfg.Jobs, db)
+	if err == nil {
+		err = queue.Start(context.Background())
+	}
+	if err != nil {
+		_ = db.Close()
+		return lifecycle.ExitServeError
+	}
+
+	tracer := tracing.New("api", cfg.Tracing)
+	srv := lifecycle.New(
+		cfg.Addr,
+		setupRouter(cfg, tracer, db, authn, queue),
+		lifecycle.WithShutdownTimeout(cfg.ShutdownTimeout),
+		lifecycle.WithTLS(cfg.TLS),
+	)
+	// Registered first so that it runs last, once the other hooks have ended their spans.
+	srv.OnShutdown("tracing", tracer.Shutdown)
+	srv.OnShutdown("database", db.Shutdown)
+	// Registered after the database so that running jobs finish before it closes.
+	srv.OnShutdown("jobs", queue.Shutdown)
+	scheduler := newScheduler(queue)
+	scheduler.Start()
+	// Registered last so that it runs first, and no task runs while the jobs drain.
+	srv.OnShutdown("schedule", scheduler.Shutdown)
+	slog.Info("Server starting", "addr", cfg.Addr, "tls", cfg.TLS.Enabled())
+	return lifecycle.ExitCode(srv.Run(context.Background()))
+}
+
+// openDatabase opens the database and, if configured to, applies pending migrations.
+func openDatabase(ctx context.Context, cfg database.Config) (*database.DB, error) {
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AutoMigrate {
+		m, err := database.NewDefaultMigrator(db)
+		if err == nil {
+			_, err = m.Up(ctx)
+		}
+		if err != nil {
+			slog.Error("Failed to migrate database", "error", err)
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// newAuthenticator returns the authenticator of the protected routes, looking API keys up
+// in db when it is not nil.
+func newAuthenticator(cfg auth.Config, db *database.DB) (*auth.Authenticator, error) {
+	var apiKeys auth.APIKeyStore
+	if db != nil {
+		apiKeys = database.NewAPIKeyRepository(db)
 	}
+	return auth.New(cfg, apiKeys)
 }
@@ -1,19 +1,42 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/config"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/apps/server/internal/jobs"
+	"github.com/supergeoff/go-starter/apps/server/internal/openapi"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+	"github.com/supergeoff/go-starter/apps/server/internal/websocket"
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
 )
 
 func TestSetupRouter(t *testing.T) {
-	r := setupRouter()
+	r := setupRouter(config.Default(), nil, nil, nil, nil)
 	require.NotNil(t, r, "setupRouter() should return a non-nil chi.Mux router")
 
-	var foundAPIGet bool
+	var foundAPIGet, foundLivez, foundReadyz bool
 
 	err := chi.Walk(
 		r,
@@ -25,12 +48,417 @@ func TestSetupRouter(t *testing.T) {
 				method,
 				route,
 			)
-			if method == "GET" && route == "/api" {
+			switch {
+			case method == http.MethodGet && route == "/api":
 				foundAPIGet = true
+			case method == http.MethodGet && route == "/livez":
+				foundLivez = true
+			case method == http.MethodGet && route == "/readyz":
+				foundReadyz = true
 			}
 			return nil
 		},
 	)
 	assert.NoError(t, err, "chi.Walk should not return an error")
 	assert.True(t, foundAPIGet, "Expected GET /api route to be registered in the router")
+	assert.True(t, foundLivez, "Expected GET /livez route to be registered in the router")
+	assert.True(t, foundReadyz, "Expected GET /readyz route to be registered in the router")
+}
+
+func TestRun_ConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "help requested", args: []string{"-h"}, expectedCode: lifecycle.ExitOK},
+		{name: "unknown flag", args: []string{"-unknown"}, expectedCode: lifecycle.ExitUsage},
+		{name: "invalid addr", args: []string{"-addr", "nope"}, expectedCode: lifecycle.ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCode, run(tt.args), "run returned wrong exit code")
+		})
+	}
+}
+
+func TestSetupRouter_HealthProbes(t *testing.T) {
+	r := setupRouter(config.Default(), nil, nil, nil, nil)
+
+	for _, path := range []string{"/livez", "/readyz"} {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code, "probe returned wrong status code")
+			var report health.Report
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report), "decoding report")
+			assert.Equal(t, health.StatusPass, report.Status, "probe should pass")
+			assert.NotEmpty(t, report.Checks, "probe should report its checks")
+		})
+	}
+}
+
+func TestSetupRouter_HealthEvents(t *testing.T) {
+	srv := httptest.NewServer(setupRouter(config.Default(), nil, nil, nil, nil))
+	defer srv.Close()
+
+	stream, err := contract.NewClient(srv.URL).HealthEvents(context.Background(), "")
+	require.NoError(t, err, "the health events should be streamed")
+	defer func() { _ = stream.Close() }()
+	event, err := stream.Next()
+	require.NoError(t, err, "the stream should start with the current report")
+	assert.NotEmpty(t, event.ID, "events should have an ID to resume after")
+	assert.Equal(t, health.StatusPass, event.Report.Status, "the report should pass")
+	assert.NotEmpty(t, event.Report.Checks, "the report should list its checks")
+}
+
+func TestSetupRouter_Metrics(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.HMACSecrets = []string{testSecret}
+	authn, err := newAuthenticator(cfg.Auth, nil)
+	require.NoError(t, err, "newAuthenticator should not fail")
+	r := setupRouter(cfg, nil, nil, authn, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, "svc"))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "metrics endpoint returned wrong status code")
+	assert.Contains(t, rr.Body.String(),
+		`http_requests_total{method="GET",route="/api",status="200"} 1`,
+		"metrics should count requests by route pattern")
+	assert.Contains(t, rr.Body.String(), "# TYPE go_goroutines gauge",
+		"metrics should include runtime metrics")
+}
+
+func TestSetupRouter_OpenAPI(t *testing.T) {
+	rr := httptest.NewRecorder()
+	setupRouter(config.Default(), nil, nil, nil, nil).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "openapi endpoint returned wrong status code")
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc), "decoding document")
+	for _, path := range []string{"/api", "/livez", "/readyz"} {
+		assert.Contains(t, doc.Paths, path, "document should describe %s", path)
+	}
+}
+
+// TestOpenAPI_DocumentIsUpToDate fails when the routes changed without running mage OpenAPI.
+func TestOpenAPI_DocumentIsUpToDate(t *testing.T) {
+	var buf bytes.Buffer
+	require.Equal(t, lifecycle.ExitOK, writeOpenAPI(&buf, nil), "writeOpenAPI should succeed")
+
+	committed, err := os.ReadFile(filepath.Join("..", "..", "openapi.json"))
+	require.NoError(t, err, "reading apps/server/openapi.json")
+	assert.Equal(t, string(committed), buf.String(),
+		"apps/server/openapi.json is stale, run mage OpenAPI")
+}
+
+func TestSetupRouter_ReadinessChecksDatabase(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(t.TempDir(), "api.db")
+	db, err := openDatabase(context.Background(), cfg.Database)
+	require.NoError(t, err, "openDatabase should open and migrate the database")
+	r := setupRouter(cfg, nil, db, nil, nil)
+
+	readiness := func() health.Report {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report), "decoding report")
+		return report
+	}
+
+	report := readiness()
+	assert.Equal(t, health.StatusPass, report.Status, "readiness should pass with the database")
+	statuses := make(map[string]health.Status)
+	for _, c := range report.Checks {
+		statuses[c.Name] = c.Status
+	}
+	assert.Equal(t, health.StatusPass, statuses["database"], "readiness should check the database")
+
+	require.NoError(t, db.Close(), "closing the database")
+	assert.Equal(t, health.StatusFail, readiness().Status,
+		"readiness should fail once the database is unreachable")
+}
+
+// testSecret is the HS256 secret of the tokens signed by signTestToken.
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// signTestToken returns an HS256 JWT for subject, valid for a minute.
+func signTestToken(t *testing.T, subject string) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	payload, err := json.Marshal(map[string]any{
+		"sub": subject,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	require.NoError(t, err, "encoding claims")
+	input := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(input))
+	return input + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestSetupRouter_APIRequiresAuthentication(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(t.TempDir(), "api.db")
+	cfg.Auth.HMACSecrets = []string{testSecret}
+	db, err := openDatabase(context.Background(), cfg.Database)
+	require.NoError(t, err, "openDatabase should open and migrate the database")
+	defer func() { _ = db.Close() }()
+	authn, err := newAuthenticator(cfg.Auth, db)
+	require.NoError(t, err, "newAuthenticator should not fail")
+	r := setupRouter(cfg, nil, db, authn, nil)
+
+	key, hash, err := auth.GenerateAPIKey()
+	require.NoError(t, err, "GenerateAPIKey should not fail")
+	require.NoError(t, database.NewAPIKeyRepository(db).Create(context.Background(),
+		&database.APIKey{Name: "web", Hash: hash}), "storing the API key")
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "no credentials", expectedStatus: http.StatusUnauthorized},
+		{
+			name:           "malformed token",
+			authorization:  "Bearer not-a-jwt",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown API key",
+			authorization:  "Bearer " + auth.APIKeyPrefix + "unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "valid JWT",
+			authorization:  "Bearer " + signTestToken(t, "svc"),
+			expectedStatus: http.StatusOK,
+		},
+		{name: "valid API key", authorization: "Bearer " + key, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, "unexpected status code")
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"),
+					"failures should be RFC 7807 problems")
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer",
+					"failures should carry a bearer challenge")
+			}
+		})
+	}
+}
+
+func TestSetupRouter_RateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.HMACSecrets = []string{testSecret}
+	cfg.RateLimit.Routes = []string{contract.PathCheck + " 2/1m token_bucket api_key"}
+	authn, err := newAuthenticator(cfg.Auth, nil)
+	require.NoError(t, err, "newAuthenticator should not fail")
+	r := setupRouter(cfg, nil, nil, authn, nil)
+
+	// send returns the status of a request for the check endpoint, from the same address.
+	send := func(subject string) int {
+		req := httptest.NewRequest(http.MethodGet, contract.PathCheck, nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, subject))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("alice"), "the first request should pass")
+	assert.Equal(t, http.StatusOK, send("alice"), "the second request should pass")
+	assert.Equal(
+		t,
+		http.StatusTooManyRequests,
+		send("alice"),
+		"the third request should be limited",
+	)
+	assert.Equal(t, http.StatusOK, send("bob"), "other clients should have their own quota")
+}
+
+// countingAPIKeys is an auth.APIKeyStore that knows no key and counts the lookups.
+type countingAPIKeys struct{ lookups atomic.Int32 }
+
+func (s *countingAPIKeys) ByHash(context.Context, string) (database.APIKey, error) {
+	s.lookups.Add(1)
+	return database.APIKey{}, database.ErrNotFound
+}
+
+func TestSetupRouter_RateLimitBeforeAuth(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.PreAuth = "3/1m token_bucket ip"
+	apiKeys := &countingAPIKeys{}
+	authn, err := auth.New(cfg.Auth, apiKeys)
+	require.NoError(t, err, "auth.New should not fail")
+	r := setupRouter(cfg, nil, nil, authn, nil)
+
+	var codes []int
+	for range 5 {
+		req := httptest.NewRequest(http.MethodGet, contract.PathCheck, nil)
+		req.Header.Set("Authorization", "Bearer "+auth.APIKeyPrefix+"made-up")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		codes = append(codes, rr.Code)
+	}
+	assert.Equal(t, []int{
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusTooManyRequests,
+		http.StatusTooManyRequests,
+	}, codes, "repeated bad API keys should be limited")
+	assert.EqualValues(t, 3, apiKeys.lookups.Load(),
+		"limited requests should not look up their API key")
+}
+
+func TestSetupRouter_CORS(t *testing.T) {
+	cfg := config.Default()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	r := setupRouter(cfg, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodOptions, contract.PathCheck, nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code, "the preflight should be answered")
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"),
+		"the configured origin should be allowed")
+
+	// Refusals carry the CORS headers too, so that browser code can read them.
+	req = httptest.NewRequest(http.MethodGet, contract.PathCheck, nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "the request should need credentials")
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"),
+		"the refusal should be readable by the origin")
+
+	req = httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("Origin", "https://docs.example.net")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"),
+		"the API document should be readable from any origin")
+}
+
+func TestSetupRouter_WebSocket(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.HMACSecrets = []string{testSecret}
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	authn, err := newAuthenticator(cfg.Auth, nil)
+	require.NoError(t, err, "newAuthenticator should not fail")
+	srv := httptest.NewServer(setupRouter(cfg, nil, nil, authn, nil))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + contract.PathWebSocket
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{name: "no credentials", header: http.Header{}, expectedStatus: http.StatusUnauthorized},
+		{
+			name: "origin not allowed",
+			header: http.Header{
+				"Authorization": {"Bearer " + signTestToken(t, "svc")},
+				"Origin":        {"https://evil.example.com"},
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "allowed origin",
+			header: http.Header{
+				"Authorization": {"Bearer " + signTestToken(t, "svc")},
+				"Origin":        {"https://app.example.com"},
+			},
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := websocket.Dial(context.Background(), url, tt.header)
+			require.NotNil(t, resp, "the server should answer: %v", err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "status mismatch")
+			if conn == nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+
+			err = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"join","room":"doc"}`))
+			require.NoError(t, err, "joining a room")
+			_, b, err := conn.ReadMessage()
+			require.NoError(t, err, "reading the presence")
+			assert.JSONEq(t, `{"type":"presence","room":"doc","members":["svc"]}`, string(b),
+				"the member should be identified by the subject of its token")
+		})
+	}
+}
+
+func TestSetupRouter_Jobs(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Auth.HMACSecrets = []string{testSecret}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "api.db")
+	db, err := openDatabase(ctx, cfg.Database)
+	require.NoError(t, err, "openDatabase should open and migrate the database")
+	t.Cleanup(func() { _ = db.Close() })
+	authn, err := newAuthenticator(cfg.Auth, nil)
+	require.NoError(t, err, "newAuthenticator should not fail")
+	queue, err := newQueue(cfg.Jobs, db)
+	require.NoError(t, err, "newQueue should not fail")
+	jobs.Register(queue, "broken", func(context.Context, struct{}) error {
+		return jobs.Permanent(errors.New("always down"))
+	})
+	require.NoError(t, queue.Start(ctx), "Start should not fail")
+	t.Cleanup(func() { _ = queue.Shutdown(ctx) })
+	_, err = queue.Enqueue(ctx, "broken", struct{}{})
+	require.NoError(t, err, "Enqueue should not fail")
+	_, err = queue.Enqueue(ctx, "broken", struct{}{}, jobs.After(time.Hour))
+	require.NoError(t, err, "Enqueue should not fail")
+
+	srv := httptest.NewServer(setupRouter(cfg, nil, db, authn, queue))
+	defer srv.Close()
+	_, err = contract.NewClient(srv.URL).JobQueue(ctx)
+	assert.ErrorContains(t, err, "Unauthorized", "the queue state should be protected")
+
+	client := contract.NewClient(srv.URL, contract.WithBearerToken(signTestToken(t, "svc")))
+	var state contract.JobQueueState
+	require.Eventually(t, func() bool {
+		state, err = client.JobQueue(ctx)
+		require.NoError(t, err, "JobQueue should not fail")
+		return len(state.DeadJobs) == 1
+	}, 5*time.Second, 10*time.Millisecond, "the failed job should be reported")
+	assert.Equal(t, map[string]int{"pending": 1, "dead": 1}, state.Counts, "counts mismatch")
+	assert.Equal(t, cfg.Jobs.Workers, state.Workers, "workers mismatch")
+	assert.Equal(t, "broken", state.DeadJobs[0].Kind, "dead job mismatch")
+	assert.Equal(t, "always down", state.DeadJobs[0].LastError, "the cause should be reported")
+}
+
+func TestNewScheduler(t *testing.T) {
+	queue, err := newQueue(config.Default().Jobs, nil)
+	require.NoError(t, err, "newQueue should not fail")
+	entries := newScheduler(queue).Entries()
+	require.Len(t, entries, 1, "the maintenance tasks should be scheduled")
+	assert.Equal(t, "jobs.prune", entries[0].Name, "task mismatch")
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/config"
+	"github.com/supergeoff/go-starter/apps/server/internal/openapi"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+// apiInfo is the metadata of the OpenAPI document.
+var apiInfo = openapi.Info{
+	Title:       "go-starter API",
+	Version:     "1.0.0",
+	Description: "HTTP API served by apps/server.",
+}
+
+// writeOpenAPI writes the OpenAPI document of the routes configured by args to w and
+// returns the process exit code.
+func writeOpenAPI(w io.Writer, args []string) int {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return lifecycle.ExitOK
+	}
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return lifecycle.ExitUsage
+	}
+
+	// The queue is not started: it only has to be there for its endpoint to be documented.
+	queue, err := newQueue(cfg.Jobs, nil)
+	if err != nil {
+		return lifecycle.ExitUsage
+	}
+	doc, err := openapi.Generate(setupRouter(cfg, nil, nil, nil, queue), apiInfo)
+	if err != nil {
+		return lifecycle.ExitServeError
+	}
+	body, err := doc.JSON()
+	if err == nil {
+		_, err = w.Write(body)
+	}
+	if err != nil {
+		slog.Error("Failed to write the OpenAPI document", "error", err)
+		return lifecycle.ExitServeError
+	}
+	return lifecycle.ExitOK
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/jobs"
+	"github.com/supergeoff/go-starter/apps/server/internal/schedule"
+)
+
+// newScheduler returns the scheduler of the periodic maintenance tasks of the server.
+func newScheduler(queue *jobs.Queue) *schedule.Scheduler {
+	s := schedule.New()
+	err := s.Add("jobs.prune", "@hourly", func(ctx context.Context) error {
+		n, err := queue.Prune(ctx)
+		if n > 0 {
+			slog.Info("Pruned succeeded jobs", "jobs", n)
+		}
+		return err
+	})
+	if err != nil {
+		panic("Error: invalid scheduled task: " + err.Error())
+	}
+	return s
+}
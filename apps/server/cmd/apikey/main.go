@@ -0,0 +1,145 @@
+// Command apikey creates, lists and revokes the API keys accepted by the API server.
+//
+// Usage:
+//
+//	apikey create <name> [scope...] [flags]
+//	apikey list [flags]
+//	apikey revoke <id> [flags]
+//
+// Flags, environment variables and the config file are those of the API server, e.g.
+// -database.path or API_DATABASE_PATH. create prints the new key once; only its hash is
+// stored, so it cannot be recovered later.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/config"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+const usage = "usage: apikey create <name> [scope...]|list|revoke <id> [flags]"
+
+func main() {
+	os.Exit(run(context.Background(), os.Stdout, os.Args[1:]))
+}
+
+// run executes the command given by args, writes its report to out and returns the process
+// exit code.
+func run(ctx context.Context, out io.Writer, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return lifecycle.ExitUsage
+	}
+	command, args := args[0], args[1:]
+	// Positional arguments come before the flags.
+	var operands []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		operands, args = append(operands, args[0]), args[1:]
+	}
+	var id int64
+	switch {
+	case command == "create" && len(operands) >= 1:
+	case command == "list" && len(operands) == 0:
+	case command == "revoke" && len(operands) == 1:
+		n, err := strconv.ParseInt(operands[0], 10, 64)
+		if err != nil || n <= 0 {
+			fmt.Fprintln(os.Stderr, "id must be a positive integer")
+			return lifecycle.ExitUsage
+		}
+		id = n
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return lifecycle.ExitUsage
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return lifecycle.ExitOK
+	}
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return lifecycle.ExitUsage
+	}
+
+	db, err := database.Open(ctx, cfg.Database)
+	if err != nil {
+		return lifecycle.ExitServeError
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("Failed to close database", "error", err)
+		}
+	}()
+	keys := database.NewAPIKeyRepository(db)
+
+	switch command {
+	case "create":
+		err = create(ctx, out, keys, operands[0], operands[1:])
+	case "list":
+		err = list(ctx, out, keys)
+	case "revoke":
+		if err = keys.Revoke(ctx, id); err == nil {
+			fmt.Fprintf(out, "revoked API key %d\n", id)
+		}
+	}
+	if err != nil {
+		slog.Error("API key command failed", "command", command, "error", err)
+		return lifecycle.ExitServeError
+	}
+	return lifecycle.ExitOK
+}
+
+// create stores a new key and prints it.
+func create(
+	ctx context.Context,
+	out io.Writer,
+	keys database.APIKeyRepository,
+	name string,
+	scopes []string,
+) error {
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	k := database.APIKey{Name: name, Hash: hash, Scopes: scopes}
+	if err := keys.Create(ctx, &k); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created API key %d (%s)\n%s\n", k.ID, k.Name, key)
+	return nil
+}
+
+// list writes one line per key.
+func list(ctx context.Context, out io.Writer, keys database.APIKeyRepository) error {
+	all, err := keys.List(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED AT\tREVOKED AT")
+	for _, k := range all {
+		scopes, revoked := strings.Join(k.Scopes, " "), "-"
+		if scopes == "" {
+			scopes = "-"
+		}
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, scopes, k.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return w.Flush()
+}
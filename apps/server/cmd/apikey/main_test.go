@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	cfg := database.Config{Path: filepath.Join(t.TempDir(), "api.db")}
+	db, err := database.Open(ctx, cfg)
+	require.NoError(t, err, "Open should not fail")
+	m, err := database.NewDefaultMigrator(db)
+	require.NoError(t, err, "NewDefaultMigrator should not fail")
+	_, err = m.Up(ctx)
+	require.NoError(t, err, "Up should not fail")
+	require.NoError(t, db.Close(), "Close should not fail")
+
+	dbFlag := []string{"-database.path", cfg.Path}
+	steps := []struct {
+		name            string
+		args            []string
+		expectedCode    int
+		expectedContent []string
+	}{
+		{name: "no command", expectedCode: lifecycle.ExitUsage},
+		{name: "unknown command", args: []string{"rotate"}, expectedCode: lifecycle.ExitUsage},
+		{name: "create without name", args: []string{"create"}, expectedCode: lifecycle.ExitUsage},
+		{name: "invalid id", args: []string{"revoke", "web"}, expectedCode: lifecycle.ExitUsage},
+		{
+			name:            "create",
+			args:            append([]string{"create", "web", "notes:read"}, dbFlag...),
+			expectedContent: []string{"created API key 1 (web)", auth.APIKeyPrefix},
+		},
+		{
+			name:         "create duplicate",
+			args:         append([]string{"create", "web"}, dbFlag...),
+			expectedCode: lifecycle.ExitServeError,
+		},
+		{
+			name:            "revoke",
+			args:            append([]string{"revoke", "1"}, dbFlag...),
+			expectedContent: []string{"revoked API key 1"},
+		},
+		{
+			name:            "list",
+			args:            append([]string{"list"}, dbFlag...),
+			expectedContent: []string{"ID", "web", "notes:read"},
+		},
+		{
+			name:         "revoke unknown",
+			args:         append([]string{"revoke", "42"}, dbFlag...),
+			expectedCode: lifecycle.ExitServeError,
+		},
+	}
+
+	// The steps share the database and run in order.
+	for _, tt := range steps {
+		var out bytes.Buffer
+		code := run(ctx, &out, tt.args)
+		assert.Equal(t, tt.expectedCode, code, "%s: run returned wrong exit code", tt.name)
+		for _, want := range tt.expectedContent {
+			assert.Contains(t, out.String(), want, "%s: output mismatch", tt.name)
+		}
+	}
+}
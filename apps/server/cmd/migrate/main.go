@@ -0,0 +1,121 @@
+// Command migrate applies, reverts and lists the database migrations of the API server.
+//
+// Usage:
+//
+//	migrate up [flags]
+//	migrate down [steps] [flags]
+//	migrate status [flags]
+//
+// Flags, environment variables and the config file are those of the API server, e.g.
+// -database.path or API_DATABASE_PATH. down reverts one migration unless told otherwise.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/config"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+const usage = "usage: migrate up|down [steps]|status [flags]"
+
+func main() {
+	os.Exit(run(context.Background(), os.Stdout, os.Args[1:]))
+}
+
+// run executes the command given by args, writes its report to out and returns the process
+// exit code.
+func run(ctx context.Context, out io.Writer, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return lifecycle.ExitUsage
+	}
+	command, args := args[0], args[1:]
+	steps := 1
+	if command == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n <= 0 {
+				fmt.Fprintln(os.Stderr, "steps must be positive")
+				return lifecycle.ExitUsage
+			}
+			steps, args = n, args[1:]
+		}
+	}
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, usage)
+		return lifecycle.ExitUsage
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return lifecycle.ExitOK
+	}
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return lifecycle.ExitUsage
+	}
+
+	db, err := database.Open(ctx, cfg.Database)
+	if err != nil {
+		return lifecycle.ExitServeError
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			slog.Error("Failed to close database", "error", err)
+		}
+	}()
+	m, err := database.NewDefaultMigrator(db)
+	if err != nil {
+		slog.Error("Failed to load migrations", "error", err)
+		return lifecycle.ExitServeError
+	}
+
+	switch command {
+	case "up":
+		var applied []database.Migration
+		applied, err = m.Up(ctx)
+		fmt.Fprintf(out, "applied %d migration(s)\n", len(applied))
+	case "down":
+		var reverted []database.Migration
+		reverted, err = m.Down(ctx, steps)
+		fmt.Fprintf(out, "reverted %d migration(s)\n", len(reverted))
+	case "status":
+		err = printStatus(ctx, out, m)
+	}
+	if err != nil {
+		slog.Error("Migration command failed", "command", command, "error", err)
+		return lifecycle.ExitServeError
+	}
+	return lifecycle.ExitOK
+}
+
+// printStatus writes one line per migration.
+func printStatus(ctx context.Context, out io.Writer, m *database.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, at, name := "pending", "-", s.Name
+		if s.Applied {
+			state, at = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Unknown {
+			name = "(unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, name, state, at)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+func TestRun(t *testing.T) {
+	dbFlag := []string{"-database.path", filepath.Join(t.TempDir(), "api.db")}
+	steps := []struct {
+		name            string
+		args            []string
+		expectedCode    int
+		expectedContent []string
+	}{
+		{name: "no command", expectedCode: lifecycle.ExitUsage},
+		{name: "unknown command", args: []string{"sideways"}, expectedCode: lifecycle.ExitUsage},
+		{name: "invalid steps", args: []string{"down", "0"}, expectedCode: lifecycle.ExitUsage},
+		{
+			name:            "status before up",
+			args:            append([]string{"status"}, dbFlag...),
+			expectedContent: []string{"VERSION", "1", "create_notes", "pending"},
+		},
+		{
+			name:            "up",
+			args:            append([]string{"up"}, dbFlag...),
+			expectedContent: []string{"applied 3 migration(s)"},
+		},
+		{
+			name:            "status after up",
+			args:            append([]string{"status"}, dbFlag...),
+			expectedContent: []string{"create_notes", "create_api_keys", "create_jobs", "applied"},
+		},
+		{
+			name:            "down",
+			args:            append([]string{"down", "1"}, dbFlag...),
+			expectedContent: []string{"reverted 1 migration(s)"},
+		},
+	}
+
+	// The steps share the database and run in order.
+	for _, tt := range steps {
+		var out bytes.Buffer
+		code := run(context.Background(), &out, tt.args)
+		assert.Equal(t, tt.expectedCode, code, "%s: run returned wrong exit code", tt.name)
+		for _, want := range tt.expectedContent {
+			assert.Contains(t, out.String(), want, "%s: output mismatch", tt.name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package auth authenticates the clients of the API server. Clients send either a JWT or an
+// opaque API key as a bearer token; the resulting Principal is attached to the request
+// context, and failures are answered with RFC 7807 problems.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from JWTs and makes leaked keys
+// easy to spot by secret scanners.
+const APIKeyPrefix = "gsk_"
+
+// ErrInvalidAPIKey is returned for API keys that are unknown or revoked.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// Method is the way a principal authenticated.
+type Method string
+
+// Authentication methods.
+const (
+	MethodJWT    Method = "jwt"
+	MethodAPIKey Method = "api_key"
+)
+
+// Principal is the authenticated client of a request.
+type Principal struct {
+	// Subject identifies the client: the sub claim of a JWT, or "api-key:<name>".
+	Subject string
+	Method  Method
+	Scopes  []string
+	// Claims are the claims of the JWT, nil for API keys.
+	Claims map[string]any
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal authenticated by the middleware, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// GenerateAPIKey returns a new random API key and the hash to store for it.
+func GenerateAPIKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hash under which key is stored. API keys are random, so a fast
+// hash is enough to make a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore looks up API keys by hash. database.APIKeyRepository implements it.
+type APIKeyStore interface {
+	ByHash(ctx context.Context, hash string) (database.APIKey, error)
+}
+
+// Authenticator verifies bearer tokens: JWTs with a Verifier, and API keys, recognized by
+// APIKeyPrefix, with an APIKeyStore.
+type Authenticator struct {
+	verifier *Verifier
+	apiKeys  APIKeyStore
+}
+
+// NewAuthenticator returns an Authenticator. A nil verifier refuses every JWT and nil
+// apiKeys refuse every API key.
+func NewAuthenticator(verifier *Verifier, apiKeys APIKeyStore) *Authenticator {
+	return &Authenticator{verifier: verifier, apiKeys: apiKeys}
+}
+
+// Authenticate returns the principal identified by token. It returns an error wrapping
+// ErrInvalidToken, ErrTokenExpired or ErrInvalidAPIKey for credentials that are refused,
+// and any other error when they could not be checked. A nil Authenticator refuses all
+// tokens.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (Principal, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		if a == nil || a.apiKeys == nil {
+			return Principal{}, ErrInvalidAPIKey
+		}
+		key, err := a.apiKeys.ByHash(ctx, HashAPIKey(token))
+		if errors.Is(err, database.ErrNotFound) || (err == nil && key.RevokedAt != nil) {
+			return Principal{}, ErrInvalidAPIKey
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{
+			Subject: "api-key:" + key.Name,
+			Method:  MethodAPIKey,
+			Scopes:  key.Scopes,
+		}, nil
+	}
+
+	if a == nil || a.verifier == nil {
+		return Principal{}, ErrInvalidToken
+	}
+	claims, err := a.verifier.Verify(token)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{
+		Subject: claims.Subject,
+		Method:  MethodJWT,
+		Scopes:  claims.Scopes,
+		Claims:  claims.Raw,
+	}, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header.
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
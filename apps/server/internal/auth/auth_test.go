@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// stubKeys is an APIKeyStore over a map of keys by hash.
+type stubKeys struct {
+	keys map[string]database.APIKey
+	err  error
+}
+
+func (s stubKeys) ByHash(_ context.Context, hash string) (database.APIKey, error) {
+	if s.err != nil {
+		return database.APIKey{}, s.err
+	}
+	k, ok := s.keys[hash]
+	if !ok {
+		return database.APIKey{}, database.ErrNotFound
+	}
+	return k, nil
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	require.NoError(t, err, "GenerateAPIKey should not fail")
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix), "key should start with the prefix")
+	assert.Equal(t, HashAPIKey(key), hash, "hash should be the hash of the key")
+
+	other, _, err := GenerateAPIKey()
+	require.NoError(t, err, "GenerateAPIKey should not fail")
+	assert.NotEqual(t, key, other, "keys should be random")
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := NewVerifier(keys.verifierKeys(t))
+	verifier.now = func() time.Time { return testNow }
+
+	revokedAt := testNow
+	apiKeys := stubKeys{keys: map[string]database.APIKey{
+		HashAPIKey("gsk_valid"):   {Name: "web", Scopes: []string{"notes:read"}},
+		HashAPIKey("gsk_revoked"): {Name: "old", RevokedAt: &revokedAt},
+	}}
+	expired := validClaims()
+	expired["exp"] = testNow.Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name              string
+		authn             *Authenticator
+		authorization     string
+		expectedStatus    int
+		expectedSubject   string
+		expectedChallenge string
+	}{
+		{
+			name:              "no credentials",
+			authn:             NewAuthenticator(verifier, apiKeys),
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api"`,
+		},
+		{
+			name:              "other scheme",
+			authn:             NewAuthenticator(verifier, apiKeys),
+			authorization:     "Basic dXNlcjpwYXNz",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api"`,
+		},
+		{
+			name:  "valid JWT",
+			authn: NewAuthenticator(verifier, apiKeys),
+			authorization: "Bearer " +
+				keys.sign(t, map[string]any{"alg": AlgEdDSA}, validClaims()),
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "svc",
+		},
+		{
+			name:  "expired JWT",
+			authn: NewAuthenticator(verifier, apiKeys),
+			authorization: "Bearer " +
+				keys.sign(t, map[string]any{"alg": AlgEdDSA}, expired),
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api", error="invalid_token", ` +
+				`error_description="the bearer token has expired"`,
+		},
+		{
+			name:            "valid API key",
+			authn:           NewAuthenticator(verifier, apiKeys),
+			authorization:   "bearer gsk_valid",
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "api-key:web",
+		},
+		{
+			name:           "revoked API key",
+			authn:          NewAuthenticator(verifier, apiKeys),
+			authorization:  "Bearer gsk_revoked",
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api", error="invalid_token", ` +
+				`error_description="the bearer token is not valid"`,
+		},
+		{
+			name:           "API keys disabled",
+			authn:          NewAuthenticator(verifier, nil),
+			authorization:  "Bearer gsk_valid",
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api", error="invalid_token", ` +
+				`error_description="the bearer token is not valid"`,
+		},
+		{
+			name: "key store failure",
+			authn: NewAuthenticator(
+				verifier,
+				stubKeys{err: errors.New("database is down")},
+			),
+			authorization:  "Bearer gsk_valid",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "nil authenticator",
+			authn: nil,
+			authorization: "Bearer " +
+				keys.sign(t, map[string]any{"alg": AlgEdDSA}, validClaims()),
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="api", error="invalid_token", ` +
+				`error_description="the bearer token is not valid"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			h := tt.authn.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, ok := PrincipalFromContext(r.Context())
+				require.True(t, ok, "the principal should be in the request context")
+				subject = p.Subject
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "status code mismatch")
+			assert.Equal(t, tt.expectedSubject, subject, "principal subject mismatch")
+			assert.Equal(t, tt.expectedChallenge, rec.Header().Get("WWW-Authenticate"),
+				"WWW-Authenticate mismatch")
+		})
+	}
+}
+
+func TestAuthenticator_Identify(t *testing.T) {
+	authn := NewAuthenticator(nil, stubKeys{keys: map[string]database.APIKey{
+		HashAPIKey("gsk_valid"): {Name: "web"},
+	}})
+
+	tests := []struct {
+		name            string
+		authorization   string
+		expectedSubject string
+	}{
+		{name: "no credentials"},
+		{name: "invalid credentials", authorization: "Bearer gsk_unknown"},
+		{
+			name:            "valid credentials",
+			authorization:   "Bearer gsk_valid",
+			expectedSubject: "api-key:web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			h := authn.Identify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, _ := PrincipalFromContext(r.Context())
+				subject = p.Subject
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code, "Identify should let every request through")
+			assert.Equal(t, tt.expectedSubject, subject, "principal subject mismatch")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Config selects the keys verifying JWTs and whether API keys are accepted. It is meant to be
+// embedded in the configuration of the API server.
+type Config struct {
+	JWKSFile       string        `config:"jwks_file"        usage:"JWKS file verifying JWTs"`
+	HMACSecrets    []string      `config:"hmac_secrets"     usage:"HS256 secrets, 32 bytes or more"`
+	PublicKeyFiles []string      `config:"public_key_files" usage:"PEM RS256 or EdDSA public keys"`
+	Issuer         string        `config:"issuer"           usage:"required iss claim, if any"`
+	Audience       string        `config:"audience"         usage:"required aud claim, if any"`
+	Leeway         time.Duration `config:"leeway"           usage:"clock skew tolerated on JWT expiry"`
+	APIKeys        bool          `config:"api_keys"         usage:"accept stored API keys"`
+}
+
+// DefaultConfig returns a configuration accepting API keys only, until JWT keys are set.
+func DefaultConfig() Config {
+	return Config{Leeway: 30 * time.Second, APIKeys: true}
+}
+
+// Validate checks that the configuration is usable. Key files are checked by New.
+func (c Config) Validate() error {
+	for _, secret := range c.HMACSecrets {
+		if len(secret) < MinHMACSecretLength {
+			return errors.New("auth.hmac_secrets must be at least 32 bytes long")
+		}
+	}
+	if c.Leeway < 0 {
+		return errors.New("auth.leeway must not be negative")
+	}
+	return nil
+}
+
+// Keys loads the JWT verification keys configured by c.
+func (c Config) Keys() ([]Key, error) {
+	var keys []Key
+	if c.JWKSFile != "" {
+		jwks, err := LoadJWKS(c.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwks...)
+	}
+	for _, secret := range c.HMACSecrets {
+		key, err := NewHMACKey("", []byte(secret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	for _, path := range c.PublicKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("Failed to read public key file", "path", path, "error", err)
+			return nil, err
+		}
+		key, err := ParsePublicKeyPEM("", data)
+		if err != nil {
+			slog.Error("Failed to parse public key file", "path", path, "error", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// New returns the Authenticator configured by cfg, looking API keys up in apiKeys.
+func New(cfg Config, apiKeys APIKeyStore) (*Authenticator, error) {
+	keys, err := cfg.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var verifier *Verifier
+	if len(keys) > 0 {
+		verifier = NewVerifier(keys,
+			WithIssuer(cfg.Issuer), WithAudience(cfg.Audience), WithLeeway(cfg.Leeway))
+	}
+	if !cfg.APIKeys {
+		apiKeys = nil
+	}
+	if verifier == nil && apiKeys == nil {
+		slog.Warn("No JWT key nor API key store configured, protected routes refuse every request")
+	}
+	return NewAuthenticator(verifier, apiKeys), nil
+}
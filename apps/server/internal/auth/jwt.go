@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or whose signature does not
+	// verify with any key.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned for well-signed tokens past their expiry.
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Claims are the verified claims of a JWT.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+	// Scopes come from the space-separated "scope" claim, or the "scp" array.
+	Scopes []string
+	// Raw holds every claim of the payload, numbers decoded as json.Number.
+	Raw map[string]any
+}
+
+// Verifier verifies JWS-signed JWTs in compact serialization.
+type Verifier struct {
+	keys     []Key
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// VerifierOption configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithIssuer requires the iss claim to equal issuer.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) { v.issuer = issuer }
+}
+
+// WithAudience requires the aud claim to contain audience.
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) { v.audience = audience }
+}
+
+// WithLeeway tolerates clock skew on the exp and nbf claims. The default is none.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) { v.leeway = leeway }
+}
+
+// NewVerifier returns a Verifier accepting tokens signed by any of keys.
+func NewVerifier(keys []Key, opts ...VerifierOption) *Verifier {
+	v := &Verifier{keys: keys, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// header is the JOSE header of a token.
+type header struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+// Verify checks the signature and the registered claims of token and returns its claims.
+// Tokens must carry exp and sub claims. Errors wrap ErrInvalidToken or ErrTokenExpired.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, invalid("not a compact JWS")
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, invalid("malformed header")
+	}
+	if len(h.Crit) > 0 {
+		return Claims{}, invalid("unsupported critical header parameters")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, invalid("malformed signature")
+	}
+	if !v.verifySignature(h, []byte(parts[0]+"."+parts[1]), sig) {
+		return Claims{}, invalid("signature does not verify")
+	}
+
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, invalid("malformed payload")
+	}
+	return v.checkClaims(raw)
+}
+
+// verifySignature reports whether sig is valid for input with a key matching h. The
+// algorithm comes from the key, never from the token alone, which defeats "none" and
+// algorithm confusion attacks.
+func (v *Verifier) verifySignature(h header, input, sig []byte) bool {
+	for _, k := range v.keys {
+		if k.Alg != h.Alg || (h.Kid != "" && k.ID != "" && k.ID != h.Kid) {
+			continue
+		}
+		switch key := k.verifier.(type) {
+		case []byte:
+			mac := hmac.New(sha256.New, key)
+			mac.Write(input)
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			digest := sha256.Sum256(input)
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, input, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkClaims validates the registered claims of raw and converts them.
+func (v *Verifier) checkClaims(raw map[string]any) (Claims, error) {
+	c := Claims{Raw: raw}
+	now := v.now()
+
+	exp, ok, err := numericDate(raw, "exp")
+	if err != nil || !ok {
+		return Claims{}, invalid("missing or malformed exp claim")
+	}
+	if !now.Before(exp.Add(v.leeway)) {
+		return Claims{}, ErrTokenExpired
+	}
+	c.ExpiresAt = exp
+	nbf, ok, err := numericDate(raw, "nbf")
+	if err != nil {
+		return Claims{}, invalid("malformed nbf claim")
+	}
+	if ok && now.Add(v.leeway).Before(nbf) {
+		return Claims{}, invalid("token not valid yet")
+	}
+	if c.IssuedAt, _, err = numericDate(raw, "iat"); err != nil {
+		return Claims{}, invalid("malformed iat claim")
+	}
+
+	c.Subject, _ = raw["sub"].(string)
+	if c.Subject == "" {
+		return Claims{}, invalid("missing sub claim")
+	}
+	c.Issuer, _ = raw["iss"].(string)
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return Claims{}, invalid("unexpected issuer")
+	}
+	c.Audience = stringList(raw["aud"])
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return Claims{}, invalid("unexpected audience")
+	}
+	if scope, ok := raw["scope"].(string); ok {
+		c.Scopes = strings.Fields(scope)
+	} else {
+		c.Scopes = stringList(raw["scp"])
+	}
+	return c, nil
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token into v.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// maxNumericDate bounds NumericDate claims, in seconds, well before they overflow time.Time.
+const maxNumericDate = 1e12
+
+// numericDate returns the NumericDate claim name, reporting false if it is absent.
+func numericDate(raw map[string]any, name string) (time.Time, bool, error) {
+	v, ok := raw[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, errors.New(name + " is not a number")
+	}
+	f, err := n.Float64()
+	if err != nil || f < 0 || f > maxNumericDate {
+		return time.Time{}, false, errors.New(name + " is out of range")
+	}
+	sec := math.Floor(f)
+	return time.Unix(int64(sec), int64((f-sec)*float64(time.Second))), true, nil
+}
+
+// stringList converts a claim that is either a string or an array of strings.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
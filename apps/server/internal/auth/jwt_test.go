@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testNow    = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	hmacSecret = []byte(strings.Repeat("k", MinHMACSecretLength))
+)
+
+// testKeys holds a private key of each supported algorithm.
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSAKeyBits)
+	require.NoError(t, err, "generating RSA key")
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, "generating Ed25519 key")
+	return testKeys{rsa: rsaKey, ed25519: edKey}
+}
+
+// sign returns a token with the given header and claims, signed with the key of alg.
+func (k testKeys) sign(t *testing.T, hdr map[string]any, claims map[string]any) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	h, err := json.Marshal(hdr)
+	require.NoError(t, err, "encoding header")
+	c, err := json.Marshal(claims)
+	require.NoError(t, err, "encoding claims")
+	input := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+
+	var sig []byte
+	switch hdr["alg"] {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, hmacSecret)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(input))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		require.NoError(t, err, "signing with RSA")
+	case AlgEdDSA:
+		sig = ed25519.Sign(k.ed25519, []byte(input))
+	}
+	return input + "." + enc.EncodeToString(sig)
+}
+
+// verifierKeys returns the verification keys matching k.
+func (k testKeys) verifierKeys(t *testing.T) []Key {
+	t.Helper()
+	hs, err := NewHMACKey("hs", hmacSecret)
+	require.NoError(t, err, "NewHMACKey should not fail")
+	rs, err := NewRSAKey("rs", &k.rsa.PublicKey)
+	require.NoError(t, err, "NewRSAKey should not fail")
+	ed, err := NewEd25519Key("ed", k.ed25519.Public().(ed25519.PublicKey))
+	require.NoError(t, err, "NewEd25519Key should not fail")
+	return []Key{hs, rs, ed}
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "svc",
+		"iss":   "https://issuer.test",
+		"aud":   []string{"api", "other"},
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"iat":   testNow.Unix(),
+		"scope": "notes:read notes:write",
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewVerifier(keys.verifierKeys(t),
+		WithIssuer("https://issuer.test"), WithAudience("api"), WithLeeway(time.Minute))
+	v.now = func() time.Time { return testNow }
+
+	with := func(name string, value any) map[string]any {
+		c := validClaims()
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+		return c
+	}
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"svc","exp":9999999999}`)) + "."
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedError error
+	}{
+		{name: "HS256", token: keys.sign(t, map[string]any{"alg": AlgHS256}, validClaims())},
+		{name: "RS256", token: keys.sign(t, map[string]any{"alg": AlgRS256}, validClaims())},
+		{name: "EdDSA", token: keys.sign(t, map[string]any{"alg": AlgEdDSA}, validClaims())},
+		{
+			name:  "matching kid",
+			token: keys.sign(t, map[string]any{"alg": AlgRS256, "kid": "rs"}, validClaims()),
+		},
+		{
+			name:          "unknown kid",
+			token:         keys.sign(t, map[string]any{"alg": AlgRS256, "kid": "x"}, validClaims()),
+			expectedError: ErrInvalidToken,
+		},
+		{name: "alg none", token: none, expectedError: ErrInvalidToken},
+		{name: "not a JWT", token: "abc", expectedError: ErrInvalidToken},
+		{
+			name: "critical header",
+			token: keys.sign(
+				t,
+				map[string]any{"alg": AlgHS256, "crit": []string{"b64"}},
+				validClaims(),
+			),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name: "expired beyond leeway",
+			token: keys.sign(
+				t,
+				map[string]any{"alg": AlgHS256},
+				with("exp", testNow.Add(-2*time.Minute).Unix()),
+			),
+			expectedError: ErrTokenExpired,
+		},
+		{
+			name: "expired within leeway",
+			token: keys.sign(
+				t,
+				map[string]any{"alg": AlgHS256},
+				with("exp", testNow.Add(-30*time.Second).Unix()),
+			),
+		},
+		{
+			name:          "missing exp",
+			token:         keys.sign(t, map[string]any{"alg": AlgHS256}, with("exp", nil)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name: "not valid yet",
+			token: keys.sign(
+				t,
+				map[string]any{"alg": AlgHS256},
+				with("nbf", testNow.Add(time.Hour).Unix()),
+			),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "missing subject",
+			token:         keys.sign(t, map[string]any{"alg": AlgHS256}, with("sub", nil)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name: "wrong issuer",
+			token: keys.sign(
+				t,
+				map[string]any{"alg": AlgHS256},
+				with("iss", "https://evil.test"),
+			),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "wrong audience",
+			token:         keys.sign(t, map[string]any{"alg": AlgHS256}, with("aud", "other")),
+			expectedError: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError, "Verify error mismatch")
+				return
+			}
+			require.NoError(t, err, "Verify should accept the token")
+			assert.Equal(t, "svc", claims.Subject, "subject mismatch")
+			assert.Equal(t, []string{"notes:read", "notes:write"}, claims.Scopes, "scopes mismatch")
+			assert.Equal(t, []string{"api", "other"}, claims.Audience, "audience mismatch")
+		})
+	}
+}
+
+func TestVerifier_TamperedToken(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewVerifier(keys.verifierKeys(t))
+	v.now = func() time.Time { return testNow }
+
+	token := keys.sign(t, map[string]any{"alg": AlgEdDSA}, validClaims())
+	parts := strings.Split(token, ".")
+	forged := validClaims()
+	forged["sub"] = "admin"
+	payload, err := json.Marshal(forged)
+	require.NoError(t, err, "encoding claims")
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+
+	_, err = v.Verify(strings.Join(parts, "."))
+	assert.ErrorIs(t, err, ErrInvalidToken, "a modified payload should not verify")
+}
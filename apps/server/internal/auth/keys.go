@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"os"
+)
+
+// Signature algorithms accepted in JWT headers.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Minimum key sizes. Shorter HMAC secrets can be brute-forced offline from a single token.
+const (
+	MinHMACSecretLength = 32
+	MinRSAKeyBits       = 2048
+)
+
+// Key is a key verifying the signature of JWTs of a single algorithm.
+type Key struct {
+	// ID is matched against the kid header of tokens. A key without ID is tried on every
+	// token of its algorithm.
+	ID  string
+	Alg string
+	// verifier is a []byte for HS256, an *rsa.PublicKey for RS256 and an
+	// ed25519.PublicKey for EdDSA.
+	verifier any
+}
+
+// NewHMACKey returns an HS256 key. The secret must be at least MinHMACSecretLength bytes.
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) < MinHMACSecretLength {
+		return Key{}, errors.New("auth: HMAC secrets must be at least 32 bytes long")
+	}
+	return Key{ID: id, Alg: AlgHS256, verifier: secret}, nil
+}
+
+// NewRSAKey returns an RS256 key. The key must be at least MinRSAKeyBits long.
+func NewRSAKey(id string, pub *rsa.PublicKey) (Key, error) {
+	if pub == nil || pub.N.BitLen() < MinRSAKeyBits {
+		return Key{}, errors.New("auth: RSA keys must be at least 2048 bits long")
+	}
+	return Key{ID: id, Alg: AlgRS256, verifier: pub}, nil
+}
+
+// NewEd25519Key returns an EdDSA key.
+func NewEd25519Key(id string, pub ed25519.PublicKey) (Key, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return Key{}, errors.New("auth: invalid Ed25519 public key")
+	}
+	return Key{ID: id, Alg: AlgEdDSA, verifier: pub}, nil
+}
+
+// ParsePublicKeyPEM parses a PEM-encoded PKIX public key, RSA or Ed25519, into a Key.
+func ParsePublicKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return Key{}, errors.New("auth: expected a PEM \"PUBLIC KEY\" block")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return Key{}, err
+	}
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return NewRSAKey(id, pub)
+	case ed25519.PublicKey:
+		return NewEd25519Key(id, pub)
+	default:
+		return Key{}, errors.New("auth: unsupported public key type, expected RSA or Ed25519")
+	}
+}
+
+// jwk is a JSON Web Key (RFC 7517), limited to the fields of the supported key types.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`   // oct
+	N   string `json:"n"`   // RSA
+	E   string `json:"e"`   // RSA
+	Crv string `json:"crv"` // OKP
+	X   string `json:"x"`   // OKP
+}
+
+// ParseJWKS parses a JSON Web Key Set. It supports oct (HS256), RSA (RS256) and OKP
+// Ed25519 (EdDSA) keys; keys of other types, or meant for encryption, are skipped.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.New("auth: decoding JWKS: " + err.Error())
+	}
+	var keys []Key
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, ok, err := k.key()
+		if err != nil {
+			return nil, errors.New("auth: JWKS key " + k.Kid + ": " + err.Error())
+		}
+		if !ok {
+			slog.Warn("Skipping unsupported JWKS key", "kid", k.Kid, "kty", k.Kty, "alg", k.Alg)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LoadJWKS reads and parses the JSON Web Key Set at path.
+func LoadJWKS(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("Failed to read JWKS file", "path", path, "error", err)
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// key converts the JWK into a Key. It reports false for unsupported key types.
+func (k jwk) key() (Key, bool, error) {
+	switch {
+	case k.Kty == "oct" && (k.Alg == "" || k.Alg == AlgHS256):
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return Key{}, false, errors.New("invalid k")
+		}
+		key, err := NewHMACKey(k.Kid, secret)
+		return key, true, err
+	case k.Kty == "RSA" && (k.Alg == "" || k.Alg == AlgRS256):
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return Key{}, false, errors.New("invalid n")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return Key{}, false, errors.New("invalid e")
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		key, err := NewRSAKey(k.Kid, pub)
+		return key, true, err
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && (k.Alg == "" || k.Alg == AlgEdDSA):
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return Key{}, false, errors.New("invalid x")
+		}
+		key, err := NewEd25519Key(k.Kid, ed25519.PublicKey(x))
+		return key, true, err
+	default:
+		return Key{}, false, nil
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	enc := base64.RawURLEncoding
+	jwks := `{"keys": [
+		{"kty": "oct", "kid": "hs", "k": "` + enc.EncodeToString(hmacSecret) + `"},
+		{"kty": "RSA", "kid": "rs", "alg": "RS256", "use": "sig",
+		 "n": "` + enc.EncodeToString(keys.rsa.N.Bytes()) + `",
+		 "e": "` + enc.EncodeToString(big.NewInt(int64(keys.rsa.E)).Bytes()) + `"},
+		{"kty": "OKP", "crv": "Ed25519", "kid": "ed",
+		 "x": "` + enc.EncodeToString(keys.ed25519.Public().(ed25519.PublicKey)) + `"},
+		{"kty": "EC", "crv": "P-256", "kid": "ec", "x": "AA", "y": "AA"},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AA", "e": "AQAB"}
+	]}`
+
+	parsed, err := ParseJWKS([]byte(jwks))
+	require.NoError(t, err, "ParseJWKS should not fail")
+	require.Len(t, parsed, 3, "ParseJWKS should skip unsupported and encryption keys")
+	assert.Equal(t, keys.verifierKeys(t), parsed, "parsed keys mismatch")
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "oct", "kid": "short", "k": "c2hvcnQ"}]}`))
+	assert.Error(t, err, "ParseJWKS should refuse short HMAC secrets")
+	_, err = ParseJWKS([]byte(`{`))
+	assert.Error(t, err, "ParseJWKS should refuse malformed JSON")
+}
+
+func TestParsePublicKeyPEM(t *testing.T) {
+	keys := newTestKeys(t)
+	der, err := x509.MarshalPKIXPublicKey(keys.ed25519.Public())
+	require.NoError(t, err, "encoding public key")
+
+	key, err := ParsePublicKeyPEM(
+		"ed",
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	)
+	require.NoError(t, err, "ParsePublicKeyPEM should not fail")
+	assert.Equal(t, AlgEdDSA, key.Alg, "algorithm mismatch")
+
+	_, err = ParsePublicKeyPEM(
+		"ed",
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	)
+	assert.Error(t, err, "ParsePublicKeyPEM should refuse other PEM blocks")
+}
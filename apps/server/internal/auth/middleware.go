@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// BearerScheme documents the credentials accepted by Protect in the API documentation.
+var BearerScheme = handlers.SecurityScheme{
+	Name:        "bearerAuth",
+	Scheme:      "bearer",
+	Description: "A JWT, or an API key starting with " + APIKeyPrefix,
+}
+
+// Identify puts the Principal of requests carrying a valid bearer token in their context,
+// and lets every request through. Mounted on the router, it lets the middlewares that run
+// before Middleware, such as rate limiting, tell clients apart.
+func (a *Authenticator) Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r.Header.Get("Authorization")); ok {
+			p, err := a.Authenticate(r.Context(), token)
+			if err == nil {
+				r = r.WithContext(WithPrincipal(r.Context(), p))
+			} else {
+				// Kept for Middleware, so that invalid credentials are only checked once.
+				r = r.WithContext(context.WithValue(r.Context(), failureKey{}, err))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// failureKey is the context key of the error Identify failed to authenticate a request
+// with.
+type failureKey struct{}
+
+// Middleware lets requests carrying a valid bearer token through, with their Principal in
+// the request context. Other requests get a 401 problem with a WWW-Authenticate challenge.
+// Requests already checked by Identify are not checked again.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PrincipalFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			problem.Write(w, r, problem.New(http.StatusUnauthorized,
+				"the request must carry a bearer token"))
+			return
+		}
+		err, checked := r.Context().Value(failureKey{}).(error)
+		var p Principal
+		if !checked {
+			p, err = a.Authenticate(r.Context(), token)
+		}
+		if err != nil {
+			detail := "the bearer token is not valid"
+			switch {
+			case errors.Is(err, ErrTokenExpired):
+				detail = "the bearer token has expired"
+			case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrInvalidAPIKey):
+			default:
+				// The credentials could not be checked, e.g. the database is down.
+				problem.Write(w, r, err)
+				return
+			}
+			w.Header().Set("WWW-Authenticate",
+				`Bearer realm="api", error="invalid_token", error_description="`+detail+`"`)
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, detail))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// Protect wraps e with Middleware, and adds BearerScheme to its description so that the
+// API documentation shows which endpoints require credentials.
+func (a *Authenticator) Protect(e *handlers.Endpoint) *handlers.Endpoint {
+	d := e.Description()
+	d.Security = append(d.Security, BearerScheme)
+	return handlers.Describe(a.Middleware(e), d)
+}
@@ -0,0 +1,95 @@
+// Package config defines the runtime configuration of the API server.
+package config
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/cors"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+	"github.com/supergeoff/go-starter/apps/server/internal/hub"
+	"github.com/supergeoff/go-starter/apps/server/internal/jobs"
+	"github.com/supergeoff/go-starter/apps/server/internal/ratelimit"
+	pkgconfig "github.com/supergeoff/go-starter/pkg/config"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+	"github.com/supergeoff/go-starter/pkg/tracing"
+)
+
+// EnvPrefix is the prefix of every environment variable read by the API server, e.g. API_ADDR.
+const EnvPrefix = "API"
+
+// Config holds the runtime configuration of the API server.
+type Config struct {
+	Addr            string              `config:"addr"             usage:"address the HTTP server listens on"`
+	ShutdownTimeout time.Duration       `config:"shutdown_timeout" usage:"drain deadline on shutdown"`
+	Tracing         tracing.Config      `config:"tracing"`
+	Database        database.Config     `config:"database"`
+	Auth            auth.Config         `config:"auth"`
+	RateLimit       ratelimit.Config    `config:"ratelimit"`
+	CORS            cors.Config         `config:"cors"`
+	TLS             lifecycle.TLSConfig `config:"tls"`
+	WebSocket       hub.Config          `config:"websocket"`
+	Jobs            jobs.Config         `config:"jobs"`
+}
+
+// Default returns the configuration used when no other source overrides a setting.
+func Default() Config {
+	return Config{
+		Addr:            ":3000",
+		ShutdownTimeout: 15 * time.Second,
+		Tracing:         tracing.DefaultConfig(),
+		Database:        database.DefaultConfig(),
+		Auth:            auth.DefaultConfig(),
+		RateLimit:       ratelimit.DefaultConfig(),
+		CORS:            cors.DefaultConfig(),
+		TLS:             lifecycle.DefaultTLSConfig(),
+		WebSocket:       hub.DefaultConfig(),
+		Jobs:            jobs.DefaultConfig(),
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return errors.New("addr must be a host:port address, got: " + c.Addr)
+	}
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("shutdown_timeout must be positive")
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+	if err := c.Database.Validate(); err != nil {
+		return err
+	}
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := c.CORS.Validate(); err != nil {
+		return err
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if err := c.WebSocket.Validate(); err != nil {
+		return err
+	}
+	return c.Jobs.Validate()
+}
+
+// Load resolves the configuration from defaults, the config file, API_* environment
+// variables and the given command-line arguments, in that order of precedence.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+	err := pkgconfig.Load(&cfg, pkgconfig.Options{
+		Name:      "api",
+		EnvPrefix: EnvPrefix,
+		Args:      args,
+	})
+	return cfg, err
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		args          []string
+		expectedAddr  string
+		containsError string
+	}{
+		{
+			name:         "defaults",
+			expectedAddr: ":3000",
+		},
+		{
+			name:         "environment",
+			env:          map[string]string{"API_ADDR": "127.0.0.1:8080"},
+			expectedAddr: "127.0.0.1:8080",
+		},
+		{
+			name:         "flag wins over environment",
+			env:          map[string]string{"API_ADDR": "127.0.0.1:8080"},
+			args:         []string{"-addr", ":9090"},
+			expectedAddr: ":9090",
+		},
+		{
+			name:          "invalid addr",
+			args:          []string{"-addr", "nope"},
+			containsError: "addr must be a host:port address",
+		},
+		{
+			name:          "invalid tracing exporter",
+			env:           map[string]string{"API_TRACING_EXPORTER": "jaeger"},
+			containsError: "tracing.exporter must be one of",
+		},
+		{
+			name:          "key without certificate",
+			env:           map[string]string{"API_TLS_KEY_FILE": "key.pem"},
+			containsError: "tls.cert_file and tls.key_file must be set together",
+		},
+		{
+			name:          "pong timeout shorter than ping interval",
+			env:           map[string]string{"API_WEBSOCKET_PONG_TIMEOUT": "1s"},
+			containsError: "websocket.pong_timeout must be longer",
+		},
+		{
+			name:          "no job workers",
+			env:           map[string]string{"API_JOBS_WORKERS": "0"},
+			containsError: "jobs.workers must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(tt.args)
+			if tt.containsError != "" {
+				assert.ErrorContains(t, err, tt.containsError, "Load error mismatch")
+				return
+			}
+			assert.NoError(t, err, "Load should not fail")
+			assert.Equal(t, tt.expectedAddr, cfg.Addr, "Addr mismatch")
+		})
+	}
+}
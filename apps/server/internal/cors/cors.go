@@ -0,0 +1,278 @@
+// Package cors implements Cross-Origin Resource Sharing, which lets browser code served from
+// other origins call the API. A CORS value answers preflight requests and adds the
+// Access-Control-* headers to the responses of allowed origins, with a default policy and
+// per-route overrides.
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegexPrefix starts the allowed origins that are regular expressions, e.g.
+// "regex:https://(app|admin)\.example\.com". The expression must match the whole origin,
+// regardless of case, like the other origins.
+const RegexPrefix = "regex:"
+
+// Config is a CORS policy. Allowed origins are exact, such as https://app.example.com, "*"
+// for any origin, wildcards such as https://*.example.com, where * matches one or more
+// subdomains, or regular expressions starting with RegexPrefix. It is meant to be embedded
+// in the configuration of the API server.
+type Config struct {
+	AllowedOrigins   []string      `config:"allowed_origins"   usage:"origins allowed to call the API"`
+	AllowedMethods   []string      `config:"allowed_methods"   usage:"methods allowed cross-origin"`
+	AllowedHeaders   []string      `config:"allowed_headers"   usage:"request headers allowed, * for any"`
+	ExposedHeaders   []string      `config:"exposed_headers"   usage:"response headers scripts can read"`
+	AllowCredentials bool          `config:"allow_credentials" usage:"allow cookies and credentials"`
+	MaxAge           time.Duration `config:"max_age"           usage:"preflight cache duration"`
+}
+
+// DefaultConfig returns a policy allowing no origin, whose other settings suit the API
+// once origins are added.
+func DefaultConfig() Config {
+	return Config{
+		AllowedMethods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+		ExposedHeaders: []string{
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+			"Retry-After", "WWW-Authenticate", "X-Request-ID",
+		},
+		MaxAge: 10 * time.Minute,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	_, err := compile(c)
+	return err
+}
+
+// policy is a compiled Config.
+type policy struct {
+	anyOrigin      bool
+	origins        []string
+	patterns       []*regexp.Regexp
+	methods        []string
+	anyHeader      bool
+	headers        []string
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+}
+
+// wildcard is what * matches in wildcard origins: one or more DNS labels.
+const wildcard = `[a-z0-9-]+(?:\.[a-z0-9-]+)*`
+
+// compile checks c and prepares it for matching.
+func compile(c Config) (policy, error) {
+	p := policy{
+		credentials:    c.AllowCredentials,
+		exposedHeaders: strings.Join(c.ExposedHeaders, ", "),
+	}
+	for _, o := range c.AllowedOrigins {
+		switch {
+		case o == "*":
+			p.anyOrigin = true
+		case strings.HasPrefix(o, RegexPrefix):
+			re, err := regexp.Compile("^(?i:" + strings.TrimPrefix(o, RegexPrefix) + ")$")
+			if err != nil {
+				return policy{}, errors.New("cors.allowed_origins: invalid regex: " + err.Error())
+			}
+			p.patterns = append(p.patterns, re)
+		default:
+			u, err := url.Parse(strings.ToLower(o))
+			if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				return policy{}, errors.New(
+					"cors.allowed_origins: expected scheme://host, got: " + o,
+				)
+			}
+			origin := u.Scheme + "://" + u.Host
+			if !strings.Contains(origin, "*") {
+				p.origins = append(p.origins, origin)
+				continue
+			}
+			expr := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, wildcard)
+			p.patterns = append(p.patterns, regexp.MustCompile("^(?i:"+expr+")$"))
+		}
+	}
+	if p.anyOrigin && p.credentials {
+		return policy{}, errors.New(
+			"cors.allow_credentials cannot be combined with the * origin, list the origins")
+	}
+	for _, m := range c.AllowedMethods {
+		p.methods = append(p.methods, strings.ToUpper(m))
+	}
+	for _, h := range c.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers = append(p.headers, http.CanonicalHeaderKey(h))
+	}
+	if c.MaxAge < 0 {
+		return policy{}, errors.New("cors.max_age must not be negative")
+	}
+	if c.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(c.MaxAge.Seconds()))
+	}
+	return p, nil
+}
+
+// allowOrigin reports whether origin may read the responses of the policy.
+func (p policy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	if slices.Contains(p.origins, strings.ToLower(origin)) {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOriginHeader returns the Access-Control-Allow-Origin value for an allowed origin.
+func (p policy) allowOriginHeader(origin string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	return origin
+}
+
+// allowHeaders reports whether every header of the comma-separated list may be sent.
+func (p policy) allowHeaders(list string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for h := range strings.SplitSeq(list, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !slices.Contains(p.headers, http.CanonicalHeaderKey(h)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CORS applies CORS policies to the requests of a chi router.
+type CORS struct {
+	def    policy
+	routes map[string]policy
+}
+
+// Option configures a CORS.
+type Option func(*CORS) error
+
+// WithRoute applies c instead of the default policy to the route registered with pattern,
+// e.g. "/openapi.json".
+func WithRoute(pattern string, c Config) Option {
+	return func(cs *CORS) error {
+		p, err := compile(c)
+		if err != nil {
+			return errors.New(pattern + ": " + err.Error())
+		}
+		cs.routes[pattern] = p
+		return nil
+	}
+}
+
+// New returns a CORS applying c to the routes without a policy of their own.
+func New(c Config, opts ...Option) (*CORS, error) {
+	def, err := compile(c)
+	if err != nil {
+		return nil, err
+	}
+	cs := &CORS{def: def, routes: make(map[string]policy)}
+	for _, opt := range opts {
+		if err := opt(cs); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// Middleware answers the preflight requests of allowed origins with 204 No Content, and adds
+// the CORS headers to the responses of allowed origins. Other OPTIONS requests, preflights
+// of refused origins included, reach next like any request. Refused requests get no CORS
+// headers, which makes the browser withhold the response. Every response varies by Origin,
+// so that caches do not serve the headers of one origin to another. A nil CORS allows no
+// origin.
+func (cs *CORS) Middleware(next http.Handler) http.Handler {
+	if cs == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		origin := r.Header.Get("Origin")
+		method := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || method == "" || origin == "" ||
+			!cs.policy(r, method).allowOrigin(origin) {
+			h.Add("Vary", "Origin")
+			if p := cs.policy(r, r.Method); origin != "" && p.allowOrigin(origin) {
+				h.Set("Access-Control-Allow-Origin", p.allowOriginHeader(origin))
+				if p.credentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if p.exposedHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Origin")
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		p := cs.policy(r, method)
+		headers := r.Header.Get("Access-Control-Request-Headers")
+		if slices.Contains(p.methods, method) && p.allowHeaders(headers) {
+			h.Set("Access-Control-Allow-Origin", p.allowOriginHeader(origin))
+			if p.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			h.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			}
+			if p.maxAge != "" {
+				h.Set("Access-Control-Max-Age", p.maxAge)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// AllowOrigin reports whether the policy of the route serving r lets the origin of r call
+// the API. Handlers that browsers reach without CORS checks, such as WebSocket upgrades,
+// use it to refuse other origins themselves. A nil CORS allows no origin.
+func (cs *CORS) AllowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if cs == nil || origin == "" {
+		return false
+	}
+	return cs.policy(r, r.Method).allowOrigin(origin)
+}
+
+// policy returns the policy of the route that serves method requests for the path of r.
+func (cs *CORS) policy(r *http.Request, method string) policy {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.Routes != nil {
+		pattern := rctx.Routes.Find(chi.NewRouteContext(), method, r.URL.Path)
+		if p, ok := cs.routes[pattern]; ok {
+			return p
+		}
+	}
+	return cs.def
+}
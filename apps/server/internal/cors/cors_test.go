@@ -0,0 +1,358 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter returns a router sharing its /api and /public routes as configured by c.
+func newTestRouter(t *testing.T, c *CORS) *chi.Mux {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Use(c.Middleware)
+	r.Get("/api", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("api")) })
+	r.Delete("/api", func(http.ResponseWriter, *http.Request) {})
+	r.Get(
+		"/public",
+		func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("public")) },
+	)
+	return r
+}
+
+func newTestCORS(t *testing.T) *CORS {
+	t.Helper()
+	c := DefaultConfig()
+	c.AllowedOrigins = []string{
+		"https://app.example.com",
+		"https://*.preview.example.com",
+		RegexPrefix + `https://(admin|ops)\.example\.org`,
+	}
+	c.ExposedHeaders = []string{"X-Request-ID"}
+	c.AllowCredentials = true
+	cs, err := New(c, WithRoute("/public", Config{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet},
+	}))
+	require.NoError(t, err, "New should not fail")
+	return cs
+}
+
+func TestCORS_SimpleRequests(t *testing.T) {
+	r := newTestRouter(t, newTestCORS(t))
+
+	tests := []struct {
+		name                string
+		path                string
+		origin              string
+		expectedAllowOrigin string
+		expectedCredentials string
+		expectedExpose      string
+	}{
+		{name: "same origin", path: "/api"},
+		{
+			name:                "exact origin",
+			path:                "/api",
+			origin:              "https://app.example.com",
+			expectedAllowOrigin: "https://app.example.com",
+			expectedCredentials: "true",
+			expectedExpose:      "X-Request-ID",
+		},
+		{
+			name:                "wildcard origin",
+			path:                "/api",
+			origin:              "https://pr-42.preview.example.com",
+			expectedAllowOrigin: "https://pr-42.preview.example.com",
+			expectedCredentials: "true",
+			expectedExpose:      "X-Request-ID",
+		},
+		{
+			name:   "wildcard does not match the bare domain",
+			path:   "/api",
+			origin: "https://preview.example.com",
+		},
+		{
+			name:                "regex origin",
+			path:                "/api",
+			origin:              "https://ops.example.org",
+			expectedAllowOrigin: "https://ops.example.org",
+			expectedCredentials: "true",
+			expectedExpose:      "X-Request-ID",
+		},
+		{
+			name:                "regex origin ignores case",
+			path:                "/api",
+			origin:              "https://Ops.Example.org",
+			expectedAllowOrigin: "https://Ops.Example.org",
+			expectedCredentials: "true",
+			expectedExpose:      "X-Request-ID",
+		},
+		{name: "regex is anchored", path: "/api", origin: "https://ops.example.org.evil.test"},
+		{name: "unknown origin", path: "/api", origin: "https://evil.test"},
+		{name: "other scheme", path: "/api", origin: "http://app.example.com"},
+		{
+			name:                "route override",
+			path:                "/public",
+			origin:              "https://evil.test",
+			expectedAllowOrigin: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			h := rec.Header()
+			assert.Equal(t, http.StatusOK, rec.Code, "the request should be served")
+			assert.Equal(t, tt.expectedAllowOrigin, h.Get("Access-Control-Allow-Origin"),
+				"Access-Control-Allow-Origin mismatch")
+			assert.Equal(t, tt.expectedCredentials, h.Get("Access-Control-Allow-Credentials"),
+				"Access-Control-Allow-Credentials mismatch")
+			assert.Equal(t, tt.expectedExpose, h.Get("Access-Control-Expose-Headers"),
+				"Access-Control-Expose-Headers mismatch")
+			assert.Equal(t, []string{"Origin"}, h.Values("Vary"), "Vary mismatch")
+		})
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	r := newTestRouter(t, newTestCORS(t))
+
+	tests := []struct {
+		name                string
+		path                string
+		origin              string
+		method              string
+		headers             string
+		expectedAllowOrigin string
+		expectedMethods     string
+		expectedHeaders     string
+		expectedMaxAge      string
+		expectedPassedOn    bool
+	}{
+		{
+			name:                "allowed",
+			path:                "/api",
+			origin:              "https://app.example.com",
+			method:              http.MethodDelete,
+			headers:             "authorization, content-type",
+			expectedAllowOrigin: "https://app.example.com",
+			expectedMethods:     "GET, POST, PUT, PATCH, DELETE",
+			expectedHeaders:     "authorization, content-type",
+			expectedMaxAge:      "600",
+		},
+		{
+			name:                "no headers requested",
+			path:                "/api",
+			origin:              "https://app.example.com",
+			method:              http.MethodGet,
+			expectedAllowOrigin: "https://app.example.com",
+			expectedMethods:     "GET, POST, PUT, PATCH, DELETE",
+			expectedMaxAge:      "600",
+		},
+		{
+			name:             "unknown origin",
+			path:             "/api",
+			origin:           "https://evil.test",
+			method:           http.MethodDelete,
+			expectedPassedOn: true,
+		},
+		{
+			name:   "method not allowed",
+			path:   "/api",
+			origin: "https://app.example.com",
+			method: "PROPFIND",
+		},
+		{
+			name:    "header not allowed",
+			path:    "/api",
+			origin:  "https://app.example.com",
+			method:  http.MethodGet,
+			headers: "Authorization, X-Debug",
+		},
+		{
+			name:                "route override",
+			path:                "/public",
+			origin:              "https://evil.test",
+			method:              http.MethodGet,
+			expectedAllowOrigin: "*",
+			expectedMethods:     "GET",
+		},
+		{
+			name:             "route override restricts methods",
+			path:             "/public",
+			origin:           "https://evil.test",
+			method:           http.MethodPost,
+			expectedPassedOn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if tt.expectedPassedOn {
+				assert.Equal(t, http.StatusMethodNotAllowed, rec.Code,
+					"refused preflights should reach the router")
+				assert.Empty(t, h.Get("Access-Control-Allow-Origin"),
+					"refused preflights should get no CORS headers")
+				assert.Equal(t, []string{"Origin"}, h.Values("Vary"), "Vary mismatch")
+				return
+			}
+			assert.Equal(t, http.StatusNoContent, rec.Code, "preflights should be answered")
+			assert.Empty(t, rec.Body.String(), "preflights should not reach the handler")
+			assert.Equal(t, tt.expectedAllowOrigin, h.Get("Access-Control-Allow-Origin"),
+				"Access-Control-Allow-Origin mismatch")
+			assert.Equal(t, tt.expectedMethods, h.Get("Access-Control-Allow-Methods"),
+				"Access-Control-Allow-Methods mismatch")
+			assert.Equal(t, tt.expectedHeaders, h.Get("Access-Control-Allow-Headers"),
+				"Access-Control-Allow-Headers mismatch")
+			assert.Equal(t, tt.expectedMaxAge, h.Get("Access-Control-Max-Age"),
+				"Access-Control-Max-Age mismatch")
+			assert.Equal(
+				t,
+				[]string{
+					"Origin",
+					"Access-Control-Request-Method",
+					"Access-Control-Request-Headers",
+				},
+				h.Values("Vary"),
+				"Vary mismatch",
+			)
+		})
+	}
+}
+
+func TestCORS_PlainOptionsRequest(t *testing.T) {
+	r := newTestRouter(t, newTestCORS(t))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{
+			name:    "no preflight method",
+			headers: map[string]string{"Origin": "https://app.example.com"},
+		},
+		{
+			name:    "no origin",
+			headers: map[string]string{"Access-Control-Request-Method": http.MethodGet},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusMethodNotAllowed, rec.Code,
+				"OPTIONS requests that are not preflights should reach the router")
+		})
+	}
+}
+
+func TestCORS_AllowOrigin(t *testing.T) {
+	cs := newTestCORS(t)
+	var allowed bool
+	r := chi.NewRouter()
+	r.Get("/api", func(_ http.ResponseWriter, r *http.Request) { allowed = cs.AllowOrigin(r) })
+	r.Get("/public", func(_ http.ResponseWriter, r *http.Request) { allowed = cs.AllowOrigin(r) })
+
+	tests := []struct {
+		name     string
+		path     string
+		origin   string
+		expected bool
+	}{
+		{name: "allowed origin", path: "/api", origin: "https://app.example.com", expected: true},
+		{name: "other origin", path: "/api", origin: "https://evil.example.com"},
+		{name: "no origin", path: "/api"},
+		{
+			name:     "route policy",
+			path:     "/public",
+			origin:   "https://evil.example.com",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			allowed = !tt.expected
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.expected, allowed, "AllowOrigin mismatch")
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	assert.False(t, (*CORS)(nil).AllowOrigin(req), "a nil CORS should allow no origin")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{name: "default", config: DefaultConfig()},
+		{name: "any origin", config: Config{AllowedOrigins: []string{"*"}}},
+		{
+			name:        "any origin with credentials",
+			config:      Config{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			expectError: true,
+		},
+		{
+			name:        "origin with a path",
+			config:      Config{AllowedOrigins: []string{"https://app.example.com/login"}},
+			expectError: true,
+		},
+		{
+			name:        "host without scheme",
+			config:      Config{AllowedOrigins: []string{"app.example.com"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid regex",
+			config:      Config{AllowedOrigins: []string{RegexPrefix + "("}},
+			expectError: true,
+		},
+		{name: "negative max age", config: Config{MaxAge: -time.Second}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectError {
+				assert.Error(t, err, "Validate should fail")
+			} else {
+				assert.NoError(t, err, "Validate should not fail")
+			}
+		})
+	}
+}
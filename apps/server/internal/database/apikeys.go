@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrConflict is returned by repositories when a record clashes with a unique one.
+var ErrConflict = errors.New("record already exists")
+
+// APIKey is an opaque credential of an API client. Only the hash of the key is stored; the
+// key itself is shown once, when it is created.
+type APIKey struct {
+	ID        int64
+	Name      string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	// RevokedAt is set once the key no longer grants access.
+	RevokedAt *time.Time
+}
+
+// APIKeyRepository stores API keys. Methods return ErrNotFound for missing keys.
+type APIKeyRepository interface {
+	// Create stores k and sets its ID and CreatedAt. It returns ErrConflict if the name or
+	// hash is already in use.
+	Create(ctx context.Context, k *APIKey) error
+	// ByHash returns the key with the given hash, revoked or not.
+	ByHash(ctx context.Context, hash string) (APIKey, error)
+	// List returns every key, oldest first.
+	List(ctx context.Context) ([]APIKey, error)
+	// Revoke marks the key id as revoked. Revoking a revoked key is not an error.
+	Revoke(ctx context.Context, id int64) error
+}
+
+// SQLAPIKeyRepository is the APIKeyRepository backed by the database.
+type SQLAPIKeyRepository struct {
+	db *DB
+}
+
+var _ APIKeyRepository = (*SQLAPIKeyRepository)(nil)
+
+// NewAPIKeyRepository returns an APIKeyRepository storing keys in db.
+func NewAPIKeyRepository(db *DB) *SQLAPIKeyRepository {
+	return &SQLAPIKeyRepository{db: db}
+}
+
+// Create implements APIKeyRepository.
+func (r *SQLAPIKeyRepository) Create(ctx context.Context, k *APIKey) error {
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO api_keys (name, key_hash, scopes, created_at) VALUES (?, ?, ?, ?)",
+		k.Name, k.Hash, strings.Join(k.Scopes, " "), formatTime(now))
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return ErrConflict
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	k.ID, k.CreatedAt, k.RevokedAt = id, now, nil
+	return nil
+}
+
+// ByHash implements APIKeyRepository.
+func (r *SQLAPIKeyRepository) ByHash(ctx context.Context, hash string) (APIKey, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT id, name, key_hash, scopes, created_at, revoked_at FROM api_keys
+		WHERE key_hash = ?`, hash)
+	k, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+// List implements APIKeyRepository.
+func (r *SQLAPIKeyRepository) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, name, key_hash, scopes, created_at, revoked_at FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke implements APIKeyRepository.
+func (r *SQLAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?",
+		formatTime(time.Now()), id)
+	return checkAffected(res, err)
+}
+
+func scanAPIKey(s scanner) (APIKey, error) {
+	var k APIKey
+	var scopes, createdAt string
+	var revokedAt sql.NullString
+	if err := s.Scan(&k.ID, &k.Name, &k.Hash, &scopes, &createdAt, &revokedAt); err != nil {
+		return APIKey{}, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	var err error
+	if k.CreatedAt, err = parseTime(createdAt); err != nil {
+		return APIKey{}, err
+	}
+	if revokedAt.Valid {
+		t, err := parseTime(revokedAt.String)
+		if err != nil {
+			return APIKey{}, err
+		}
+		k.RevokedAt = &t
+	}
+	return k, nil
+}
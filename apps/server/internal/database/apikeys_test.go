@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	keys := NewAPIKeyRepository(openMigratedTestDB(t))
+
+	web := APIKey{Name: "web", Hash: "hash-web", Scopes: []string{"read", "write"}}
+	require.NoError(t, keys.Create(ctx, &web), "Create should not fail")
+	assert.NotZero(t, web.ID, "Create should set the ID")
+	assert.False(t, web.CreatedAt.IsZero(), "Create should set CreatedAt")
+	assert.ErrorIs(t, keys.Create(ctx, &APIKey{Name: "web", Hash: "other"}), ErrConflict,
+		"Create should refuse a duplicate name")
+	assert.ErrorIs(t, keys.Create(ctx, &APIKey{Name: "other", Hash: "hash-web"}), ErrConflict,
+		"Create should refuse a duplicate hash")
+
+	got, err := keys.ByHash(ctx, "hash-web")
+	require.NoError(t, err, "ByHash should not fail")
+	assert.Equal(t, web, got, "ByHash should return the stored key")
+	_, err = keys.ByHash(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound, "ByHash should report unknown hashes")
+
+	require.NoError(t, keys.Revoke(ctx, web.ID), "Revoke should not fail")
+	got, err = keys.ByHash(ctx, "hash-web")
+	require.NoError(t, err, "ByHash should return revoked keys")
+	require.NotNil(t, got.RevokedAt, "Revoke should set RevokedAt")
+	revokedAt := *got.RevokedAt
+	require.NoError(t, keys.Revoke(ctx, web.ID), "Revoke should accept a revoked key")
+	got, err = keys.ByHash(ctx, "hash-web")
+	require.NoError(t, err, "ByHash should not fail")
+	assert.Equal(t, revokedAt, *got.RevokedAt, "revoking again should keep the first date")
+	assert.ErrorIs(t, keys.Revoke(ctx, 999), ErrNotFound, "Revoke should report unknown keys")
+
+	list, err := keys.List(ctx)
+	require.NoError(t, err, "List should not fail")
+	require.Len(t, list, 1, "List should return every key")
+	assert.Equal(t, "web", list[0].Name, "List returned the wrong key")
+}
@@ -0,0 +1,113 @@
+// Package database provides the storage of the API server: an embedded SQLite database,
+// the versioned migrations of its schema and the repositories built on it.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" driver.
+)
+
+// ErrNotFound is returned by repositories when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// Config configures the database. It is meant to be embedded in the configuration of the
+// API server.
+type Config struct {
+	Path        string `config:"path"         usage:"path of the SQLite database file"`
+	AutoMigrate bool   `config:"auto_migrate" usage:"apply pending migrations on start-up"`
+}
+
+// DefaultConfig returns the configuration used when no other source overrides a setting.
+func DefaultConfig() Config {
+	return Config{Path: "data/api.db", AutoMigrate: true}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if c.Path == "" {
+		return errors.New("database.path must not be empty")
+	}
+	return nil
+}
+
+// DB is a handle to the database, safe for concurrent use.
+type DB struct {
+	*sql.DB
+}
+
+// Open opens the SQLite database at cfg.Path, creating the file and its directory if
+// needed, and checks that it is reachable. Foreign keys are enforced, and the WAL journal
+// lets readers proceed while a write is in progress.
+func Open(ctx context.Context, cfg Config) (*DB, error) {
+	if dir := filepath.Dir(cfg.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			slog.Error("Failed to create database directory", "dir", dir, "error", err)
+			return nil, fmt.Errorf("failed to create database directory %s: %w", dir, err)
+		}
+	}
+
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Add("_txlock", "immediate")
+	dsn := "file:" + cfg.Path + "?" + query.Encode()
+
+	sqlDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		slog.Error("Failed to open database", "path", cfg.Path, "error", err)
+		return nil, fmt.Errorf("failed to open database %s: %w", cfg.Path, err)
+	}
+	// SQLite serializes writes; a small pool avoids contention on the database lock.
+	sqlDB.SetMaxOpenConns(4)
+	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		slog.Error("Failed to connect to database", "path", cfg.Path, "error", err)
+		return nil, fmt.Errorf("failed to connect to database %s: %w", cfg.Path, err)
+	}
+	return &DB{DB: sqlDB}, nil
+}
+
+// Check reports whether the database is reachable. It is meant to be registered as a
+// readiness check.
+func (db *DB) Check(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
+
+// Shutdown closes the database. It is meant to be registered as a shutdown hook.
+func (db *DB) Shutdown(context.Context) error {
+	return db.Close()
+}
+
+// InTx runs fn in a transaction, committed if fn returns nil and rolled back otherwise.
+func (db *DB) InTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.Error("Failed to roll back transaction", "error", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// timeLayout is how timestamps are stored: sortable text in UTC.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func formatTime(t time.Time) string { return t.UTC().Format(timeLayout) }
+
+func parseTime(s string) (time.Time, error) { return time.Parse(timeLayout, s) }
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB opens a database in a temporary file removed at the end of the test.
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(
+		context.Background(),
+		Config{Path: filepath.Join(t.TempDir(), "sub", "test.db")},
+	)
+	require.NoError(t, err, "opening test database")
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql": {Data: []byte(
+			"CREATE TABLE b (id INTEGER PRIMARY KEY, a_id INTEGER REFERENCES a (id));\n" +
+				"CREATE INDEX b_a_id ON b (a_id);")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+}
+
+func tableExists(t *testing.T, db *DB, name string) bool {
+	t.Helper()
+	var n int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).
+		Scan(&n)
+	require.NoError(t, err, "querying sqlite_master")
+	return n == 1
+}
+
+func versions(migrations []Migration) []int64 {
+	var v []int64
+	for _, m := range migrations {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m, err := NewMigrator(db, testMigrations())
+	require.NoError(t, err, "NewMigrator should accept valid migrations")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err, "Status should not fail")
+	assert.Equal(t, []bool{false, false}, []bool{statuses[0].Applied, statuses[1].Applied},
+		"nothing should be applied on a new database")
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err, "Up should not fail")
+	assert.Equal(t, []int64{1, 2}, versions(applied), "Up should apply migrations in order")
+	assert.True(t, tableExists(t, db, "b"), "table b should exist after Up")
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err, "Up should be idempotent")
+	assert.Empty(t, applied, "nothing should be pending")
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err, "Status should not fail")
+	require.Len(t, statuses, 2, "Status should list every migration")
+	assert.Equal(t, "create_b", statuses[1].Name, "status name mismatch")
+	assert.True(t, statuses[1].Applied, "migration 2 should be applied")
+	assert.False(t, statuses[1].AppliedAt.IsZero(), "applied migrations have a timestamp")
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err, "Down should not fail")
+	assert.Equal(t, []int64{2}, versions(reverted), "Down should revert the last migration")
+	assert.False(t, tableExists(t, db, "b"), "table b should be dropped")
+	assert.True(t, tableExists(t, db, "a"), "table a should be kept")
+
+	reverted, err = m.Down(ctx, 5)
+	require.NoError(t, err, "Down should not fail")
+	assert.Equal(t, []int64{1}, versions(reverted), "Down stops once everything is reverted")
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	fsys := testMigrations()
+	fsys["0003_broken.up.sql"] = &fstest.MapFile{
+		Data: []byte("CREATE TABLE c (id INTEGER PRIMARY KEY);\nNOT VALID SQL;"),
+	}
+	fsys["0003_broken.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;")}
+	m, err := NewMigrator(db, fsys)
+	require.NoError(t, err, "NewMigrator should not fail")
+
+	applied, err := m.Up(ctx)
+	assert.ErrorContains(
+		t,
+		err,
+		"failed to apply migration 3_broken",
+		"Up should report the failure",
+	)
+	assert.Equal(t, []int64{1, 2}, versions(applied), "earlier migrations stay applied")
+	assert.False(t, tableExists(t, db, "c"), "the failed migration should be rolled back")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err, "Status should not fail")
+	assert.False(t, statuses[2].Applied, "the failed migration should not be recorded")
+}
+
+func TestMigrator_UnknownAppliedVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	newer := testMigrations()
+	newer["0003_create_c.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id INTEGER);")}
+	newer["0003_create_c.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;")}
+	m, err := NewMigrator(db, newer)
+	require.NoError(t, err, "NewMigrator should not fail")
+	_, err = m.Up(ctx)
+	require.NoError(t, err, "Up should not fail")
+
+	older, err := NewMigrator(db, testMigrations())
+	require.NoError(t, err, "NewMigrator should not fail")
+	_, err = older.Up(ctx)
+	assert.ErrorContains(t, err, "which this build does not know",
+		"an older build should not migrate a newer database")
+
+	statuses, err := older.Status(ctx)
+	require.NoError(t, err, "Status should not fail")
+	require.Len(t, statuses, 3, "Status should list unknown versions")
+	assert.True(t, statuses[2].Unknown, "version 3 should be reported as unknown")
+}
+
+func TestNewMigrator_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         fstest.MapFS
+		containsError string
+	}{
+		{
+			name:          "invalid name",
+			files:         fstest.MapFS{"create_a.sql": {}},
+			containsError: "invalid migration file name",
+		},
+		{
+			name:          "missing down",
+			files:         fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 1;")}},
+			containsError: "needs both an up and a down file",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			containsError: "has several names",
+		},
+		{
+			name:          "version zero",
+			files:         fstest.MapFS{"0000_a.up.sql": {}},
+			containsError: "invalid migration version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMigrator(nil, tt.files)
+			assert.ErrorContains(t, err, tt.containsError, "NewMigrator error mismatch")
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m, err := NewDefaultMigrator(db)
+	require.NoError(t, err, "embedded migrations should be valid")
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err, "embedded migrations should apply")
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err, "Status should not fail")
+	_, err = m.Down(ctx, len(statuses))
+	require.NoError(t, err, "embedded migrations should revert")
+	_, err = m.Up(ctx)
+	require.NoError(t, err, "embedded migrations should apply again after a full revert")
+	require.NoError(t, db.Check(ctx), "Check should pass on an open database")
+}
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// JobState is the state of a background job.
+type JobState string
+
+// Job states. A job is pending until a worker claims it, and goes back to pending when an
+// attempt fails and it has attempts left; it is dead once it has none.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobDead      JobState = "dead"
+)
+
+// Job is a unit of background work, run by the handler registered for its kind.
+type Job struct {
+	ID   int64
+	Kind string
+	// Payload is the input of the handler, encoded in JSON.
+	Payload []byte
+	State   JobState
+	// UniqueKey, when set, keeps another job with the same key from being enqueued while
+	// this one is pending or running.
+	UniqueKey   string
+	Attempts    int
+	MaxAttempts int
+	// RunAt is when the job is due, first or next.
+	RunAt time.Time
+	// LastError is the error of the last failed attempt.
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// JobRepository stores background jobs. Methods changing a running job return ErrNotFound
+// if the job does not exist or is not running.
+type JobRepository interface {
+	// Enqueue stores j as pending and sets its ID, State and timestamps; RunAt defaults to
+	// now. It returns ErrConflict if a pending or running job holds the UniqueKey of j.
+	Enqueue(ctx context.Context, j *Job) error
+	// Claim marks the pending job of one of kinds that has been due the longest at now as
+	// running, counts the attempt and returns it. It returns ErrNotFound if none is due.
+	Claim(ctx context.Context, kinds []string, now time.Time) (Job, error)
+	// Complete marks the running job id as succeeded.
+	Complete(ctx context.Context, id int64) error
+	// Retry puts the running job id back in pending, due at runAt, and records reason.
+	Retry(ctx context.Context, id int64, runAt time.Time, reason string) error
+	// Bury marks the running job id as dead and records reason.
+	Bury(ctx context.Context, id int64, reason string) error
+	// Requeue puts the jobs left running, e.g. by a process that crashed, back in pending,
+	// and returns their number. It must not be called while workers run jobs.
+	Requeue(ctx context.Context) (int, error)
+	// Prune deletes the jobs that succeeded before t, and returns their number.
+	Prune(ctx context.Context, t time.Time) (int, error)
+	// Counts returns the number of jobs in each state.
+	Counts(ctx context.Context) (map[JobState]int, error)
+	// List returns at most limit jobs in state, most recently updated first.
+	List(ctx context.Context, state JobState, limit int) ([]Job, error)
+}
+
+// SQLJobRepository is the JobRepository backed by the database.
+type SQLJobRepository struct {
+	db *DB
+}
+
+var _ JobRepository = (*SQLJobRepository)(nil)
+
+// NewJobRepository returns a JobRepository storing jobs in db.
+func NewJobRepository(db *DB) *SQLJobRepository {
+	return &SQLJobRepository{db: db}
+}
+
+// jobColumns are the columns scanned by scanJob, in order.
+const jobColumns = `id, kind, payload, state, unique_key, attempts, max_attempts, run_at,
+	last_error, created_at, updated_at`
+
+// Enqueue implements JobRepository.
+func (r *SQLJobRepository) Enqueue(ctx context.Context, j *Job) error {
+	now := time.Now().UTC()
+	runAt := j.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+	payload := j.Payload
+	if payload == nil {
+		payload = []byte("null")
+	}
+	var uniqueKey sql.NullString
+	if j.UniqueKey != "" {
+		uniqueKey = sql.NullString{String: j.UniqueKey, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO jobs (kind, payload, state, unique_key, max_attempts, run_at, created_at,
+		updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		j.Kind, string(payload), JobPending, uniqueKey, j.MaxAttempts, formatTime(runAt),
+		formatTime(now), formatTime(now))
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return ErrConflict
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	j.ID, j.Payload, j.State, j.Attempts, j.LastError = id, payload, JobPending, 0, ""
+	j.RunAt, j.CreatedAt, j.UpdatedAt = runAt.UTC(), now, now
+	return nil
+}
+
+// Claim implements JobRepository. A single statement finds and updates the job, so that
+// two workers never claim the same one.
+func (r *SQLJobRepository) Claim(ctx context.Context, kinds []string, now time.Time) (Job, error) {
+	if len(kinds) == 0 {
+		return Job{}, ErrNotFound
+	}
+	args := []any{JobRunning, formatTime(time.Now()), JobPending, formatTime(now)}
+	for _, k := range kinds {
+		args = append(args, k)
+	}
+	row := r.db.QueryRowContext(ctx,
+		`UPDATE jobs SET state = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs WHERE state = ? AND run_at <= ?
+			AND kind IN (?`+strings.Repeat(", ?", len(kinds)-1)+`)
+			ORDER BY run_at, id LIMIT 1
+		) RETURNING `+jobColumns, args...)
+	j, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	return j, err
+}
+
+// Complete implements JobRepository.
+func (r *SQLJobRepository) Complete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET state = ?, updated_at = ? WHERE id = ? AND state = ?",
+		JobSucceeded, formatTime(time.Now()), id, JobRunning)
+	return checkAffected(res, err)
+}
+
+// Retry implements JobRepository.
+func (r *SQLJobRepository) Retry(
+	ctx context.Context,
+	id int64,
+	runAt time.Time,
+	reason string,
+) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET state = ?, run_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ? AND state = ?`,
+		JobPending, formatTime(runAt), reason, formatTime(time.Now()), id, JobRunning)
+	return checkAffected(res, err)
+}
+
+// Bury implements JobRepository.
+func (r *SQLJobRepository) Bury(ctx context.Context, id int64, reason string) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET state = ?, last_error = ?, updated_at = ? WHERE id = ? AND state = ?",
+		JobDead, reason, formatTime(time.Now()), id, JobRunning)
+	return checkAffected(res, err)
+}
+
+// Requeue implements JobRepository.
+func (r *SQLJobRepository) Requeue(ctx context.Context) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET state = ?, updated_at = ? WHERE state = ?",
+		JobPending, formatTime(time.Now()), JobRunning)
+	return rowsAffected(res, err)
+}
+
+// Prune implements JobRepository.
+func (r *SQLJobRepository) Prune(ctx context.Context, t time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM jobs WHERE state = ? AND updated_at < ?", JobSucceeded, formatTime(t))
+	return rowsAffected(res, err)
+}
+
+// Counts implements JobRepository.
+func (r *SQLJobRepository) Counts(ctx context.Context) (map[JobState]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT state, COUNT(*) FROM jobs GROUP BY state")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[JobState]int)
+	for rows.Next() {
+		var state JobState
+		var n int
+		if err := rows.Scan(&state, &n); err != nil {
+			return nil, err
+		}
+		counts[state] = n
+	}
+	return counts, rows.Err()
+}
+
+// List implements JobRepository.
+func (r *SQLJobRepository) List(ctx context.Context, state JobState, limit int) ([]Job, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+jobColumns+" FROM jobs WHERE state = ? ORDER BY updated_at DESC, id DESC LIMIT ?",
+		state, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var jobs []Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// rowsAffected returns the number of rows changed by a statement that ran without error.
+func rowsAffected(res sql.Result, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func scanJob(s scanner) (Job, error) {
+	var j Job
+	var payload, runAt, createdAt, updatedAt string
+	var uniqueKey sql.NullString
+	err := s.Scan(&j.ID, &j.Kind, &payload, &j.State, &uniqueKey, &j.Attempts, &j.MaxAttempts,
+		&runAt, &j.LastError, &createdAt, &updatedAt)
+	if err != nil {
+		return Job{}, err
+	}
+	j.Payload, j.UniqueKey = []byte(payload), uniqueKey.String
+	if j.RunAt, err = parseTime(runAt); err != nil {
+		return Job{}, err
+	}
+	if j.CreatedAt, err = parseTime(createdAt); err != nil {
+		return Job{}, err
+	}
+	if j.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return Job{}, err
+	}
+	return j, nil
+}
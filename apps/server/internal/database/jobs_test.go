@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepository(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewJobRepository(openMigratedTestDB(t))
+	now := time.Now()
+
+	first := Job{Kind: "email", Payload: []byte(`{"to":"a"}`), UniqueKey: "a", MaxAttempts: 3}
+	require.NoError(t, jobs.Enqueue(ctx, &first), "Enqueue should not fail")
+	assert.NotZero(t, first.ID, "Enqueue should set the ID")
+	assert.Equal(t, JobPending, first.State, "enqueued jobs should be pending")
+	assert.False(t, first.RunAt.IsZero(), "RunAt should default to now")
+	later := Job{Kind: "email", RunAt: now.Add(time.Hour), MaxAttempts: 3}
+	require.NoError(t, jobs.Enqueue(ctx, &later), "Enqueue should not fail")
+	other := Job{Kind: "report", MaxAttempts: 3}
+	require.NoError(t, jobs.Enqueue(ctx, &other), "Enqueue should not fail")
+
+	duplicate := Job{Kind: "email", UniqueKey: "a", MaxAttempts: 3}
+	assert.ErrorIs(t, jobs.Enqueue(ctx, &duplicate), ErrConflict,
+		"a pending job should hold its unique key")
+
+	claimed, err := jobs.Claim(ctx, []string{"email"}, now.Add(time.Second))
+	require.NoError(t, err, "Claim should not fail")
+	assert.Equal(t, first.ID, claimed.ID, "the job due first should be claimed")
+	assert.Equal(t, JobRunning, claimed.State, "claimed jobs should be running")
+	assert.Equal(t, 1, claimed.Attempts, "claiming should count the attempt")
+	assert.JSONEq(t, `{"to":"a"}`, string(claimed.Payload), "the payload should be kept")
+	_, err = jobs.Claim(ctx, []string{"email"}, now.Add(time.Second))
+	assert.ErrorIs(t, err, ErrNotFound, "jobs not due should not be claimed")
+	_, err = jobs.Claim(ctx, nil, now.Add(time.Second))
+	assert.ErrorIs(t, err, ErrNotFound, "no kind should claim nothing")
+
+	retryAt := now.Add(time.Minute)
+	require.NoError(t, jobs.Retry(ctx, first.ID, retryAt, "smtp down"), "Retry should not fail")
+	assert.ErrorIs(t, jobs.Retry(ctx, first.ID, retryAt, "again"), ErrNotFound,
+		"only running jobs should be retried")
+	claimed, err = jobs.Claim(ctx, []string{"email"}, retryAt)
+	require.NoError(t, err, "Claim should not fail")
+	assert.Equal(t, first.ID, claimed.ID, "retried jobs should be due at their new time")
+	assert.Equal(t, 2, claimed.Attempts, "attempts should add up")
+	assert.Equal(t, "smtp down", claimed.LastError, "the last error should be recorded")
+
+	require.NoError(t, jobs.Bury(ctx, first.ID, "smtp still down"), "Bury should not fail")
+	require.NoError(t, jobs.Enqueue(ctx, &duplicate),
+		"dead jobs should release their unique key")
+
+	claimed, err = jobs.Claim(ctx, []string{"email", "report"}, now.Add(time.Second))
+	require.NoError(t, err, "Claim should not fail")
+	assert.Equal(t, other.ID, claimed.ID, "jobs of every given kind should be claimed")
+	require.NoError(t, jobs.Complete(ctx, other.ID), "Complete should not fail")
+	assert.ErrorIs(t, jobs.Complete(ctx, other.ID), ErrNotFound,
+		"only running jobs should be completed")
+
+	_, err = jobs.Claim(ctx, []string{"email"}, now.Add(time.Second))
+	require.NoError(t, err, "Claim should not fail")
+	n, err := jobs.Requeue(ctx)
+	require.NoError(t, err, "Requeue should not fail")
+	assert.Equal(t, 1, n, "the running job should be requeued")
+
+	counts, err := jobs.Counts(ctx)
+	require.NoError(t, err, "Counts should not fail")
+	assert.Equal(t, map[JobState]int{JobPending: 2, JobSucceeded: 1, JobDead: 1}, counts,
+		"counts mismatch")
+	dead, err := jobs.List(ctx, JobDead, 10)
+	require.NoError(t, err, "List should not fail")
+	require.Len(t, dead, 1, "List should return the dead job")
+	assert.Equal(t, "smtp still down", dead[0].LastError, "the cause should be recorded")
+
+	n, err = jobs.Prune(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err, "Prune should not fail")
+	assert.Equal(t, 1, n, "succeeded jobs should be pruned")
+	counts, err = jobs.Counts(ctx)
+	require.NoError(t, err, "Counts should not fail")
+	assert.Equal(t, map[JobState]int{JobPending: 2, JobDead: 1}, counts,
+		"other jobs should be kept")
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Migrations holds the schema migrations of the API server.
+//
+// Each version has two files, NNNN_name.up.sql and NNNN_name.down.sql, where NNNN is
+// the version number. Versions are applied in increasing order; never edit a migration
+// once it has been applied somewhere, add a new one instead.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// migrationFile matches the names of migration files.
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Unknown is set for versions recorded in the database without a migration file,
+	// typically applied by a newer build.
+	Unknown bool
+}
+
+// Migrator applies and reverts migrations, recording applied versions in the
+// schema_migrations table. Each migration runs in its own transaction.
+type Migrator struct {
+	db         *DB
+	migrations []Migration // Sorted by version.
+}
+
+// NewMigrator loads the migrations found at the root of fsys. It fails when a file name is
+// invalid, a version is duplicated or one of its up and down files is missing.
+func NewMigrator(db *DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, errors.New("invalid migration file name: " + e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, errors.New("invalid migration version: " + e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d has several names", version)
+		}
+		if m[3] == "up" {
+			mig.up = string(body)
+		} else {
+			mig.down = string(body)
+		}
+	}
+
+	migrator := &Migrator{db: db}
+	for _, mig := range byVersion {
+		if mig.up == "" || mig.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file",
+				mig.Version, mig.Name)
+		}
+		migrator.migrations = append(migrator.migrations, *mig)
+	}
+	slices.SortFunc(migrator.migrations, func(a, b Migration) int {
+		return int(a.Version - b.Version)
+	})
+	return migrator, nil
+}
+
+// NewDefaultMigrator returns a Migrator for the embedded Migrations.
+func NewDefaultMigrator(db *DB) (*Migrator, error) {
+	sub, err := fs.Sub(Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(db, sub)
+}
+
+// Up applies every pending migration and returns those applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if !slices.ContainsFunc(m.migrations, func(mig Migration) bool {
+			return mig.Version == version
+		}) {
+			return nil, fmt.Errorf(
+				"database has migration %d applied, which this build does not know",
+				version,
+			)
+		}
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.db.InTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				mig.Version, mig.Name, formatTime(time.Now()))
+			return err
+		})
+		if err != nil {
+			slog.Error("Failed to apply migration", "version", mig.Version, "name", mig.Name,
+				"error", err)
+			return done, fmt.Errorf(
+				"failed to apply migration %d_%s: %w",
+				mig.Version,
+				mig.Name,
+				err,
+			)
+		}
+		slog.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations and returns those reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err := m.db.InTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx,
+				"DELETE FROM schema_migrations WHERE version = ?", mig.Version)
+			return err
+		})
+		if err != nil {
+			slog.Error("Failed to revert migration", "version", mig.Version, "name", mig.Name,
+				"error", err)
+			return done, fmt.Errorf(
+				"failed to revert migration %d_%s: %w",
+				mig.Version,
+				mig.Name,
+				err,
+			)
+		}
+		slog.Info("Reverted migration", "version", mig.Version, "name", mig.Name)
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Status lists every known migration, and applied versions without a migration file,
+// sorted by version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			s.Applied, s.AppliedAt = true, at
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, at := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version: version, Applied: true, AppliedAt: at, Unknown: true,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return int(a.Version - b.Version)
+	})
+	return statuses, nil
+}
+
+// applied returns the applied versions and when they were applied, creating the
+// schema_migrations table if needed.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		t, err := parseTime(at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid applied_at for migration %d: %w", version, err)
+		}
+		applied[version] = t
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Note is a titled piece of text, the sample entity of the starter.
+type Note struct {
+	ID        int64
+	Title     string
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NoteRepository stores notes. Methods return ErrNotFound for missing notes.
+type NoteRepository interface {
+	// Create stores n and sets its ID and timestamps.
+	Create(ctx context.Context, n *Note) error
+	Get(ctx context.Context, id int64) (Note, error)
+	// List returns at most limit notes, newest first, skipping the first offset ones.
+	List(ctx context.Context, limit, offset int) ([]Note, error)
+	// Update saves the title and body of n and refreshes its UpdatedAt.
+	Update(ctx context.Context, n *Note) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// SQLNoteRepository is the NoteRepository backed by the database.
+type SQLNoteRepository struct {
+	db *DB
+}
+
+var _ NoteRepository = (*SQLNoteRepository)(nil)
+
+// NewNoteRepository returns a NoteRepository storing notes in db.
+func NewNoteRepository(db *DB) *SQLNoteRepository {
+	return &SQLNoteRepository{db: db}
+}
+
+// Create implements NoteRepository.
+func (r *SQLNoteRepository) Create(ctx context.Context, n *Note) error {
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO notes (title, body, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		n.Title, n.Body, formatTime(now), formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	n.ID, n.CreatedAt, n.UpdatedAt = id, now, now
+	return nil
+}
+
+// Get implements NoteRepository.
+func (r *SQLNoteRepository) Get(ctx context.Context, id int64) (Note, error) {
+	row := r.db.QueryRowContext(ctx,
+		"SELECT id, title, body, created_at, updated_at FROM notes WHERE id = ?", id)
+	n, err := scanNote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Note{}, ErrNotFound
+	}
+	return n, err
+}
+
+// List implements NoteRepository.
+func (r *SQLNoteRepository) List(ctx context.Context, limit, offset int) ([]Note, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, title, body, created_at, updated_at FROM notes
+		ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var notes []Note
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// Update implements NoteRepository.
+func (r *SQLNoteRepository) Update(ctx context.Context, n *Note) error {
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE notes SET title = ?, body = ?, updated_at = ? WHERE id = ?",
+		n.Title, n.Body, formatTime(now), n.ID)
+	if err := checkAffected(res, err); err != nil {
+		return err
+	}
+	n.UpdatedAt = now
+	return nil
+}
+
+// Delete implements NoteRepository.
+func (r *SQLNoteRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM notes WHERE id = ?", id)
+	return checkAffected(res, err)
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNote(s scanner) (Note, error) {
+	var n Note
+	var createdAt, updatedAt string
+	if err := s.Scan(&n.ID, &n.Title, &n.Body, &createdAt, &updatedAt); err != nil {
+		return Note{}, err
+	}
+	var err error
+	if n.CreatedAt, err = parseTime(createdAt); err != nil {
+		return Note{}, err
+	}
+	if n.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return Note{}, err
+	}
+	return n, nil
+}
+
+// checkAffected returns ErrNotFound when a statement that ran without error changed no row.
+func checkAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openMigratedTestDB returns a temporary database with every embedded migration applied.
+func openMigratedTestDB(t *testing.T) *DB {
+	t.Helper()
+	db := openTestDB(t)
+	m, err := NewDefaultMigrator(db)
+	require.NoError(t, err, "loading migrations")
+	_, err = m.Up(context.Background())
+	require.NoError(t, err, "migrating test database")
+	return db
+}
+
+// newTestNotes returns a NoteRepository on a migrated temporary database.
+func newTestNotes(t *testing.T) *SQLNoteRepository {
+	t.Helper()
+	return NewNoteRepository(openMigratedTestDB(t))
+}
+
+func TestNoteRepository(t *testing.T) {
+	ctx := context.Background()
+	notes := newTestNotes(t)
+
+	first := Note{Title: "first", Body: "hello"}
+	require.NoError(t, notes.Create(ctx, &first), "Create should not fail")
+	assert.NotZero(t, first.ID, "Create should set the ID")
+	assert.False(t, first.CreatedAt.IsZero(), "Create should set CreatedAt")
+	second := Note{Title: "second"}
+	require.NoError(t, notes.Create(ctx, &second), "Create should not fail")
+
+	got, err := notes.Get(ctx, first.ID)
+	require.NoError(t, err, "Get should not fail")
+	assert.Equal(t, first, got, "Get should return the stored note")
+
+	list, err := notes.List(ctx, 10, 0)
+	require.NoError(t, err, "List should not fail")
+	require.Len(t, list, 2, "List should return both notes")
+	assert.Equal(t, "second", list[0].Title, "List should return the newest note first")
+	list, err = notes.List(ctx, 1, 1)
+	require.NoError(t, err, "List should not fail")
+	assert.Equal(t, []Note{first}, list, "List should honour limit and offset")
+
+	first.Title = "renamed"
+	require.NoError(t, notes.Update(ctx, &first), "Update should not fail")
+	got, err = notes.Get(ctx, first.ID)
+	require.NoError(t, err, "Get should not fail")
+	assert.Equal(t, "renamed", got.Title, "Update should be persisted")
+
+	require.NoError(t, notes.Delete(ctx, first.ID), "Delete should not fail")
+	_, err = notes.Get(ctx, first.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "Get of a deleted note")
+	assert.ErrorIs(t, notes.Delete(ctx, first.ID), ErrNotFound, "Delete of a deleted note")
+	assert.ErrorIs(t, notes.Update(ctx, &first), ErrNotFound, "Update of a deleted note")
+}
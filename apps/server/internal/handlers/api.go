@@ -1,15 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
-	"net/http"
+	"context"
+
+	"github.com/supergeoff/go-starter/contract"
 )
 
-func ApiHandler(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(map[string]string{"message": "check"})
-	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
-	}
-}
+// ApiHandler is a sample endpoint reporting that the API is reachable.
+var ApiHandler = Handle(
+	func(context.Context, struct{}) (contract.MessageResponse, error) {
+		return contract.MessageResponse{Message: "check"}, nil
+	},
+	WithSummary("Check that the API is reachable"),
+)
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// paramSource is where a request field is read from.
+type paramSource string
+
+const (
+	sourcePath  paramSource = "path"
+	sourceQuery paramSource = "query"
+)
+
+// param is a request struct field bound to a path or query parameter.
+type param struct {
+	source paramSource
+	name   string
+	index  []int // Field index, as used by reflect.Value.FieldByIndex.
+	typ    reflect.Type
+}
+
+// paramCache holds the params of each request type, which are computed once.
+var paramCache sync.Map // map[reflect.Type][]param
+
+// paramsOf returns the fields of the struct type t tagged with `path:"name"` or
+// `query:"name"`, including those of embedded structs. It panics on a tagged field of an
+// unsupported type, which is a programming error detected on the first request.
+func paramsOf(t reflect.Type) []param {
+	if cached, ok := paramCache.Load(t); ok {
+		return cached.([]param)
+	}
+	var params []param
+	if t.Kind() == reflect.Struct {
+		params = collectParams(t, nil)
+	}
+	paramCache.Store(t, params)
+	return params
+}
+
+func collectParams(t reflect.Type, index []int) []param {
+	var params []param
+	for i := range t.NumField() {
+		f := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			params = append(params, collectParams(f.Type, fieldIndex)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		for _, source := range []paramSource{sourcePath, sourceQuery} {
+			name, ok := f.Tag.Lookup(string(source))
+			if !ok {
+				continue
+			}
+			if !isSupportedParamType(f.Type, source == sourceQuery) {
+				panic(fmt.Sprintf("Error: unsupported type %s for %s parameter %q of %s",
+					f.Type, source, name, t))
+			}
+			params = append(
+				params,
+				param{source: source, name: name, index: fieldIndex, typ: f.Type},
+			)
+		}
+	}
+	return params
+}
+
+// isSupportedParamType reports whether t can be parsed from parameter strings: scalars,
+// pointers to scalars for optional parameters and, for query parameters, slices of
+// scalars for repeated ones.
+func isSupportedParamType(t reflect.Type, allowSlice bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return isScalar(t.Elem())
+	case reflect.Slice:
+		return allowSlice && isScalar(t.Elem())
+	default:
+		return isScalar(t)
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeRequest fills dst, a pointer to a request value, from the JSON body of r and then
+// from its path and query parameters, which take precedence.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst any, maxBodyBytes int64) error {
+	if err := decodeBody(w, r, dst, maxBodyBytes); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	query := r.URL.Query()
+	var errs problem.FieldErrors
+	for _, p := range paramsOf(v.Type()) {
+		var values []string
+		switch p.source {
+		case sourcePath:
+			if s := chi.URLParam(r, p.name); s != "" {
+				values = []string{s}
+			}
+		case sourceQuery:
+			values = query[p.name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setParam(v.FieldByIndex(p.index), values); err != nil {
+			errs = append(errs, problem.FieldError{
+				Field:   string(p.source) + "." + p.name,
+				Message: err.Error(),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		prob := problem.BadRequest("invalid request parameters")
+		prob.Errors = errs
+		return prob
+	}
+	return nil
+}
+
+// decodeBody decodes a JSON body into dst. An absent body leaves dst unchanged.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any, maxBodyBytes int64) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return problem.New(http.StatusUnsupportedMediaType, "the request body must be JSON")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after the JSON value")
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &maxBytesErr):
+		return problem.New(http.StatusRequestEntityTooLarge,
+			"the request body must not exceed "+strconv.FormatInt(maxBytesErr.Limit, 10)+" bytes")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		prob := problem.BadRequest("the request body has a field of the wrong type")
+		prob.Errors = []problem.FieldError{{
+			Field:   "body." + typeErr.Field,
+			Message: "must be of type " + typeErr.Type.String(),
+		}}
+		return prob
+	default:
+		return problem.BadRequest("malformed JSON body: " + err.Error())
+	}
+}
+
+// setParam parses values into the field v.
+func setParam(v reflect.Value, values []string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setScalar(elem.Elem(), values[0]); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setScalar(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	default:
+		return setScalar(v, values[0])
+	}
+}
+
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
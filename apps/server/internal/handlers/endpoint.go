@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Endpoint is the http.Handler returned by Handle. Besides serving requests, it describes
+// its inputs and outputs so that API documentation can be generated from the router.
+// Register it with chi's Method, e.g. r.Method(http.MethodGet, "/items/{id}", endpoint).
+type Endpoint struct {
+	handler     http.Handler
+	description func() Description
+}
+
+// ServeHTTP serves the request.
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.handler.ServeHTTP(w, r)
+}
+
+// Description returns what the endpoint accepts and returns.
+func (e *Endpoint) Description() Description {
+	return e.description()
+}
+
+// Description describes an endpoint for documentation purposes.
+type Description struct {
+	Summary string
+	// Request is the type decoded from the JSON body; nil when the endpoint reads no body.
+	// Its fields tagged `json:"-"` are not part of the body.
+	Request reflect.Type
+	// Params are the path and query parameters of the request.
+	Params []Param
+	// Responses lists the successful responses.
+	Responses []Response
+	// Problems reports whether errors are sent as RFC 7807 problems.
+	Problems bool
+	// Security lists the authentication schemes accepted by the endpoint, any one of which
+	// grants access. It is empty for public endpoints.
+	Security []SecurityScheme
+}
+
+// SecurityScheme is an HTTP authentication scheme accepted by an endpoint.
+type SecurityScheme struct {
+	Name         string // Key of the scheme in the API documentation, e.g. "bearerAuth".
+	Scheme       string // HTTP authentication scheme, e.g. "bearer".
+	BearerFormat string // Hint about the format of bearer tokens, e.g. "JWT".
+	Description  string
+}
+
+// Param is a path or query parameter.
+type Param struct {
+	In   string // "path" or "query".
+	Name string
+	Type reflect.Type
+}
+
+// Response is a response status code and the type of its JSON body, nil for no body.
+type Response struct {
+	Status int
+	Type   reflect.Type
+	// ContentType is the media type of the body, application/json when empty. For event
+	// streams, Type is the type of the data of each event.
+	ContentType string
+}
+
+// Describe attaches a description to a handler not created by Handle, such as the health
+// probes, so that it appears in the API documentation.
+func Describe(h http.Handler, d Description) *Endpoint {
+	return &Endpoint{handler: h, description: func() Description { return d }}
+}
+
+// describe returns the description of an endpoint created by Handle.
+func describe[Req, Resp any](o options) Description {
+	d := Description{Summary: o.summary, Problems: true}
+	reqType := reflect.TypeFor[Req]()
+	for _, p := range paramsOf(reqType) {
+		d.Params = append(d.Params, Param{In: string(p.source), Name: p.name, Type: p.typ})
+	}
+	if hasBodyFields(reqType) {
+		d.Request = reqType
+	}
+	resp := Response{Status: o.status}
+	if o.status != http.StatusNoContent {
+		resp.Type = reflect.TypeFor[Resp]()
+	}
+	d.Responses = []Response{resp}
+	return d
+}
+
+// hasBodyFields reports whether t is a struct with at least one field decoded from JSON.
+func hasBodyFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return t.Kind() != reflect.Interface
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if f.Tag.Get("json") == "" && hasBodyFields(f.Type) {
+				return true
+			}
+			continue
+		}
+		if f.IsExported() && f.Tag.Get("json") != "-" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Package handlers implements the HTTP endpoints of the API server.
+//
+// Endpoints are typed functions adapted by Handle. Their request type is decoded from the
+// JSON body, then fields tagged `path:"name"` or `query:"name"` are read from the chi
+// path parameters and the query string; such fields should also be tagged `json:"-"`.
+// Pointer fields stay nil when a parameter is absent and slice fields collect repeated
+// query parameters:
+//
+//	type getItemRequest struct {
+//		ID    int64    `json:"-" path:"id"`
+//		Limit *int     `json:"-" query:"limit"`
+//		Tags  []string `json:"-" query:"tag"`
+//	}
+//
+//	r.Method(http.MethodGet, "/items/{id}", handlers.Handle(getItem))
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// Func is the signature of a typed endpoint: it receives the decoded and validated
+// request and returns the response to encode as JSON, or an error. Errors are sent as
+// RFC 7807 problems, see problem.From.
+type Func[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Validator is implemented by request types that check their own fields after decoding.
+// Returning problem.FieldErrors yields a 422 response listing them.
+type Validator interface {
+	Validate() error
+}
+
+// Option configures an endpoint created by Handle.
+type Option func(*options)
+
+type options struct {
+	status       int
+	maxBodyBytes int64
+	summary      string
+}
+
+// WithStatus sets the status code of successful responses; the default is 200.
+// With 204 No Content the response is not encoded.
+func WithStatus(code int) Option {
+	return func(o *options) { o.status = code }
+}
+
+// WithSummary sets the one-line summary of the endpoint in the API documentation.
+func WithSummary(summary string) Option {
+	return func(o *options) { o.summary = summary }
+}
+
+// WithMaxBodyBytes limits the size of request bodies; the default is 1 MiB.
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *options) { o.maxBodyBytes = n }
+}
+
+// Handle adapts fn to an http.Handler. The request is decoded into a Req from the
+// JSON body, the chi path parameters and the query string (see the package
+// documentation for the struct tags), then validated if it implements Validator.
+// The response is encoded before anything is written, so that an encoding failure can
+// still be reported as a 500 problem.
+func Handle[Req, Resp any](fn Func[Req, Resp], opts ...Option) *Endpoint {
+	o := options{status: http.StatusOK, maxBodyBytes: 1 << 20}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := decodeRequest(w, r, &req, o.maxBodyBytes); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		if v, ok := any(&req).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				problem.Write(w, r, asValidationError(err))
+				return
+			}
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		if o.status == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(resp); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(o.status)
+		if _, err := buf.WriteTo(w); err != nil {
+			slog.Error("Failed to write response", "error", err)
+		}
+	})
+	return &Endpoint{handler: h, description: func() Description { return describe[Req, Resp](o) }}
+}
+
+// asValidationError turns a plain error returned by Validate into a 422 problem, while
+// keeping problems and field errors chosen by the request type.
+func asValidationError(err error) error {
+	p := problem.From(err)
+	if p.Status != http.StatusInternalServerError {
+		return p
+	}
+	return problem.New(http.StatusUnprocessableEntity, err.Error())
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+type itemRequest struct {
+	ID    int64    `json:"-"    path:"id"`
+	Limit *int     `json:"-"              query:"limit"`
+	Tags  []string `json:"-"              query:"tag"`
+	Name  string   `json:"name"`
+}
+
+func (r *itemRequest) Validate() error {
+	if r.Name == "forbidden" {
+		return problem.FieldErrors{{Field: "body.name", Message: "is not allowed"}}
+	}
+	if r.Name == "plain" {
+		return errors.New("name cannot be plain")
+	}
+	return nil
+}
+
+type itemResponse struct {
+	ID    int64    `json:"id"`
+	Limit int      `json:"limit"`
+	Tags  []string `json:"tags"`
+	Name  string   `json:"name"`
+}
+
+func getItem(_ context.Context, req itemRequest) (itemResponse, error) {
+	switch req.ID {
+	case 404:
+		return itemResponse{}, problem.NotFound("no item 404")
+	case 500:
+		return itemResponse{}, errors.New("database exploded")
+	}
+	resp := itemResponse{ID: req.ID, Limit: 10, Tags: req.Tags, Name: req.Name}
+	if req.Limit != nil {
+		resp.Limit = *req.Limit
+	}
+	return resp, nil
+}
+
+func TestHandle(t *testing.T) {
+	r := chi.NewRouter()
+	r.Method(
+		http.MethodPost, "/items/{id}",
+		Handle(getItem, WithStatus(http.StatusCreated), WithMaxBodyBytes(64)),
+	)
+	r.Method(http.MethodDelete, "/items/{id}", Handle(getItem, WithStatus(http.StatusNoContent)))
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		contentType     string
+		body            string
+		expectedStatus  int
+		expectedBody    *itemResponse
+		expectedDetail  string
+		expectedInvalid []problem.FieldError
+	}{
+		{
+			name:           "decodes path, query and body",
+			method:         http.MethodPost,
+			target:         "/items/42?limit=5&tag=a&tag=b",
+			contentType:    "application/json",
+			body:           `{"name":"widget"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody: &itemResponse{
+				ID:    42,
+				Limit: 5,
+				Tags:  []string{"a", "b"},
+				Name:  "widget",
+			},
+		},
+		{
+			name:           "optional query and empty body",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   &itemResponse{ID: 1, Limit: 10},
+		},
+		{
+			name:           "no content",
+			method:         http.MethodDelete,
+			target:         "/items/1",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid path and query parameters",
+			method:         http.MethodPost,
+			target:         "/items/abc?limit=x",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid request parameters",
+			expectedInvalid: []problem.FieldError{
+				{Field: "path.id", Message: "must be an integer"},
+				{Field: "query.limit", Message: "must be an integer"},
+			},
+		},
+		{
+			name:           "malformed JSON",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "application/json",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "malformed JSON body: unexpected EOF",
+		},
+		{
+			name:           "unknown body field",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "application/json",
+			body:           `{"nom":"widget"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: `malformed JSON body: json: unknown field "nom"`,
+		},
+		{
+			name:           "wrong body field type",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "application/json",
+			body:           `{"name":3}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "the request body has a field of the wrong type",
+			expectedInvalid: []problem.FieldError{
+				{Field: "body.name", Message: "must be of type string"},
+			},
+		},
+		{
+			name:           "body too large",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "application/json",
+			body:           `{"name":"` + strings.Repeat("x", 100) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedDetail: "the request body must not exceed 64 bytes",
+		},
+		{
+			name:           "body is not JSON",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "text/plain",
+			body:           "widget",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedDetail: "the request body must be JSON",
+		},
+		{
+			name:            "validation field errors",
+			method:          http.MethodPost,
+			target:          "/items/1",
+			contentType:     "application/json",
+			body:            `{"name":"forbidden"}`,
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedDetail:  "the request has invalid fields",
+			expectedInvalid: []problem.FieldError{{Field: "body.name", Message: "is not allowed"}},
+		},
+		{
+			name:           "validation plain error",
+			method:         http.MethodPost,
+			target:         "/items/1",
+			contentType:    "application/json",
+			body:           `{"name":"plain"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedDetail: "name cannot be plain",
+		},
+		{
+			name:           "typed handler error",
+			method:         http.MethodPost,
+			target:         "/items/404",
+			expectedStatus: http.StatusNotFound,
+			expectedDetail: "no item 404",
+		},
+		{
+			name:           "untyped handler error is not disclosed",
+			method:         http.MethodPost,
+			target:         "/items/500",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if body != nil {
+				req = httptest.NewRequest(tt.method, tt.target, body)
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, "handler returned wrong status code")
+			switch {
+			case tt.expectedStatus == http.StatusNoContent:
+				assert.Empty(t, rr.Body.String(), "204 responses should have no body")
+			case tt.expectedBody != nil:
+				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"),
+					"handler returned wrong content type")
+				var got itemResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got), "decoding response")
+				assert.Equal(t, *tt.expectedBody, got, "handler returned unexpected body")
+			default:
+				assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"),
+					"errors should be problem documents")
+				var got problem.Problem
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got), "decoding problem")
+				assert.Equal(t, tt.expectedStatus, got.Status, "problem status mismatch")
+				assert.Equal(
+					t,
+					http.StatusText(tt.expectedStatus),
+					got.Title,
+					"problem title mismatch",
+				)
+				assert.Equal(t, tt.expectedDetail, got.Detail, "problem detail mismatch")
+				assert.Equal(t, tt.expectedInvalid, got.Errors, "problem field errors mismatch")
+				assert.NotContains(t, rr.Body.String(), "database exploded",
+					"internal errors must not leak")
+			}
+		})
+	}
+}
+
+func TestHandle_UnsupportedParamTypePanics(t *testing.T) {
+	type badRequest struct {
+		Filter map[string]string `json:"-" query:"filter"`
+	}
+	h := Handle(func(context.Context, badRequest) (struct{}, error) { return struct{}{}, nil })
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}, "unsupported parameter types are programming errors")
+}
@@ -0,0 +1,45 @@
+package hub
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures a Hub. It is meant to be embedded in the configuration of the API
+// server.
+type Config struct {
+	SendBuffer     int           `config:"send_buffer"      usage:"messages queued per client at most"`
+	MaxMessageSize int64         `config:"max_message_size" usage:"largest client message, in bytes"`
+	PingInterval   time.Duration `config:"ping_interval"    usage:"interval between keepalive pings"`
+	PongTimeout    time.Duration `config:"pong_timeout"     usage:"silence before dropping a client"`
+	WriteTimeout   time.Duration `config:"write_timeout"    usage:"deadline of each write"`
+}
+
+// DefaultConfig returns a configuration pinging clients every 30 seconds, and dropping
+// those that stay silent for a minute or fall 64 messages behind.
+func DefaultConfig() Config {
+	return Config{
+		SendBuffer:     64,
+		MaxMessageSize: 64 << 10,
+		PingInterval:   30 * time.Second,
+		PongTimeout:    time.Minute,
+		WriteTimeout:   10 * time.Second,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	switch {
+	case c.SendBuffer <= 0:
+		return errors.New("websocket.send_buffer must be positive")
+	case c.MaxMessageSize <= 0:
+		return errors.New("websocket.max_message_size must be positive")
+	case c.PingInterval <= 0:
+		return errors.New("websocket.ping_interval must be positive")
+	case c.PongTimeout <= c.PingInterval:
+		return errors.New("websocket.pong_timeout must be longer than websocket.ping_interval")
+	case c.WriteTimeout <= 0:
+		return errors.New("websocket.write_timeout must be positive")
+	}
+	return nil
+}
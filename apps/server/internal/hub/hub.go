@@ -0,0 +1,403 @@
+// Package hub relays real-time messages between the WebSocket clients of the API server.
+// Authenticated clients join named rooms, send messages to the other members of a room,
+// and are told who is present whenever the members of a room change. The server sends to
+// a room with Hub.Broadcast.
+//
+// Clients exchange JSON Messages in text frames:
+//
+//	→ {"type":"join","room":"doc-42"}
+//	← {"type":"presence","room":"doc-42","members":["alice","bob"]}
+//	→ {"type":"message","room":"doc-42","data":{"cursor":12}}
+//	← {"type":"message","room":"doc-42","from":"alice","data":{"cursor":12}}
+//	→ {"type":"leave","room":"doc-42"}
+//
+// Each connection has a send buffer of Config.SendBuffer messages: a client falling
+// further behind is disconnected with status 1008, rather than slowing the others down.
+// Clients are pinged every Config.PingInterval, and disconnected when they stay silent for
+// Config.PongTimeout.
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+	"github.com/supergeoff/go-starter/apps/server/internal/websocket"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+// Message types. Clients send join, leave and message; the hub sends message, presence
+// and error.
+const (
+	TypeJoin     = "join"
+	TypeLeave    = "leave"
+	TypeMessage  = "message"
+	TypePresence = "presence"
+	TypeError    = "error"
+)
+
+// maxRoomName bounds the length of room names, in bytes.
+const maxRoomName = 128
+
+// Message is a message exchanged with the clients, encoded in JSON.
+type Message struct {
+	Type string `json:"type"`
+	Room string `json:"room,omitempty"`
+	// From is the subject of the principal who sent a message, empty for broadcasts.
+	From string          `json:"from,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+	// Members lists the subjects present in Room, in presence messages.
+	Members []string `json:"members,omitempty"`
+	// Error tells why a message of the client was refused, in error messages.
+	Error string `json:"error,omitempty"`
+}
+
+// Hub tracks the rooms of the WebSocket clients and relays their messages.
+type Hub struct {
+	cfg         Config
+	allowOrigin func(r *http.Request) bool
+
+	mu    sync.Mutex
+	rooms map[string]map[*client]struct{}
+	// dropped lists the clients disconnected for lagging behind, still to be removed from
+	// their rooms.
+	dropped []*client
+}
+
+// Option configures a Hub.
+type Option func(*Hub)
+
+// WithOriginCheck lets browsers connect from the origins for which allow returns true,
+// besides the origin of the API itself, e.g. with cors.CORS.AllowOrigin. Browsers let any
+// page open WebSocket connections, so other origins are refused by default.
+func WithOriginCheck(allow func(r *http.Request) bool) Option {
+	return func(h *Hub) { h.allowOrigin = allow }
+}
+
+// New returns a Hub without clients.
+func New(cfg Config, opts ...Option) (*Hub, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	h := &Hub{cfg: cfg, rooms: make(map[string]map[*client]struct{})}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and relays its messages until
+// it closes, or until the server starts shutting down. Requests must be authenticated:
+// ServeHTTP is meant to be wrapped by auth's Middleware, and refuses requests without a
+// Principal. Members are identified by the subject of their principal.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		problem.Write(w, r, problem.New(http.StatusUnauthorized,
+			"the request must carry a bearer token"))
+		return
+	}
+	if !h.checkOrigin(r) {
+		problem.Write(w, r, problem.New(http.StatusForbidden,
+			"WebSocket connections are not allowed from origin "+r.Header.Get("Origin")))
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return // Upgrade answered the request.
+	}
+	defer func() { _ = conn.Close() }()
+	conn.SetReadLimit(h.cfg.MaxMessageSize)
+	conn.SetWriteTimeout(h.cfg.WriteTimeout)
+
+	c := &client{
+		hub:     h,
+		conn:    conn,
+		id:      p.Subject,
+		send:    make(chan []byte, h.cfg.SendBuffer),
+		closing: make(chan struct{}),
+		rooms:   make(map[string]struct{}),
+	}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.writeLoop(lifecycle.Stopping(r.Context()))
+	}()
+	if err := c.readLoop(); websocket.CloseStatus(err) == -1 {
+		// The connection is broken or silent: nobody would read a close frame.
+		_ = conn.Close()
+	}
+	h.leaveAll(c)
+	c.close(websocket.StatusNormalClosure, "")
+	<-written
+}
+
+// checkOrigin reports whether the browser that sent r, if any, may connect.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Not sent by a browser.
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.allowOrigin != nil && h.allowOrigin(r)
+}
+
+// Broadcast sends data, encoded in JSON, to the members of room in a message without
+// sender.
+func (h *Hub) Broadcast(room string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcastLocked(room, Message{Type: TypeMessage, Room: room, Data: b}, nil)
+	h.removeDroppedLocked()
+	return nil
+}
+
+// Presence returns the subjects of the members of room, sorted.
+func (h *Hub) Presence(room string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.presenceLocked(room)
+}
+
+// handle acts on message m of client c.
+func (h *Hub) handle(c *client, m Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	defer h.removeDroppedLocked()
+
+	switch m.Type {
+	case TypeJoin, TypeLeave, TypeMessage:
+	default:
+		h.sendLocked(c, errorMessage(m.Room, "unknown message type: "+m.Type))
+		return
+	}
+	if m.Room == "" || len(m.Room) > maxRoomName {
+		h.sendLocked(c, errorMessage(m.Room,
+			"room must be 1 to "+strconv.Itoa(maxRoomName)+" bytes long"))
+		return
+	}
+	_, member := c.rooms[m.Room]
+	switch m.Type {
+	case TypeJoin:
+		members := h.rooms[m.Room]
+		if members == nil {
+			members = make(map[*client]struct{})
+			h.rooms[m.Room] = members
+		}
+		members[c] = struct{}{}
+		c.rooms[m.Room] = struct{}{}
+		h.announceLocked(m.Room)
+	case TypeLeave:
+		if member {
+			h.leaveLocked(c, m.Room)
+		}
+	case TypeMessage:
+		if !member {
+			h.sendLocked(c, errorMessage(m.Room, "join the room before sending to it"))
+			return
+		}
+		h.broadcastLocked(m.Room, Message{
+			Type: TypeMessage,
+			Room: m.Room,
+			From: c.id,
+			Data: m.Data,
+		}, c)
+	}
+}
+
+// leaveAll removes c from its rooms.
+func (h *Hub) leaveAll(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range c.rooms {
+		h.leaveLocked(c, room)
+	}
+	h.removeDroppedLocked()
+}
+
+// leaveLocked removes c from room, and tells the members left. h.mu must be held.
+func (h *Hub) leaveLocked(c *client, room string) {
+	delete(c.rooms, room)
+	members := h.rooms[room]
+	delete(members, c)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+		return
+	}
+	h.announceLocked(room)
+}
+
+// removeDroppedLocked removes the clients dropped for lagging behind from their rooms,
+// which may drop more clients. h.mu must be held.
+func (h *Hub) removeDroppedLocked() {
+	for len(h.dropped) > 0 {
+		c := h.dropped[0]
+		h.dropped = h.dropped[1:]
+		for room := range c.rooms {
+			h.leaveLocked(c, room)
+		}
+	}
+}
+
+// presenceLocked returns the subjects of the members of room, sorted, each once even
+// when connected several times. h.mu must be held.
+func (h *Hub) presenceLocked(room string) []string {
+	var subjects []string
+	for c := range h.rooms[room] {
+		subjects = append(subjects, c.id)
+	}
+	slices.Sort(subjects)
+	return slices.Compact(subjects)
+}
+
+// announceLocked sends the presence of room to its members. h.mu must be held.
+func (h *Hub) announceLocked(room string) {
+	h.broadcastLocked(room, Message{
+		Type:    TypePresence,
+		Room:    room,
+		Members: h.presenceLocked(room),
+	}, nil)
+}
+
+// broadcastLocked sends m to the members of room but except. h.mu must be held.
+func (h *Hub) broadcastLocked(room string, m Message, except *client) {
+	b, _ := json.Marshal(m)
+	for c := range h.rooms[room] {
+		if c != except && !c.enqueue(b) {
+			h.dropped = append(h.dropped, c)
+		}
+	}
+}
+
+// sendLocked sends m to c. h.mu must be held.
+func (h *Hub) sendLocked(c *client, m Message) {
+	b, _ := json.Marshal(m)
+	if !c.enqueue(b) {
+		h.dropped = append(h.dropped, c)
+	}
+}
+
+func errorMessage(room, reason string) Message {
+	return Message{Type: TypeError, Room: room, Error: reason}
+}
+
+// client is a WebSocket connection of the hub. Its rooms are guarded by hub.mu.
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	id   string
+
+	send    chan []byte
+	rooms   map[string]struct{}
+	closing chan struct{}
+	once    sync.Once
+	// code and reason are the status of the close frame, set before closing is closed.
+	code   websocket.StatusCode
+	reason string
+}
+
+// enqueue queues the encoded message b, unless c is closing. It returns false if the send
+// buffer of c is full, which closes c.
+func (c *client) enqueue(b []byte) bool {
+	select {
+	case <-c.closing:
+		return true
+	default:
+	}
+	select {
+	case c.send <- b:
+		return true
+	default:
+		c.close(websocket.StatusPolicyViolation, "too slow to receive messages")
+		return false
+	}
+}
+
+// close makes the write loop send a close frame with code and reason, and end. Only the
+// first call has an effect.
+func (c *client) close(code websocket.StatusCode, reason string) {
+	c.once.Do(func() {
+		c.code, c.reason = code, reason
+		close(c.closing)
+	})
+}
+
+// readLoop handles the messages of c until the connection closes, and returns the error
+// that closed it.
+func (c *client) readLoop() error {
+	c.extendDeadline()
+	c.conn.SetPongHandler(func([]byte) { c.extendDeadline() })
+	for {
+		typ, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.extendDeadline()
+		if typ != websocket.TextMessage {
+			c.close(websocket.StatusUnsupportedData, "messages must be JSON text")
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			c.hub.mu.Lock()
+			c.hub.sendLocked(c, errorMessage("", "messages must be JSON objects"))
+			c.hub.removeDroppedLocked()
+			c.hub.mu.Unlock()
+			continue
+		}
+		c.hub.handle(c, m)
+	}
+}
+
+// extendDeadline gives the client Config.PongTimeout more to show it is alive, unless c
+// is closing: the deadline then bounds the wait for the close frame answering ours.
+func (c *client) extendDeadline() {
+	select {
+	case <-c.closing:
+	default:
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.cfg.PongTimeout))
+	}
+}
+
+// writeLoop sends the queued messages and the keepalive pings of c until c is closed or
+// stopping is closed, then sends the close frame.
+func (c *client) writeLoop(stopping <-chan struct{}) {
+	ping := time.NewTicker(c.hub.cfg.PingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case b := <-c.send:
+			err = c.conn.WriteMessage(websocket.TextMessage, b)
+		case <-ping.C:
+			err = c.conn.Ping(nil)
+		case <-stopping:
+			c.close(websocket.StatusGoingAway, "server shutting down")
+			stopping = nil
+		case <-c.closing:
+			_ = c.conn.WriteClose(c.code, c.reason)
+			// The read loop ends with the close frame answering ours, or at the deadline.
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			return
+		}
+		if err != nil {
+			// Also ends the read loop: the connection is unusable.
+			_ = c.conn.Close()
+			return
+		}
+	}
+}
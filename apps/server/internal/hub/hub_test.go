@@ -0,0 +1,312 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/websocket"
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+// authenticated stands for auth's Middleware: the bearer token of requests, if any, is the
+// subject of their principal.
+func authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subject, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Subject: subject}))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newTestHub starts a server for a Hub configured by cfg, and returns the Hub and the ws
+// URL of the server.
+func newTestHub(t *testing.T, cfg Config, opts ...Option) (*Hub, string) {
+	t.Helper()
+	h, err := New(cfg, opts...)
+	require.NoError(t, err, "Setup: New should not fail")
+	srv := httptest.NewServer(authenticated(h))
+	t.Cleanup(srv.Close)
+	return h, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// dial connects to url as subject.
+func dial(t *testing.T, url, subject string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.Dial(context.Background(), url,
+		http.Header{"Authorization": {"Bearer " + subject}})
+	require.NoError(t, err, "Setup: connecting as "+subject)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// send writes m to conn.
+func send(t *testing.T, conn *websocket.Conn, m Message) {
+	t.Helper()
+	b, err := json.Marshal(m)
+	require.NoError(t, err, "Setup: encoding message")
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, b), "sending message")
+}
+
+// receive reads the next message of conn.
+func receive(t *testing.T, conn *websocket.Conn) Message {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)), "Setup: deadline")
+	typ, b, err := conn.ReadMessage()
+	require.NoError(t, err, "receiving message")
+	assert.Equal(t, websocket.TextMessage, typ, "messages should be text")
+	var m Message
+	require.NoError(t, json.Unmarshal(b, &m), "decoding message")
+	return m
+}
+
+func TestHub_Rooms(t *testing.T) {
+	h, url := newTestHub(t, DefaultConfig())
+	alice := dial(t, url, "alice")
+	bob := dial(t, url, "bob")
+
+	send(t, alice, Message{Type: TypeJoin, Room: "doc"})
+	assert.Equal(t, []string{"alice"}, receive(t, alice).Members, "alice should be present")
+
+	send(t, bob, Message{Type: TypeJoin, Room: "doc"})
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		m := receive(t, conn)
+		assert.Equal(t, Message{Type: TypePresence, Room: "doc", Members: []string{"alice", "bob"}},
+			m, "every member should be told who joined")
+	}
+
+	send(t, alice, Message{Type: TypeMessage, Room: "doc", Data: json.RawMessage(`{"x":1}`)})
+	assert.Equal(t,
+		Message{Type: TypeMessage, Room: "doc", From: "alice", Data: json.RawMessage(`{"x":1}`)},
+		receive(t, bob), "messages should reach the other members with their sender")
+
+	require.NoError(t, h.Broadcast("doc", map[string]string{"note": "saved"}), "Broadcast")
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		m := receive(t, conn)
+		assert.Empty(t, m.From, "broadcasts should have no sender")
+		assert.JSONEq(t, `{"note":"saved"}`, string(m.Data), "broadcast data mismatch")
+	}
+
+	// A second connection of alice does not change the presence.
+	send(t, dial(t, url, "alice"), Message{Type: TypeJoin, Room: "doc"})
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		assert.Equal(t, []string{"alice", "bob"}, receive(t, conn).Members,
+			"members connected twice should be present once")
+	}
+
+	send(t, bob, Message{Type: TypeLeave, Room: "doc"})
+	assert.Equal(t, []string{"alice"}, receive(t, alice).Members, "leaving should be announced")
+
+	require.NoError(t, alice.WriteClose(websocket.StatusNormalClosure, ""), "closing alice")
+	assert.Eventually(t, func() bool { return slices.Equal(h.Presence("doc"), []string{"alice"}) },
+		time.Second, time.Millisecond, "the other connection of alice should remain")
+	assert.Nil(t, h.Presence("other"), "unknown rooms should have no members")
+}
+
+func TestHub_InvalidMessages(t *testing.T) {
+	_, url := newTestHub(t, DefaultConfig())
+	conn := dial(t, url, "alice")
+
+	tests := []struct {
+		name          string
+		message       string
+		containsError string
+	}{
+		{name: "not JSON", message: "hello", containsError: "must be JSON objects"},
+		{name: "unknown type", message: `{"type":"shout"}`, containsError: "unknown message type"},
+		{name: "no room", message: `{"type":"join"}`, containsError: "room must be 1 to 128"},
+		{
+			name:          "long room",
+			message:       `{"type":"join","room":"` + strings.Repeat("r", 129) + `"}`,
+			containsError: "room must be 1 to 128",
+		},
+		{
+			name:          "message outside the room",
+			message:       `{"type":"message","room":"doc","data":1}`,
+			containsError: "join the room",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message))
+			require.NoError(t, err, "sending message")
+			m := receive(t, conn)
+			assert.Equal(t, TypeError, m.Type, "the message should be refused")
+			assert.Contains(t, m.Error, tt.containsError, "error mismatch")
+		})
+	}
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{1}), "sending binary")
+	_, _, err := conn.ReadMessage()
+	assert.Equal(t, websocket.StatusUnsupportedData, websocket.CloseStatus(err),
+		"binary messages should close the connection")
+}
+
+func TestHub_Upgrade(t *testing.T) {
+	_, url := newTestHub(t, DefaultConfig(), WithOriginCheck(func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://app.example.com"
+	}))
+	host := strings.TrimPrefix(url, "ws://")
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{
+			name:           "no credentials",
+			header:         http.Header{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "other origin",
+			header:         http.Header{"Origin": {"https://evil.example.com"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "same origin",
+			header:         http.Header{"Origin": {"http://" + host}},
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "allowed origin",
+			header:         http.Header{"Origin": {"https://app.example.com"}},
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectedStatus != http.StatusUnauthorized {
+				tt.header.Set("Authorization", "Bearer alice")
+			}
+			conn, resp, err := websocket.Dial(context.Background(), url, tt.header)
+			if conn != nil {
+				_ = conn.Close()
+			}
+			require.NotNil(t, resp, "the server should answer: %v", err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "status mismatch")
+		})
+	}
+}
+
+func TestHub_SlowClient(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SendBuffer = 2
+	h, url := newTestHub(t, cfg)
+	slow := dial(t, url, "slow")
+	send(t, slow, Message{Type: TypeJoin, Room: "doc"})
+	require.Eventually(t, func() bool { return len(h.Presence("doc")) == 1 }, time.Second,
+		time.Millisecond, "Setup: joining")
+
+	// slow reads nothing: once the socket buffers are full, its send buffer fills up.
+	payload := strings.Repeat("x", 32<<10)
+	for i := 0; i < 10000 && len(h.Presence("doc")) > 0; i++ {
+		require.NoError(t, h.Broadcast("doc", payload), "Broadcast")
+	}
+	assert.Empty(t, h.Presence("doc"), "clients lagging behind should be dropped")
+
+	require.NoError(t, slow.SetReadDeadline(time.Now().Add(5*time.Second)), "Setup: deadline")
+	var err error
+	for err == nil {
+		_, _, err = slow.ReadMessage()
+	}
+	assert.Equal(t, websocket.StatusPolicyViolation, websocket.CloseStatus(err),
+		"the client should be told why it was dropped")
+}
+
+func TestHub_KeepAlive(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PingInterval = 10 * time.Millisecond
+	cfg.PongTimeout = 100 * time.Millisecond
+	h, url := newTestHub(t, cfg)
+
+	// Reading answers the pings.
+	alive := dial(t, url, "alive")
+	send(t, alive, Message{Type: TypeJoin, Room: "doc"})
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	silent := dial(t, url, "silent")
+	send(t, silent, Message{Type: TypeJoin, Room: "doc"})
+	require.Eventually(t, func() bool { return len(h.Presence("doc")) == 2 }, time.Second,
+		time.Millisecond, "Setup: joining")
+
+	assert.Eventually(t, func() bool { return slices.Equal(h.Presence("doc"), []string{"alive"}) },
+		2*time.Second, 5*time.Millisecond, "clients not answering pings should be dropped")
+	time.Sleep(3 * cfg.PongTimeout)
+	assert.Equal(t, []string{"alive"}, h.Presence("doc"),
+		"clients answering pings should stay connected")
+}
+
+func TestHub_Shutdown(t *testing.T) {
+	h, err := New(DefaultConfig())
+	require.NoError(t, err, "Setup: New should not fail")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+	srv := lifecycle.New("", authenticated(h), lifecycle.WithSignals(syscall.SIGUSR1))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	conn := dial(t, "ws://"+ln.Addr().String(), "alice")
+	send(t, conn, Message{Type: TypeJoin, Room: "doc"})
+	receive(t, conn)
+	cancel()
+
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(err),
+		"clients should be told that the server is shutting down")
+	assert.NoError(t, <-done, "the server should shut down")
+	assert.Eventually(t, func() bool { return len(h.Presence("doc")) == 0 }, time.Second,
+		time.Millisecond, "the rooms should be left")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*Config)
+		containsError string
+	}{
+		{name: "defaults", modify: func(*Config) {}},
+		{
+			name:          "no send buffer",
+			modify:        func(c *Config) { c.SendBuffer = 0 },
+			containsError: "send_buffer must be positive",
+		},
+		{
+			name:          "pong timeout shorter than ping interval",
+			modify:        func(c *Config) { c.PongTimeout = c.PingInterval / 2 },
+			containsError: "pong_timeout must be longer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.containsError == "" {
+				assert.NoError(t, err, "Validate should not fail")
+				return
+			}
+			assert.ErrorContains(t, err, tt.containsError, "Validate error mismatch")
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures a Queue. It is meant to be embedded in the configuration of the API
+// server.
+type Config struct {
+	Workers      int           `config:"workers"       usage:"jobs run at the same time"`
+	MaxAttempts  int           `config:"max_attempts"  usage:"attempts before a job is dead"`
+	Backoff      time.Duration `config:"backoff"       usage:"delay before the first retry, then doubled"`
+	MaxBackoff   time.Duration `config:"max_backoff"   usage:"longest delay between attempts"`
+	Timeout      time.Duration `config:"timeout"       usage:"deadline of each attempt"`
+	PollInterval time.Duration `config:"poll_interval" usage:"how often due jobs are looked for"`
+	Retention    time.Duration `config:"retention"     usage:"how long succeeded jobs are kept"`
+}
+
+// DefaultConfig returns a configuration running four jobs at a time, trying each five
+// times over about three minutes, and keeping succeeded jobs for a day.
+func DefaultConfig() Config {
+	return Config{
+		Workers:      4,
+		MaxAttempts:  5,
+		Backoff:      10 * time.Second,
+		MaxBackoff:   time.Hour,
+		Timeout:      5 * time.Minute,
+		PollInterval: time.Second,
+		Retention:    24 * time.Hour,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	switch {
+	case c.Workers <= 0:
+		return errors.New("jobs.workers must be positive")
+	case c.MaxAttempts <= 0:
+		return errors.New("jobs.max_attempts must be positive")
+	case c.Backoff <= 0:
+		return errors.New("jobs.backoff must be positive")
+	case c.MaxBackoff < c.Backoff:
+		return errors.New("jobs.max_backoff must not be shorter than jobs.backoff")
+	case c.Timeout <= 0:
+		return errors.New("jobs.timeout must be positive")
+	case c.PollInterval <= 0:
+		return errors.New("jobs.poll_interval must be positive")
+	case c.Retention <= 0:
+		return errors.New("jobs.retention must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+// Package jobs runs background work outside of the requests of the API server. Handlers
+// are registered for a kind of job with Register, and jobs are enqueued with a JSON
+// payload, to run at once or at a given time. A pool of workers runs the due jobs; failed
+// attempts are retried with an exponential backoff until the jobs run out of attempts and
+// are kept as dead, for inspection. Jobs are kept in a Store, in memory or in the database.
+//
+// Jobs may run more than once, e.g. when the process stops in the middle of one, so their
+// handlers should be idempotent.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// ErrDuplicate is returned by Enqueue when a pending or running job has the same unique key.
+var ErrDuplicate = errors.New("a job with the same unique key is pending or running")
+
+// ErrInterrupted is returned by Shutdown when running jobs had to be interrupted.
+var ErrInterrupted = errors.New("running jobs were interrupted by the shutdown deadline")
+
+// deadJobs is the number of dead jobs listed by Stats.
+const deadJobs = 20
+
+// interruptGrace is how long Shutdown waits for the jobs it interrupts to return. Those
+// that do not are requeued by the next Start.
+const interruptGrace = time.Second
+
+// handlerFunc runs a job given its JSON payload.
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// Queue runs the jobs of its Store with a bounded pool of workers.
+type Queue struct {
+	cfg      Config
+	store    Store
+	handlers map[string]handlerFunc
+	kinds    []string
+
+	// wake is signalled by Enqueue, so that new jobs do not wait for the next poll.
+	wake chan struct{}
+	// stop is closed by Shutdown, and done by the dispatcher once it stopped.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	// ctx is the parent of the contexts of the jobs, cancelled by Shutdown at its deadline.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	busy    atomic.Int32
+
+	mu      sync.Mutex
+	started bool
+}
+
+// New returns a Queue running the jobs of store as configured by cfg. Handlers must be
+// registered before Start.
+func New(cfg Config, store Store) (*Queue, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		cfg:      cfg,
+		store:    store,
+		handlers: make(map[string]handlerFunc),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Register makes fn run the jobs of kind, with their payload decoded into a T. Jobs whose
+// payload cannot be decoded are dead at once. It panics if kind is already registered or
+// the queue has started.
+func Register[T any](q *Queue, kind string, fn func(ctx context.Context, payload T) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		panic("Error: job kind " + kind + " registered after the queue started")
+	}
+	if _, ok := q.handlers[kind]; ok {
+		panic("Error: job kind " + kind + " registered twice")
+	}
+	q.handlers[kind] = func(ctx context.Context, payload []byte) error {
+		var p T
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Permanent(fmt.Errorf("decoding payload: %w", err))
+		}
+		return fn(ctx, p)
+	}
+	q.kinds = append(q.kinds, kind)
+}
+
+// permanentError is an error that retrying the job would not fix.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the job returning it is dead at once, without the attempts
+// it has left, e.g. when its payload refers to something that does not exist anymore.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// EnqueueOption configures a job enqueued by Enqueue.
+type EnqueueOption func(*database.Job)
+
+// At makes the job due at t instead of now.
+func At(t time.Time) EnqueueOption {
+	return func(j *database.Job) { j.RunAt = t }
+}
+
+// After makes the job due after d.
+func After(d time.Duration) EnqueueOption {
+	return At(time.Now().Add(d))
+}
+
+// Unique keeps the job from being enqueued while another one with the same key is pending
+// or running, e.g. to send a single reminder per user.
+func Unique(key string) EnqueueOption {
+	return func(j *database.Job) { j.UniqueKey = key }
+}
+
+// MaxAttempts sets the number of attempts of the job, Config.MaxAttempts by default.
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *database.Job) { j.MaxAttempts = n }
+}
+
+// Enqueue stores a job of kind, whose handler receives payload encoded in JSON, and returns
+// it. It returns an error wrapping ErrDuplicate if the job is Unique and another one with
+// its key is pending or running.
+func (q *Queue) Enqueue(
+	ctx context.Context,
+	kind string,
+	payload any,
+	opts ...EnqueueOption,
+) (database.Job, error) {
+	q.mu.Lock()
+	_, ok := q.handlers[kind]
+	q.mu.Unlock()
+	if !ok {
+		return database.Job{}, errors.New("no handler is registered for job kind " + kind)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return database.Job{}, fmt.Errorf("encoding payload: %w", err)
+	}
+	j := database.Job{Kind: kind, Payload: b, MaxAttempts: q.cfg.MaxAttempts}
+	for _, opt := range opts {
+		opt(&j)
+	}
+	if err := q.store.Enqueue(ctx, &j); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return database.Job{}, fmt.Errorf("%w: %s", ErrDuplicate, j.UniqueKey)
+		}
+		slog.Error("Failed to enqueue job", "kind", kind, "error", err)
+		return database.Job{}, err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return j, nil
+}
+
+// Start requeues the jobs left running by a previous process, then starts the workers.
+// It must be called once, after the handlers are registered.
+func (q *Queue) Start(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return errors.New("the job queue is already started")
+	}
+	n, err := q.store.Requeue(ctx)
+	if err != nil {
+		slog.Error("Failed to requeue interrupted jobs", "error", err)
+		return err
+	}
+	if n > 0 {
+		slog.Warn("Requeued jobs interrupted by a previous run", "jobs", n)
+	}
+	q.started = true
+	go q.dispatch()
+	return nil
+}
+
+// Shutdown stops starting jobs and waits for the running ones to finish. At the deadline
+// of ctx, their contexts are cancelled, they are retried at once by the next Start, and
+// ErrInterrupted is returned. It is meant to be registered as a shutdown hook.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stop) })
+	q.mu.Lock()
+	started := q.started
+	q.mu.Unlock()
+	if !started {
+		return nil
+	}
+	<-q.done
+
+	finished := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		select {
+		case <-finished:
+		case <-time.After(interruptGrace):
+		}
+		return ErrInterrupted
+	}
+}
+
+// dispatch claims the due jobs and runs each in a goroutine, as long as a worker is free,
+// until the queue stops.
+func (q *Queue) dispatch() {
+	defer close(q.done)
+	workers := make(chan struct{}, q.cfg.Workers)
+	poll := time.NewTicker(q.cfg.PollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case workers <- struct{}{}:
+		case <-q.stop:
+			return
+		}
+		j, err := q.store.Claim(q.ctx, q.kinds, time.Now())
+		if err != nil {
+			<-workers
+			if !errors.Is(err, database.ErrNotFound) {
+				slog.Error("Failed to claim job", "error", err)
+			}
+			select {
+			case <-q.wake:
+			case <-poll.C:
+			case <-q.stop:
+				return
+			}
+			continue
+		}
+		q.running.Add(1)
+		go func() {
+			defer q.running.Done()
+			defer func() { <-workers }()
+			q.run(j)
+		}()
+	}
+}
+
+// run runs the attempt of j and records its outcome.
+func (q *Queue) run(j database.Job) {
+	q.busy.Add(1)
+	defer q.busy.Add(-1)
+	ctx, cancel := context.WithTimeout(q.ctx, q.cfg.Timeout)
+	defer cancel()
+	err := q.call(ctx, j)
+
+	// The outcome is recorded even when the queue is shutting down.
+	storeCtx := context.Background()
+	var storeErr error
+	var permanent permanentError
+	switch {
+	case err == nil:
+		storeErr = q.store.Complete(storeCtx, j.ID)
+	case q.ctx.Err() != nil:
+		storeErr = q.store.Retry(
+			storeCtx,
+			j.ID,
+			time.Now(),
+			"interrupted by shutdown: "+err.Error(),
+		)
+	case errors.As(err, &permanent) || j.Attempts >= j.MaxAttempts:
+		slog.Error("Job failed for good", "job", j.ID, "kind", j.Kind, "attempts", j.Attempts,
+			"error", err)
+		storeErr = q.store.Bury(storeCtx, j.ID, err.Error())
+	default:
+		delay := q.backoff(j.Attempts)
+		slog.Warn("Job failed, retrying", "job", j.ID, "kind", j.Kind, "attempts", j.Attempts,
+			"retry_in", delay, "error", err)
+		storeErr = q.store.Retry(storeCtx, j.ID, time.Now().Add(delay), err.Error())
+	}
+	if storeErr != nil {
+		slog.Error("Failed to record job outcome", "job", j.ID, "kind", j.Kind, "error", storeErr)
+	}
+}
+
+// call runs the handler of j, turning a panic into an error.
+func (q *Queue) call(ctx context.Context, j database.Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return q.handlers[j.Kind](ctx, j.Payload)
+}
+
+// backoff returns the delay before the attempt following attempt number n: Config.Backoff
+// doubled for each attempt already failed, up to Config.MaxBackoff.
+func (q *Queue) backoff(n int) time.Duration {
+	d := q.cfg.Backoff
+	for i := 1; i < n && d < q.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.cfg.MaxBackoff)
+}
+
+// Prune deletes the jobs that succeeded longer than Config.Retention ago, and returns
+// their number. Failed jobs are kept for inspection.
+func (q *Queue) Prune(ctx context.Context) (int, error) {
+	n, err := q.store.Prune(ctx, time.Now().Add(-q.cfg.Retention))
+	if err != nil {
+		slog.Error("Failed to prune jobs", "error", err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// Stats is a snapshot of the state of a Queue.
+type Stats struct {
+	// Counts is the number of jobs in each state.
+	Counts map[database.JobState]int
+	// Workers is the size of the worker pool, and Busy the number of workers running a job.
+	Workers int
+	Busy    int
+	// Dead lists the last jobs that failed for good, most recent first.
+	Dead []database.Job
+}
+
+// Stats returns the state of the queue.
+func (q *Queue) Stats(ctx context.Context) (Stats, error) {
+	counts, err := q.store.Counts(ctx)
+	if err != nil {
+		slog.Error("Failed to count jobs", "error", err)
+		return Stats{}, err
+	}
+	dead, err := q.store.List(ctx, database.JobDead, deadJobs)
+	if err != nil {
+		slog.Error("Failed to list dead jobs", "error", err)
+		return Stats{}, err
+	}
+	return Stats{
+		Counts:  counts,
+		Workers: q.cfg.Workers,
+		Busy:    int(q.busy.Load()),
+		Dead:    dead,
+	}, nil
+}
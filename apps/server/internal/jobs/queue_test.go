@@ -0,0 +1,354 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// testConfig returns a configuration with short delays.
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Backoff = 10 * time.Millisecond
+	cfg.MaxBackoff = 40 * time.Millisecond
+	cfg.PollInterval = 5 * time.Millisecond
+	return cfg
+}
+
+// newTestQueue returns a Queue on a MemoryStore, with the handlers registered by register,
+// started and shut down at the end of the test.
+func newTestQueue(t *testing.T, cfg Config, register func(q *Queue)) (*Queue, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	q, err := New(cfg, store)
+	require.NoError(t, err, "Setup: New should not fail")
+	register(q)
+	require.NoError(t, q.Start(context.Background()), "Setup: Start should not fail")
+	t.Cleanup(func() { _ = q.Shutdown(context.Background()) })
+	return q, store
+}
+
+// waitFor waits for the job id to be in state, and returns it.
+func waitFor(t *testing.T, s *MemoryStore, id int64, state database.JobState) database.Job {
+	t.Helper()
+	var j database.Job
+	require.Eventually(t, func() bool {
+		jobs, err := s.List(context.Background(), state, 100)
+		require.NoError(t, err, "List should not fail")
+		for _, j = range jobs {
+			if j.ID == id {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, time.Millisecond, "the job should be %s", state)
+	return j
+}
+
+type email struct {
+	To string `json:"to"`
+}
+
+func TestQueue_Run(t *testing.T) {
+	received := make(chan email, 1)
+	q, store := newTestQueue(t, testConfig(), func(q *Queue) {
+		Register(q, "email", func(_ context.Context, e email) error {
+			received <- e
+			return nil
+		})
+	})
+
+	j, err := q.Enqueue(context.Background(), "email", email{To: "alice"})
+	require.NoError(t, err, "Enqueue should not fail")
+	select {
+	case e := <-received:
+		assert.Equal(t, email{To: "alice"}, e, "the handler should receive the payload")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job should run")
+	}
+	j = waitFor(t, store, j.ID, database.JobSucceeded)
+	assert.Equal(t, 1, j.Attempts, "the job should run once")
+
+	_, err = q.Enqueue(context.Background(), "sms", email{To: "alice"})
+	assert.ErrorContains(t, err, "no handler", "unknown kinds should be refused")
+}
+
+func TestQueue_Retries(t *testing.T) {
+	var calls atomic.Int32
+	var times []time.Time
+	q, store := newTestQueue(t, testConfig(), func(q *Queue) {
+		Register(q, "flaky", func(_ context.Context, fail int32) error {
+			times = append(times, time.Now())
+			if calls.Add(1) <= fail {
+				return errors.New("unavailable")
+			}
+			return nil
+		})
+		Register(q, "broken", func(context.Context, any) error {
+			return errors.New("always down")
+		})
+		Register(q, "invalid", func(context.Context, any) error {
+			return Permanent(errors.New("no such user"))
+		})
+		Register(q, "panics", func(context.Context, any) error {
+			panic("oops")
+		})
+	})
+	ctx := context.Background()
+
+	flaky, err := q.Enqueue(ctx, "flaky", 2)
+	require.NoError(t, err, "Enqueue should not fail")
+	j := waitFor(t, store, flaky.ID, database.JobSucceeded)
+	assert.Equal(t, 3, j.Attempts, "failed attempts should be retried")
+	assert.Equal(t, "unavailable", j.LastError, "the last error should be recorded")
+	require.Len(t, times, 3, "the handler should run three times")
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), 10*time.Millisecond, "first backoff")
+	assert.GreaterOrEqual(t, times[2].Sub(times[1]), 20*time.Millisecond,
+		"the backoff should double")
+
+	tests := []struct {
+		name             string
+		kind             string
+		opts             []EnqueueOption
+		expectedAttempts int
+		containsError    string
+	}{
+		{
+			name:             "attempts exhausted",
+			kind:             "broken",
+			opts:             []EnqueueOption{MaxAttempts(2)},
+			expectedAttempts: 2,
+			containsError:    "always down",
+		},
+		{name: "permanent error", kind: "invalid", expectedAttempts: 1, containsError: "no such"},
+		{
+			name: "panic", kind: "panics", opts: []EnqueueOption{MaxAttempts(1)}, expectedAttempts: 1,
+			containsError: "panic: oops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := q.Enqueue(ctx, tt.kind, nil, tt.opts...)
+			require.NoError(t, err, "Enqueue should not fail")
+			j = waitFor(t, store, j.ID, database.JobDead)
+			assert.Equal(t, tt.expectedAttempts, j.Attempts, "attempts mismatch")
+			assert.Contains(t, j.LastError, tt.containsError, "the cause should be recorded")
+		})
+	}
+
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err, "Stats should not fail")
+	assert.Equal(t, map[database.JobState]int{database.JobSucceeded: 1, database.JobDead: 3},
+		stats.Counts, "counts mismatch")
+	assert.Equal(t, 4, stats.Workers, "workers mismatch")
+	require.Len(t, stats.Dead, 3, "dead jobs should be listed")
+}
+
+func TestQueue_Prune(t *testing.T) {
+	cfg := testConfig()
+	cfg.Retention = time.Millisecond
+	q, store := newTestQueue(t, cfg, func(q *Queue) {
+		Register(q, "noop", func(context.Context, any) error { return nil })
+	})
+	ctx := context.Background()
+
+	j, err := q.Enqueue(ctx, "noop", nil)
+	require.NoError(t, err, "Enqueue should not fail")
+	waitFor(t, store, j.ID, database.JobSucceeded)
+	_, err = q.Enqueue(ctx, "noop", nil, After(time.Hour))
+	require.NoError(t, err, "Enqueue should not fail")
+	time.Sleep(2 * cfg.Retention)
+
+	n, err := q.Prune(ctx)
+	require.NoError(t, err, "Prune should not fail")
+	assert.Equal(t, 1, n, "succeeded jobs past the retention should be pruned")
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err, "Stats should not fail")
+	assert.Equal(t, map[database.JobState]int{database.JobPending: 1}, stats.Counts,
+		"pending jobs should be kept")
+}
+
+func TestQueue_InvalidPayload(t *testing.T) {
+	q, store := newTestQueue(t, testConfig(), func(q *Queue) {
+		Register(q, "email", func(context.Context, email) error { return nil })
+	})
+
+	j, err := q.Enqueue(context.Background(), "email", []int{1})
+	require.NoError(t, err, "Enqueue should not fail")
+	j = waitFor(t, store, j.ID, database.JobDead)
+	assert.Equal(t, 1, j.Attempts, "payloads that cannot be decoded should not be retried")
+	assert.Contains(t, j.LastError, "decoding payload", "the cause should be recorded")
+}
+
+func TestQueue_Scheduling(t *testing.T) {
+	ran := make(chan string, 3)
+	q, store := newTestQueue(t, testConfig(), func(q *Queue) {
+		Register(q, "remind", func(_ context.Context, who string) error {
+			ran <- who
+			return nil
+		})
+	})
+	ctx := context.Background()
+
+	later, err := q.Enqueue(ctx, "remind", "later", After(time.Hour))
+	require.NoError(t, err, "Enqueue should not fail")
+	soon, err := q.Enqueue(ctx, "remind", "soon", At(time.Now().Add(50*time.Millisecond)),
+		Unique("alice"))
+	require.NoError(t, err, "Enqueue should not fail")
+	_, err = q.Enqueue(ctx, "remind", "again", Unique("alice"))
+	assert.ErrorIs(t, err, ErrDuplicate, "pending unique jobs should not be duplicated")
+
+	start := time.Now()
+	select {
+	case who := <-ran:
+		assert.Equal(t, "soon", who, "the due job should run")
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond,
+			"jobs should not run before their time")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scheduled job should run")
+	}
+	waitFor(t, store, soon.ID, database.JobSucceeded)
+	_, err = q.Enqueue(ctx, "remind", "again", Unique("alice"))
+	assert.NoError(t, err, "finished jobs should release their unique key")
+
+	assert.Equal(t, "again", <-ran, "the new job should run")
+	waitFor(t, store, later.ID, database.JobPending)
+}
+
+func TestQueue_Workers(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workers = 2
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	q, store := newTestQueue(t, cfg, func(q *Queue) {
+		Register(q, "slow", func(context.Context, any) error {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		})
+	})
+	ctx := context.Background()
+
+	var last database.Job
+	for range 5 {
+		j, err := q.Enqueue(ctx, "slow", nil)
+		require.NoError(t, err, "Enqueue should not fail")
+		last = j
+	}
+	require.Eventually(t, func() bool { return running.Load() == 2 }, 5*time.Second,
+		time.Millisecond, "the workers should be busy")
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err, "Stats should not fail")
+	assert.Equal(t, 2, stats.Busy, "busy workers mismatch")
+	assert.Equal(t, 3, stats.Counts[database.JobPending], "the other jobs should wait")
+
+	close(release)
+	waitFor(t, store, last.ID, database.JobSucceeded)
+	assert.Equal(t, int32(2), peak.Load(), "at most Workers jobs should run at a time")
+}
+
+func TestQueue_Shutdown(t *testing.T) {
+	t.Run("drain", func(t *testing.T) {
+		started := make(chan struct{})
+		store := NewMemoryStore()
+		q, err := New(testConfig(), store)
+		require.NoError(t, err, "Setup: New should not fail")
+		Register(q, "slow", func(context.Context, any) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+		require.NoError(t, q.Start(context.Background()), "Setup: Start should not fail")
+		j, err := q.Enqueue(context.Background(), "slow", nil)
+		require.NoError(t, err, "Enqueue should not fail")
+		<-started
+
+		require.NoError(t, q.Shutdown(context.Background()), "Shutdown should not fail")
+		waitFor(t, store, j.ID, database.JobSucceeded)
+		late, err := q.Enqueue(context.Background(), "slow", nil)
+		require.NoError(t, err, "Enqueue should not fail")
+		time.Sleep(20 * time.Millisecond)
+		waitFor(t, store, late.ID, database.JobPending)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		started := make(chan struct{})
+		store := NewMemoryStore()
+		q, err := New(testConfig(), store)
+		require.NoError(t, err, "Setup: New should not fail")
+		Register(q, "stuck", func(ctx context.Context, _ any) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		require.NoError(t, q.Start(context.Background()), "Setup: Start should not fail")
+		j, err := q.Enqueue(context.Background(), "stuck", nil)
+		require.NoError(t, err, "Enqueue should not fail")
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, q.Shutdown(ctx), ErrInterrupted, "Shutdown error mismatch")
+		j = waitFor(t, store, j.ID, database.JobPending)
+		assert.Contains(t, j.LastError, "interrupted", "interrupted jobs should be retried")
+	})
+}
+
+func TestRegister_Panics(t *testing.T) {
+	q, _ := newTestQueue(t, testConfig(), func(q *Queue) {
+		Register(q, "email", func(context.Context, email) error { return nil })
+	})
+	assert.Panics(t, func() {
+		Register(q, "sms", func(context.Context, email) error { return nil })
+	}, "registering after Start should panic")
+
+	q, err := New(testConfig(), NewMemoryStore())
+	require.NoError(t, err, "Setup: New should not fail")
+	Register(q, "email", func(context.Context, email) error { return nil })
+	assert.Panics(t, func() {
+		Register(q, "email", func(context.Context, email) error { return nil })
+	}, "registering a kind twice should panic")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*Config)
+		containsError string
+	}{
+		{name: "defaults", modify: func(*Config) {}},
+		{
+			name:          "no workers",
+			modify:        func(c *Config) { c.Workers = 0 },
+			containsError: "workers must be positive",
+		},
+		{
+			name:          "max backoff shorter than backoff",
+			modify:        func(c *Config) { c.MaxBackoff = c.Backoff / 2 },
+			containsError: "max_backoff must not be shorter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.containsError == "" {
+				assert.NoError(t, err, "Validate should not fail")
+				return
+			}
+			assert.ErrorContains(t, err, tt.containsError, "Validate error mismatch")
+		})
+	}
+}
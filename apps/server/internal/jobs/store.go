@@ -0,0 +1,182 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// Store keeps the jobs of a Queue. database.SQLJobRepository keeps them in the database,
+// and MemoryStore in memory, for tests and deployments that can lose pending jobs when
+// they restart.
+type Store interface {
+	database.JobRepository
+}
+
+var (
+	_ Store = (*database.SQLJobRepository)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore is a Store keeping jobs in memory, safe for concurrent use.
+type MemoryStore struct {
+	mu   sync.Mutex
+	seq  int64
+	jobs map[int64]*database.Job
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[int64]*database.Job)}
+}
+
+// Enqueue implements database.JobRepository.
+func (s *MemoryStore) Enqueue(_ context.Context, j *database.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.UniqueKey != "" {
+		for _, other := range s.jobs {
+			if other.UniqueKey == j.UniqueKey && unfinished(other.State) {
+				return database.ErrConflict
+			}
+		}
+	}
+	now := time.Now().UTC()
+	s.seq++
+	j.ID, j.State, j.Attempts, j.LastError = s.seq, database.JobPending, 0, ""
+	if j.RunAt.IsZero() {
+		j.RunAt = now
+	}
+	if j.Payload == nil {
+		j.Payload = []byte("null")
+	}
+	j.RunAt, j.CreatedAt, j.UpdatedAt = j.RunAt.UTC(), now, now
+	stored := *j
+	s.jobs[j.ID] = &stored
+	return nil
+}
+
+// Claim implements database.JobRepository.
+func (s *MemoryStore) Claim(
+	_ context.Context,
+	kinds []string,
+	now time.Time,
+) (database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next *database.Job
+	for _, j := range s.jobs {
+		if j.State != database.JobPending || j.RunAt.After(now) || !slices.Contains(kinds, j.Kind) {
+			continue
+		}
+		if next == nil || j.RunAt.Before(next.RunAt) ||
+			(j.RunAt.Equal(next.RunAt) && j.ID < next.ID) {
+			next = j
+		}
+	}
+	if next == nil {
+		return database.Job{}, database.ErrNotFound
+	}
+	next.State = database.JobRunning
+	next.Attempts++
+	next.UpdatedAt = time.Now().UTC()
+	return *next, nil
+}
+
+// Complete implements database.JobRepository.
+func (s *MemoryStore) Complete(_ context.Context, id int64) error {
+	return s.update(id, func(j *database.Job) { j.State = database.JobSucceeded })
+}
+
+// Retry implements database.JobRepository.
+func (s *MemoryStore) Retry(_ context.Context, id int64, runAt time.Time, reason string) error {
+	return s.update(id, func(j *database.Job) {
+		j.State, j.RunAt, j.LastError = database.JobPending, runAt.UTC(), reason
+	})
+}
+
+// Bury implements database.JobRepository.
+func (s *MemoryStore) Bury(_ context.Context, id int64, reason string) error {
+	return s.update(id, func(j *database.Job) { j.State, j.LastError = database.JobDead, reason })
+}
+
+// update applies fn to the running job id.
+func (s *MemoryStore) update(id int64, fn func(j *database.Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok || j.State != database.JobRunning {
+		return database.ErrNotFound
+	}
+	fn(j)
+	j.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Requeue implements database.JobRepository.
+func (s *MemoryStore) Requeue(context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, j := range s.jobs {
+		if j.State == database.JobRunning {
+			j.State, j.UpdatedAt = database.JobPending, time.Now().UTC()
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Prune implements database.JobRepository.
+func (s *MemoryStore) Prune(_ context.Context, t time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, j := range s.jobs {
+		if j.State == database.JobSucceeded && j.UpdatedAt.Before(t) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Counts implements database.JobRepository.
+func (s *MemoryStore) Counts(context.Context) (map[database.JobState]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[database.JobState]int)
+	for _, j := range s.jobs {
+		counts[j.State]++
+	}
+	return counts, nil
+}
+
+// List implements database.JobRepository.
+func (s *MemoryStore) List(
+	_ context.Context,
+	state database.JobState,
+	limit int,
+) ([]database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []database.Job
+	for _, j := range s.jobs {
+		if j.State == state {
+			jobs = append(jobs, *j)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b database.Job) int {
+		return cmp.Or(b.UpdatedAt.Compare(a.UpdatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return jobs[:min(limit, len(jobs))], nil
+}
+
+// unfinished reports whether a job in state still holds its unique key.
+func unfinished(state database.JobState) bool {
+	return state == database.JobPending || state == database.JobRunning
+}
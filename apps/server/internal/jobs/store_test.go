@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/database"
+)
+
+// newSQLStore returns a Store on a migrated temporary database.
+func newSQLStore(t *testing.T) Store {
+	t.Helper()
+	ctx := context.Background()
+	db, err := database.Open(ctx, database.Config{Path: filepath.Join(t.TempDir(), "api.db")})
+	require.NoError(t, err, "Setup: Open should not fail")
+	t.Cleanup(func() { _ = db.Close() })
+	m, err := database.NewDefaultMigrator(db)
+	require.NoError(t, err, "Setup: NewDefaultMigrator should not fail")
+	_, err = m.Up(ctx)
+	require.NoError(t, err, "Setup: Up should not fail")
+	return database.NewJobRepository(db)
+}
+
+// TestStores checks that MemoryStore behaves as the database.
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{name: "memory", store: func(*testing.T) Store { return NewMemoryStore() }},
+		{name: "sql", store: newSQLStore},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := tt.store(t)
+			now := time.Now()
+
+			late := database.Job{Kind: "a", RunAt: now.Add(-time.Minute), MaxAttempts: 1}
+			require.NoError(t, s.Enqueue(ctx, &late), "Enqueue should not fail")
+			early := database.Job{Kind: "a", RunAt: now.Add(-time.Hour), UniqueKey: "k"}
+			require.NoError(t, s.Enqueue(ctx, &early), "Enqueue should not fail")
+			assert.JSONEq(t, "null", string(early.Payload), "the payload should default to null")
+			future := database.Job{Kind: "b", RunAt: now.Add(time.Hour)}
+			require.NoError(t, s.Enqueue(ctx, &future), "Enqueue should not fail")
+			assert.ErrorIs(t, s.Enqueue(ctx, &database.Job{Kind: "b", UniqueKey: "k"}),
+				database.ErrConflict, "unique keys should be held across kinds")
+
+			j, err := s.Claim(ctx, []string{"a", "b"}, now)
+			require.NoError(t, err, "Claim should not fail")
+			assert.Equal(t, early.ID, j.ID, "the job due first should be claimed first")
+			j, err = s.Claim(ctx, []string{"a", "b"}, now)
+			require.NoError(t, err, "Claim should not fail")
+			assert.Equal(t, late.ID, j.ID, "the next due job should be claimed")
+			_, err = s.Claim(ctx, []string{"a", "b"}, now)
+			assert.ErrorIs(t, err, database.ErrNotFound, "future jobs should not be claimed")
+
+			require.NoError(t, s.Complete(ctx, early.ID), "Complete should not fail")
+			require.NoError(t, s.Bury(ctx, late.ID, "boom"), "Bury should not fail")
+			assert.ErrorIs(t, s.Retry(ctx, late.ID, now, "again"), database.ErrNotFound,
+				"dead jobs should not be retried")
+			assert.ErrorIs(t, s.Complete(ctx, future.ID), database.ErrNotFound,
+				"pending jobs should not be completed")
+
+			counts, err := s.Counts(ctx)
+			require.NoError(t, err, "Counts should not fail")
+			assert.Equal(t, map[database.JobState]int{
+				database.JobPending: 1, database.JobSucceeded: 1, database.JobDead: 1,
+			}, counts, "counts mismatch")
+			dead, err := s.List(ctx, database.JobDead, 10)
+			require.NoError(t, err, "List should not fail")
+			require.Len(t, dead, 1, "List should return the dead job")
+			assert.Equal(t, late.ID, dead[0].ID, "dead job mismatch")
+			assert.Equal(t, "boom", dead[0].LastError, "the cause should be recorded")
+			assert.Equal(t, 1, dead[0].Attempts, "the attempts should be recorded")
+			pending, err := s.List(ctx, database.JobPending, 0)
+			require.NoError(t, err, "List should not fail")
+			assert.Empty(t, pending, "List should return at most limit jobs")
+
+			n, err := s.Prune(ctx, time.Now().Add(time.Second))
+			require.NoError(t, err, "Prune should not fail")
+			assert.Equal(t, 1, n, "succeeded jobs should be pruned")
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Package openapi generates an OpenAPI 3.1 document from the routes of a chi router.
+//
+// Routes whose handler is a *handlers.Endpoint are documented with their parameters,
+// request body and responses, with JSON schemas derived from the Go types. Other routes,
+// such as /metrics, are operational and left out of the document.
+package openapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// Version is the OpenAPI specification version of generated documents.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document, limited to what the generator produces.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lowercase HTTP method.
+type PathItem map[string]*Operation
+
+// Operation is a single API operation on a path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType associates a schema with a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the named schemas and security schemes referenced from the operations.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how clients authenticate, limited to HTTP schemes.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Generate walks routes and returns the document describing them.
+func Generate(routes chi.Routes, info Info) (*Document, error) {
+	doc := &Document{OpenAPI: Version, Info: info, Paths: make(map[string]PathItem)}
+	schemas := newSchemaRegistry()
+	securitySchemes := make(map[string]*SecurityScheme)
+
+	err := chi.Walk(routes, func(
+		method, route string,
+		handler http.Handler,
+		_ ...func(http.Handler) http.Handler,
+	) error {
+		e, ok := unwrap(handler).(*handlers.Endpoint)
+		if !ok || strings.Contains(route, "*") {
+			// Catch-all routes, such as file servers, cannot be expressed in OpenAPI.
+			return nil
+		}
+		desc := e.Description()
+		path, pathParams := convertPattern(route)
+		op := &Operation{
+			OperationID: operationID(method, path),
+			Summary:     desc.Summary,
+			Responses:   make(map[string]*Response),
+		}
+
+		described := make(map[string]handlers.Param)
+		for _, p := range desc.Params {
+			described[p.In+"."+p.Name] = p
+		}
+		for _, name := range pathParams {
+			param := Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			}
+			if p, ok := described["path."+name]; ok {
+				param.Schema = schemas.schemaOf(p.Type)
+			}
+			op.Parameters = append(op.Parameters, param)
+		}
+		for _, p := range desc.Params {
+			if p.In == "query" {
+				op.Parameters = append(op.Parameters, Parameter{
+					Name:   p.Name,
+					In:     "query",
+					Schema: schemas.schemaOf(p.Type),
+				})
+			}
+		}
+
+		if desc.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(schemas.schemaOf(desc.Request)),
+			}
+		}
+		for _, resp := range desc.Responses {
+			r := &Response{Description: http.StatusText(resp.Status)}
+			if resp.Type != nil && resp.ContentType != "" {
+				r.Content = map[string]MediaType{
+					resp.ContentType: {Schema: schemas.schemaOf(resp.Type)},
+				}
+			} else if resp.Type != nil {
+				r.Content = jsonContent(schemas.schemaOf(resp.Type))
+			}
+			op.Responses[strconv.Itoa(resp.Status)] = r
+		}
+		if desc.Problems {
+			op.Responses["default"] = &Response{
+				Description: "Error, described as an RFC 7807 problem",
+				Content: map[string]MediaType{
+					problem.ContentType: {Schema: schemas.schemaOf(problemType)},
+				},
+			}
+		}
+		for _, sec := range desc.Security {
+			securitySchemes[sec.Name] = &SecurityScheme{
+				Type:         "http",
+				Scheme:       sec.Scheme,
+				BearerFormat: sec.BearerFormat,
+				Description:  sec.Description,
+			}
+			op.Security = append(op.Security, map[string][]string{sec.Name: {}})
+		}
+		if len(desc.Security) > 0 {
+			op.Responses[strconv.Itoa(http.StatusUnauthorized)] = &Response{
+				Description: "Missing or invalid credentials",
+				Content: map[string]MediaType{
+					problem.ContentType: {Schema: schemas.schemaOf(problemType)},
+				},
+			}
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to walk routes for the OpenAPI document", "error", err)
+		return nil, err
+	}
+
+	if len(schemas.schemas) > 0 || len(securitySchemes) > 0 {
+		doc.Components = &Components{}
+		if len(schemas.schemas) > 0 {
+			doc.Components.Schemas = schemas.schemas
+		}
+		if len(securitySchemes) > 0 {
+			doc.Components.SecuritySchemes = securitySchemes
+		}
+	}
+	return doc, nil
+}
+
+// JSON returns the indented JSON encoding of the document, ending with a newline so that
+// it can be written to a file as is.
+func (d *Document) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// Handler returns an http.Handler serving the document.
+func Handler(doc *Document) (http.Handler, error) {
+	body, err := doc.JSON()
+	if err != nil {
+		slog.Error("Failed to encode the OpenAPI document", "error", err)
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			slog.Error("Failed to write the OpenAPI document", "error", err)
+		}
+	}), nil
+}
+
+// unwrap returns the endpoint of handlers registered with inline middlewares.
+func unwrap(h http.Handler) http.Handler {
+	for {
+		c, ok := h.(*chi.ChainHandler)
+		if !ok {
+			return h
+		}
+		h = c.Endpoint
+	}
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// chiParam matches a chi URL parameter, with an optional regular expression.
+var chiParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// convertPattern turns a chi route pattern into an OpenAPI path, dropping the regular
+// expressions of parameters, and returns the parameter names.
+func convertPattern(route string) (string, []string) {
+	var names []string
+	path := chiParam.ReplaceAllStringFunc(route, func(m string) string {
+		name := chiParam.FindStringSubmatch(m)[1]
+		names = append(names, name)
+		return "{" + name + "}"
+	})
+	return path, names
+}
+
+// operationID derives a camelCase identifier from the method and path,
+// e.g. "getItemsId" for GET /items/{id}.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
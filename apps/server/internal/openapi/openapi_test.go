@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/handlers"
+)
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type createItemRequest struct {
+	Store  string   `json:"-"               path:"store"`
+	DryRun *bool    `json:"-"                            query:"dry_run"`
+	Tags   []string `json:"-"                            query:"tag"`
+	Name   string   `json:"name"`
+	Price  float64  `json:"price,omitempty"`
+}
+
+type Item struct {
+	Audit
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *Item             `json:"parent,omitempty"`
+	internal string
+}
+
+func createItem(context.Context, createItemRequest) (Item, error) { return Item{}, nil }
+
+func deleteItem(context.Context, struct {
+	ID int `json:"-" path:"id"`
+},
+) (struct{}, error) {
+	return struct{}{}, nil
+}
+
+func newTestRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Method(http.MethodPost, "/stores/{store}/items", handlers.Handle(createItem,
+		handlers.WithStatus(http.StatusCreated), handlers.WithSummary("Create an item")))
+	r.With(func(next http.Handler) http.Handler { return next }).
+		Method(http.MethodDelete, "/items/{id:[0-9]+}",
+			handlers.Handle(deleteItem, handlers.WithStatus(http.StatusNoContent)))
+	r.Get("/metrics", func(http.ResponseWriter, *http.Request) {})
+	r.Handle("/static/*", http.NotFoundHandler())
+	return r
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate(newTestRouter(), Info{Title: "Test", Version: "1"})
+	require.NoError(t, err, "Generate should not fail")
+
+	assert.Equal(t, "3.1.0", doc.OpenAPI, "OpenAPI version mismatch")
+	assert.Len(t, doc.Paths, 2, "only described routes should be documented")
+	assert.NotContains(t, doc.Paths, "/metrics", "undescribed routes should be left out")
+
+	create := doc.Paths["/stores/{store}/items"]["post"]
+	require.NotNil(t, create, "POST /stores/{store}/items should be documented")
+	assert.Equal(t, "postStoresStoreItems", create.OperationID, "operationId mismatch")
+	assert.Equal(t, "Create an item", create.Summary, "summary mismatch")
+	assert.Equal(t, []Parameter{
+		{Name: "store", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "dry_run", In: "query", Schema: &Schema{Type: "boolean"}},
+		{Name: "tag", In: "query", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+	}, create.Parameters, "parameters mismatch")
+
+	require.NotNil(t, create.RequestBody, "request body should be documented")
+	assert.Equal(t, "#/components/schemas/createItemRequest",
+		create.RequestBody.Content["application/json"].Schema.Ref, "request schema mismatch")
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":  {Type: "string"},
+			"price": {Type: "number", Format: "double"},
+		},
+		Required: []string{"name"},
+	}, doc.Components.Schemas["createItemRequest"],
+		"parameters should not be part of the body schema")
+
+	require.Contains(t, create.Responses, "201", "success response should be documented")
+	assert.Equal(t, "#/components/schemas/Item",
+		create.Responses["201"].Content["application/json"].Schema.Ref, "response schema mismatch")
+	require.Contains(t, create.Responses, "default", "problem response should be documented")
+	assert.Equal(t, "#/components/schemas/Problem",
+		create.Responses["default"].Content["application/problem+json"].Schema.Ref,
+		"problem schema mismatch")
+
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"created_at": {Type: "string", Format: "date-time"},
+			"id":         {Type: "integer", Format: "int64"},
+			"name":       {Type: "string"},
+			"labels":     {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"parent":     {Ref: "#/components/schemas/Item"},
+		},
+		Required: []string{"created_at", "id", "name"},
+	}, doc.Components.Schemas["Item"], "embedded and recursive fields should be handled")
+
+	del := doc.Paths["/items/{id}"]["delete"]
+	require.NotNil(t, del, "route regular expressions should be dropped from paths")
+	assert.Nil(t, del.RequestBody, "endpoints without body fields have no request body")
+	assert.Equal(t, []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+	}, del.Parameters, "parameters mismatch")
+	require.Contains(t, del.Responses, "204", "no content response should be documented")
+	assert.Nil(t, del.Responses["204"].Content, "204 responses have no content")
+}
+
+func TestGenerate_Security(t *testing.T) {
+	bearer := handlers.SecurityScheme{Name: "bearerAuth", Scheme: "bearer", BearerFormat: "JWT"}
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/private", handlers.Describe(http.NotFoundHandler(),
+		handlers.Description{Problems: true, Security: []handlers.SecurityScheme{bearer}}))
+	r.Method(http.MethodGet, "/public", handlers.Describe(http.NotFoundHandler(),
+		handlers.Description{}))
+
+	doc, err := Generate(r, Info{Title: "Test", Version: "1"})
+	require.NoError(t, err, "Generate should not fail")
+
+	private := doc.Paths["/private"]["get"]
+	require.NotNil(t, private, "GET /private should be documented")
+	assert.Equal(t, []map[string][]string{{"bearerAuth": {}}}, private.Security,
+		"secured operations should require their scheme")
+	assert.Contains(t, private.Responses, "401", "secured operations should document 401")
+	assert.Empty(t, doc.Paths["/public"]["get"].Security, "public operations need no scheme")
+	require.NotNil(t, doc.Components, "components should hold the security schemes")
+	assert.Equal(t, &SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		doc.Components.SecuritySchemes["bearerAuth"], "security scheme mismatch")
+}
+
+func TestGenerate_ContentType(t *testing.T) {
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/events", handlers.Describe(http.NotFoundHandler(),
+		handlers.Description{Responses: []handlers.Response{{
+			Status:      http.StatusOK,
+			Type:        reflect.TypeFor[Audit](),
+			ContentType: "text/event-stream",
+		}}}))
+
+	doc, err := Generate(r, Info{Title: "Test", Version: "1"})
+	require.NoError(t, err, "Generate should not fail")
+
+	content := doc.Paths["/events"]["get"].Responses["200"].Content
+	require.Contains(t, content, "text/event-stream", "the media type should be documented")
+	assert.NotContains(t, content, "application/json", "the body is not JSON")
+	assert.Equal(t, "#/components/schemas/Audit", content["text/event-stream"].Schema.Ref,
+		"response schema mismatch")
+}
+
+func TestHandler(t *testing.T) {
+	doc, err := Generate(newTestRouter(), Info{Title: "Test", Version: "1"})
+	require.NoError(t, err, "Generate should not fail")
+	h, err := Handler(doc)
+	require.NoError(t, err, "Handler should not fail")
+
+	rr := newRecorder(t, h)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "wrong content type")
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got), "document should be valid JSON")
+	assert.Equal(t, "3.1.0", got["openapi"], "served document mismatch")
+}
+
+func newRecorder(t *testing.T, h http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code, "handler returned wrong status code")
+	return rr
+}
@@ -0,0 +1,113 @@
+// Package problem implements RFC 7807 "Problem Details for HTTP APIs" responses.
+//
+// Handlers return a *Problem as an error to choose the status code and message sent to the
+// client. Any other error is reported as a 500 without exposing its message.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/supergeoff/go-starter/contract"
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = contract.ProblemContentType
+
+// DefaultType is the problem type used when no more specific one applies, in which case
+// the title is the HTTP status text.
+const DefaultType = "about:blank"
+
+// Problem is an RFC 7807 problem details object. It implements error.
+// It is defined by the API contract so that clients decode the same type.
+type Problem = contract.Problem
+
+// FieldError describes why one field of a request is invalid.
+type FieldError = contract.FieldError
+
+// FieldErrors is an error made of several invalid fields, typically returned by the
+// Validate method of a request.
+type FieldErrors []FieldError
+
+// Error joins the field errors.
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, e := range fe {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// New returns a problem of the default type for status with the given detail.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   DefaultType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// BadRequest returns a 400 problem, for requests that cannot be decoded.
+func BadRequest(detail string) *Problem { return New(http.StatusBadRequest, detail) }
+
+// NotFound returns a 404 problem.
+func NotFound(detail string) *Problem { return New(http.StatusNotFound, detail) }
+
+// Conflict returns a 409 problem.
+func Conflict(detail string) *Problem { return New(http.StatusConflict, detail) }
+
+// Validation returns a 422 problem listing the invalid fields of a well-formed request.
+func Validation(errs ...FieldError) *Problem {
+	p := New(http.StatusUnprocessableEntity, "the request has invalid fields")
+	p.Errors = errs
+	return p
+}
+
+// From converts err into a problem: a *Problem anywhere in its chain is used as is,
+// FieldErrors become a validation problem, and anything else is an internal error whose
+// message is not disclosed.
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	var fe FieldErrors
+	if errors.As(err, &fe) {
+		return Validation(fe...)
+	}
+	return New(http.StatusInternalServerError, "")
+}
+
+// Write sends err as a problem response for r. Server errors are logged with the
+// request logger, since their cause is not part of the response.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := *From(err) // Copied so that shared problem values are not modified.
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.Status >= http.StatusInternalServerError {
+		middleware.LoggerFromContext(r.Context()).Error("Request failed",
+			"status", p.Status, "error", err)
+	}
+
+	body, mErr := json.Marshal(p)
+	if mErr != nil {
+		// Only reachable with a custom problem type that cannot be encoded.
+		slog.Error("Failed to encode problem", "error", mErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError),
+			http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(p.Status)
+	if _, wErr := w.Write(body); wErr != nil {
+		slog.Error("Failed to write problem response", "error", wErr)
+	}
+}
@@ -0,0 +1,95 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		expectedStatus   int
+		expectedDetail   string
+		expectedErrors   []FieldError
+		expectedInstance string
+	}{
+		{
+			name:             "problem",
+			err:              NotFound("no such user"),
+			expectedStatus:   http.StatusNotFound,
+			expectedDetail:   "no such user",
+			expectedInstance: "/users/7",
+		},
+		{
+			name:             "wrapped problem",
+			err:              fmt.Errorf("loading user: %w", Conflict("email taken")),
+			expectedStatus:   http.StatusConflict,
+			expectedDetail:   "email taken",
+			expectedInstance: "/users/7",
+		},
+		{
+			name:             "field errors",
+			err:              FieldErrors{{Field: "body.email", Message: "is required"}},
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedDetail:   "the request has invalid fields",
+			expectedErrors:   []FieldError{{Field: "body.email", Message: "is required"}},
+			expectedInstance: "/users/7",
+		},
+		{
+			name:             "other error",
+			err:              errors.New("connection refused"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedInstance: "/users/7",
+		},
+		{
+			name: "explicit instance",
+			err: &Problem{
+				Type:     "https://example.com/probs/quota",
+				Title:    "Quota exceeded",
+				Status:   http.StatusForbidden,
+				Instance: "/quota/1",
+			},
+			expectedStatus:   http.StatusForbidden,
+			expectedInstance: "/quota/1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			Write(rr, httptest.NewRequest(http.MethodGet, "/users/7", nil), tt.err)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, "wrong status code")
+			assert.Equal(t, ContentType, rr.Header().Get("Content-Type"), "wrong content type")
+
+			var got Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got), "decoding problem")
+			assert.Equal(t, tt.expectedStatus, got.Status, "status member mismatch")
+			assert.NotEmpty(t, got.Type, "type member is required")
+			assert.NotEmpty(t, got.Title, "title member is required")
+			assert.Equal(t, tt.expectedDetail, got.Detail, "detail mismatch")
+			assert.Equal(t, tt.expectedErrors, got.Errors, "field errors mismatch")
+			assert.Equal(t, tt.expectedInstance, got.Instance, "instance mismatch")
+			assert.NotContains(
+				t,
+				rr.Body.String(),
+				"connection refused",
+				"internal errors must not leak",
+			)
+		})
+	}
+}
+
+func TestWrite_DoesNotModifySharedProblems(t *testing.T) {
+	shared := NotFound("missing")
+	Write(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil), shared)
+	assert.Empty(t, shared.Instance, "Write should not set the instance of the caller's problem")
+}
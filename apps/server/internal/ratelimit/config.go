@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm and key names accepted in policy specs.
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	KeyIP     = "ip"
+	KeyAPIKey = "api_key"
+	KeyRoute  = "route"
+)
+
+// Config selects the quotas of the API. Policies are written "LIMIT/WINDOW [ALGORITHM]
+// [KEY]", e.g. "100/1m sliding_window api_key": the algorithm defaults to token_bucket and
+// the key, one of ip, api_key and route or several joined with "+", to ip. It is meant to
+// be embedded in the configuration of the API server.
+//
+// PreAuth is the policy applied to every request before it is authenticated, see
+// NewPreAuth: it bounds the work invalid credentials cause, such as API key lookups, which
+// the policies keyed by API key only see once the credentials have been checked.
+type Config struct {
+	Enabled bool     `config:"enabled"  usage:"limit the request rate of API clients"`
+	Default string   `config:"default"  usage:"policy of the routes without one, empty for none"`
+	Routes  []string `config:"routes"   usage:"route policies: PATTERN LIMIT/WINDOW [ALGORITHM] [KEY]"`
+	PreAuth string   `config:"pre_auth" usage:"policy of every request ahead of authentication"`
+}
+
+// DefaultConfig returns a configuration allowing each client 600 requests a minute, in
+// bursts of up to as many, and each address twice as many ahead of authentication, since
+// several clients may share it.
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+		Default: "600/1m token_bucket api_key",
+		PreAuth: "1200/1m token_bucket ip",
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if _, err := c.options(); err != nil {
+		return err
+	}
+	_, err := c.preAuthOptions()
+	return err
+}
+
+// New returns the Limiter configured by c, keeping its state in memory, or nil if rate
+// limiting is disabled.
+func New(c Config) (*Limiter, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewLimiter(NewMemoryStore(), opts...), nil
+}
+
+// NewPreAuth returns the Limiter applying the PreAuth policy of c to every request, keeping
+// its state in memory, or nil if rate limiting is disabled or c has no such policy. It is
+// meant to be mounted ahead of auth.Authenticator.Identify, with the Limiter returned by
+// New after it.
+func NewPreAuth(c Config) (*Limiter, error) {
+	if !c.Enabled || c.PreAuth == "" {
+		return nil, nil
+	}
+	opts, err := c.preAuthOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewLimiter(NewMemoryStore(), opts...), nil
+}
+
+// preAuthOptions converts the PreAuth policy of c into Limiter options.
+func (c Config) preAuthOptions() ([]Option, error) {
+	if c.PreAuth == "" {
+		return nil, nil
+	}
+	p, err := ParsePolicy(c.PreAuth)
+	if err != nil {
+		return nil, errors.New("ratelimit.pre_auth: " + err.Error())
+	}
+	return []Option{WithDefaultPolicy(p)}, nil
+}
+
+// options converts the policies of c into Limiter options.
+func (c Config) options() ([]Option, error) {
+	var opts []Option
+	if c.Default != "" {
+		p, err := ParsePolicy(c.Default)
+		if err != nil {
+			return nil, errors.New("ratelimit.default: " + err.Error())
+		}
+		opts = append(opts, WithDefaultPolicy(p))
+	}
+	for _, route := range c.Routes {
+		pattern, spec, _ := strings.Cut(strings.TrimSpace(route), " ")
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, errors.New("ratelimit.routes: expected a route pattern, got: " + route)
+		}
+		p, err := ParsePolicy(spec)
+		if err != nil {
+			return nil, errors.New("ratelimit.routes: " + pattern + ": " + err.Error())
+		}
+		opts = append(opts, WithRoutePolicy(pattern, p))
+	}
+	return opts, nil
+}
+
+// ParsePolicy parses a policy written "LIMIT/WINDOW [ALGORITHM] [KEY]", see Config.
+func ParsePolicy(spec string) (Policy, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return Policy{}, errors.New("expected LIMIT/WINDOW [ALGORITHM] [KEY], got: " + spec)
+	}
+	n, w, _ := strings.Cut(fields[0], "/")
+	limit, err := strconv.Atoi(n)
+	if err != nil || limit <= 0 {
+		return Policy{}, errors.New("limit must be a positive integer, got: " + n)
+	}
+	window, err := time.ParseDuration(w)
+	if err != nil || window < time.Duration(limit)*time.Millisecond {
+		return Policy{}, errors.New(
+			"window must be a duration of at least 1ms per request, got: " + w,
+		)
+	}
+
+	p := Policy{Algorithm: TokenBucket(limit, window), Key: KeyByIP}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case AlgorithmTokenBucket:
+		case AlgorithmSlidingWindow:
+			p.Algorithm = SlidingWindow(limit, window)
+		default:
+			return Policy{}, errors.New("unknown algorithm: " + fields[1])
+		}
+	}
+	if len(fields) > 2 {
+		var keys []KeyFunc
+		for _, name := range strings.Split(fields[2], "+") {
+			switch name {
+			case KeyIP:
+				keys = append(keys, KeyByIP)
+			case KeyAPIKey:
+				keys = append(keys, KeyByAPIKey)
+			case KeyRoute:
+				keys = append(keys, KeyByRoute)
+			default:
+				return Policy{}, errors.New("unknown key: " + name)
+			}
+		}
+		p.Key = Keys(keys...)
+	}
+	return p, nil
+}
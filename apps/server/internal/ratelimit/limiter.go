@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// KeyFunc returns the key whose quota a request counts against. route is the pattern of the
+// route matching the request, empty when none does.
+type KeyFunc func(r *http.Request, route string) string
+
+// KeyByIP keys requests by client IP address, taken from the connection. Behind a proxy,
+// RemoteAddr must be set from the forwarded headers first, e.g. with chi's RealIP.
+func KeyByIP(r *http.Request, _ string) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyByAPIKey keys requests by the client they are authenticated as, an API key or the
+// subject of a JWT, and anonymous requests by client IP. The principal is only known when
+// auth.Authenticator.Identify runs before the Limiter.
+func KeyByAPIKey(r *http.Request, route string) string {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return "principal:" + p.Subject
+	}
+	return KeyByIP(r, route)
+}
+
+// KeyByRoute keys requests by route pattern, so that every client shares the quota.
+func KeyByRoute(_ *http.Request, route string) string {
+	return "route:" + route
+}
+
+// Keys combines key functions: a request counts against the quota of the combination of
+// its keys, e.g. one quota per route and client IP.
+func Keys(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request, route string) string {
+		keys := make([]string, len(fns))
+		for i, fn := range fns {
+			keys[i] = fn(r, route)
+		}
+		return strings.Join(keys, "|")
+	}
+}
+
+// Policy is the quota applied to the requests of a route.
+type Policy struct {
+	Algorithm Algorithm
+	Key       KeyFunc
+}
+
+// Limiter applies policies to the requests of a chi router. A request is limited by the
+// policy of its route pattern, or the default policy if its route has none.
+type Limiter struct {
+	store  Store
+	def    *Policy
+	routes map[string]Policy
+	now    func() time.Time
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithDefaultPolicy applies p to the routes that have no policy of their own.
+func WithDefaultPolicy(p Policy) Option {
+	return func(l *Limiter) { l.def = &p }
+}
+
+// WithRoutePolicy applies p to the route registered with pattern, e.g. "/api/check".
+func WithRoutePolicy(pattern string, p Policy) Option {
+	return func(l *Limiter) { l.routes[pattern] = p }
+}
+
+// NewLimiter returns a Limiter keeping its state in store. Without policies, it lets every
+// request through.
+func NewLimiter(store Store, opts ...Option) *Limiter {
+	l := &Limiter{store: store, routes: make(map[string]Policy), now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Middleware limits the requests of the router it is mounted on. Responses carry the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and
+// requests over quota get a 429 problem with a Retry-After header. If the store fails,
+// requests are let through. A nil Limiter limits nothing.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routePattern(r)
+		p, id, ok := l.policy(route)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit, window := p.Algorithm.Quota()
+		var res Result
+		err := l.store.Update(r.Context(), id+"|"+p.Key(r, route), 2*window, func(s *State) {
+			res = p.Algorithm.Take(s, l.now())
+		})
+		if err != nil {
+			slog.Error("Failed to check rate limit", "route", route, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", seconds(res.Reset))
+		h.Set("RateLimit-Policy", strconv.Itoa(limit)+";w="+seconds(window))
+		if !res.Allowed {
+			h.Set("Retry-After", seconds(res.RetryAfter))
+			problem.Write(w, r, problem.New(http.StatusTooManyRequests,
+				"rate limit exceeded, retry in "+seconds(res.RetryAfter)+"s"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// policy returns the policy applying to route and the ID its keys are stored under.
+func (l *Limiter) policy(route string) (Policy, string, bool) {
+	if p, ok := l.routes[route]; ok {
+		return p, route, true
+	}
+	if l.def != nil {
+		return *l.def, "default", true
+	}
+	return Policy{}, "", false
+}
+
+// routePattern returns the pattern of the route that will serve r, ahead of routing. The
+// path itself is never used, since clients could then create keys at will.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	return rctx.Routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+}
+
+// seconds formats d as a whole number of seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/auth"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// failingStore is a Store whose backend is down.
+type failingStore struct{}
+
+func (failingStore) Update(context.Context, string, time.Duration, func(*State)) error {
+	return errors.New("store is down")
+}
+
+// newTestRouter returns a router limited by l, with a route per pattern.
+func newTestRouter(l *Limiter, patterns ...string) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(l.Middleware)
+	for _, pattern := range patterns {
+		r.Get(pattern, func(http.ResponseWriter, *http.Request) {})
+	}
+	return r
+}
+
+// get sends a GET request for path from the given address to h.
+func get(h http.Handler, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(),
+		WithDefaultPolicy(Policy{Algorithm: TokenBucket(2, time.Minute), Key: KeyByIP}),
+		WithRoutePolicy("/notes/{id}",
+			Policy{Algorithm: SlidingWindow(1, time.Minute), Key: KeyByRoute}),
+	)
+	l.now = func() time.Time { return testNow }
+	r := newTestRouter(l, "/check", "/notes/{id}")
+
+	rec := get(r, "/check", "192.0.2.1:1234")
+	assert.Equal(t, http.StatusOK, rec.Code, "the first request should pass")
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"), "RateLimit-Limit mismatch")
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"), "RateLimit-Remaining mismatch")
+	assert.Equal(t, "30", rec.Header().Get("RateLimit-Reset"), "RateLimit-Reset mismatch")
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"), "RateLimit-Policy mismatch")
+
+	assert.Equal(t, http.StatusOK, get(r, "/check", "192.0.2.1:5678").Code,
+		"the second request of the address should pass")
+	rec = get(r, "/check", "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "the third request should be limited")
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"), "Retry-After mismatch")
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"),
+		"a limited request should get a problem")
+	assert.Equal(t, http.StatusOK, get(r, "/check", "192.0.2.2:1234").Code,
+		"other addresses should have their own quota")
+
+	// The route policy keys by route, so that every client shares its quota, whatever the
+	// path parameters.
+	assert.Equal(t, http.StatusOK, get(r, "/notes/1", "192.0.2.3:1234").Code,
+		"the first request of the route should pass")
+	rec = get(r, "/notes/2", "192.0.2.4:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "the route quota should be shared")
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"), "the route policy should apply")
+}
+
+func TestLimiter_Middleware_Passthrough(t *testing.T) {
+	policy := Policy{Algorithm: TokenBucket(1, time.Minute), Key: KeyByIP}
+	tests := []struct {
+		name    string
+		limiter *Limiter
+	}{
+		{name: "nil limiter", limiter: nil},
+		{name: "no policy", limiter: NewLimiter(NewMemoryStore())},
+		{name: "store failure", limiter: NewLimiter(failingStore{}, WithDefaultPolicy(policy))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(tt.limiter, "/check")
+			for range 3 {
+				rec := get(r, "/check", "192.0.2.1:1234")
+				assert.Equal(t, http.StatusOK, rec.Code, "requests should not be limited")
+				assert.Empty(t, rec.Header().Get("RateLimit-Limit"), "no quota should be sent")
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/check", nil)
+	req.RemoteAddr = "[2001:db8::1]:1234"
+	key := Keys(KeyByRoute, KeyByAPIKey)
+
+	assert.Equal(t, "route:/check|ip:2001:db8::1", key(req, "/check"),
+		"anonymous requests should be keyed by address")
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "api-key:web"}))
+	assert.Equal(t, "route:/check|principal:api-key:web", key(req, "/check"),
+		"authenticated requests should be keyed by principal")
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectError bool
+	}{
+		{spec: "100/1m"},
+		{spec: "100/1m sliding_window"},
+		{spec: "100/1m token_bucket route+ip"},
+		{spec: "", expectError: true},
+		{spec: "100", expectError: true},
+		{spec: "0/1m", expectError: true},
+		{spec: "100/1ms", expectError: true},
+		{spec: "100/1m leaky_bucket", expectError: true},
+		{spec: "100/1m token_bucket cookie", expectError: true},
+		{spec: "100/1m token_bucket ip extra", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParsePolicy(tt.spec)
+			if tt.expectError {
+				assert.Error(t, err, "ParsePolicy should fail")
+			} else {
+				assert.NoError(t, err, "ParsePolicy should not fail")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	l, err := New(Config{Enabled: false, Default: "100/1m"})
+	require.NoError(t, err, "New should not fail")
+	assert.Nil(t, l, "a disabled limiter should be nil")
+
+	_, err = New(Config{Enabled: true, Routes: []string{"check 100/1m"}})
+	assert.Error(t, err, "New should refuse routes that are not patterns")
+
+	l, err = New(Config{Enabled: true, Routes: []string{"/check 1/1m"}})
+	require.NoError(t, err, "New should not fail")
+	r := newTestRouter(l, "/check", "/other")
+	assert.Equal(t, http.StatusOK, get(r, "/check", "192.0.2.1:1234").Code, "status mismatch")
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "/check", "192.0.2.1:1234").Code,
+		"the route policy should apply")
+	assert.Equal(t, http.StatusOK, get(r, "/other", "192.0.2.1:1234").Code,
+		"routes without policy should not be limited")
+}
+
+func TestNewPreAuth(t *testing.T) {
+	for _, c := range []Config{
+		{Enabled: false, PreAuth: "100/1m"},
+		{Enabled: true, Default: "100/1m"},
+	} {
+		l, err := NewPreAuth(c)
+		require.NoError(t, err, "NewPreAuth should not fail")
+		assert.Nil(t, l, "a disabled or empty pre-auth policy should have no limiter")
+	}
+	_, err := NewPreAuth(Config{Enabled: true, PreAuth: "often"})
+	assert.ErrorContains(t, err, "ratelimit.pre_auth", "NewPreAuth should refuse invalid policies")
+	assert.ErrorContains(t, Config{PreAuth: "often"}.Validate(), "ratelimit.pre_auth",
+		"Validate should refuse invalid policies")
+
+	l, err := NewPreAuth(Config{Enabled: true, Routes: []string{"/check 5/1m"}, PreAuth: "1/1m"})
+	require.NoError(t, err, "NewPreAuth should not fail")
+	r := newTestRouter(l, "/check", "/other")
+	assert.Equal(t, http.StatusOK, get(r, "/check", "192.0.2.1:1234").Code, "status mismatch")
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "/other", "192.0.2.1:1234").Code,
+		"the pre-auth policy should apply to every route of an address")
+	assert.Equal(t, http.StatusOK, get(r, "/check", "192.0.2.2:1234").Code,
+		"other addresses should have their own quota")
+}
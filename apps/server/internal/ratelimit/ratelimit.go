@@ -0,0 +1,130 @@
+// Package ratelimit limits the request rate of API clients. A Limiter applies a policy per
+// route: an Algorithm deciding whether a request fits the quota of its key, and a KeyFunc
+// telling which requests share a quota. The state of each key lives in a Store.
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Result is the outcome of taking one request from a quota.
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests allowed per window.
+	Limit int
+	// Remaining is the number of requests still allowed right now.
+	Remaining int
+	// Reset is the time until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero if Allowed.
+	RetryAfter time.Duration
+}
+
+// Algorithm decides whether a request fits a quota. Implementations keep their state in a
+// State, and must not retain it.
+type Algorithm interface {
+	// Take consumes one request from s at now and reports the outcome.
+	Take(s *State, now time.Time) Result
+	// Quota returns the number of requests allowed per window.
+	Quota() (limit int, window time.Duration)
+}
+
+// TokenBucket returns an Algorithm allowing bursts of up to limit requests, refilled at
+// limit requests per window.
+func TokenBucket(limit int, window time.Duration) Algorithm {
+	return tokenBucket{limit: limit, window: window}
+}
+
+type tokenBucket struct {
+	limit  int
+	window time.Duration
+}
+
+// Quota implements Algorithm.
+func (b tokenBucket) Quota() (int, time.Duration) { return b.limit, b.window }
+
+// Take implements Algorithm. s.Value holds the tokens left at s.At.
+func (b tokenBucket) Take(s *State, now time.Time) Result {
+	capacity := float64(b.limit)
+	perToken := b.window / time.Duration(b.limit)
+	tokens := capacity
+	if !s.At.IsZero() {
+		elapsed := max(now.Sub(s.At), 0)
+		tokens = min(capacity, s.Value+float64(elapsed)/float64(perToken))
+	}
+
+	res := Result{Limit: b.limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	s.Value, s.At = tokens, now
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = time.Duration((capacity - tokens) * float64(perToken))
+	return res
+}
+
+// SlidingWindow returns an Algorithm allowing limit requests in any window. It weighs the
+// count of the previous fixed window by its overlap with the sliding one, which keeps two
+// counters per key instead of a log of requests.
+func SlidingWindow(limit int, window time.Duration) Algorithm {
+	return slidingWindow{limit: limit, window: window}
+}
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+// Quota implements Algorithm.
+func (w slidingWindow) Quota() (int, time.Duration) { return w.limit, w.window }
+
+// Take implements Algorithm. s.Value counts the requests of the fixed window starting at
+// s.At, and s.Previous those of the window before.
+func (w slidingWindow) Take(s *State, now time.Time) Result {
+	start := now.Truncate(w.window)
+	if !s.At.Equal(start) {
+		if s.At.Equal(start.Add(-w.window)) {
+			s.Previous = s.Value
+		} else {
+			s.Previous = 0
+		}
+		s.Value, s.At = 0, start
+	}
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(w.window)
+	estimate := s.Previous*weight + s.Value
+
+	res := Result{Limit: w.limit}
+	if estimate+1 <= float64(w.limit) {
+		s.Value++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = w.retryAfter(s, elapsed)
+	}
+	res.Remaining = max(int(math.Floor(float64(w.limit)-estimate)), 0)
+	// The current window weighs on the next one until its end.
+	res.Reset = 2*w.window - elapsed
+	if s.Value == 0 {
+		res.Reset = w.window - elapsed
+	}
+	return res
+}
+
+// retryAfter returns the time from elapsed into the current window until the estimate
+// leaves room for one more request.
+func (w slidingWindow) retryAfter(s *State, elapsed time.Duration) time.Duration {
+	room := float64(w.limit - 1)
+	if s.Value <= room {
+		// The previous window has to slide out until Previous*weight <= room-Value.
+		weight := (room - s.Value) / s.Previous
+		return time.Duration((1-weight)*float64(w.window)) - elapsed + 1
+	}
+	// The current window is full: wait for the next one, where it weighs as the previous.
+	weight := room / s.Value
+	return w.window - elapsed + time.Duration((1-weight)*float64(w.window)) + 1
+}
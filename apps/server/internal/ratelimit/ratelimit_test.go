@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTokenBucket(t *testing.T) {
+	alg := TokenBucket(3, 3*time.Second)
+	var s State
+
+	for i := range 3 {
+		res := alg.Take(&s, testNow)
+		assert.True(t, res.Allowed, "request %d should fit the burst", i)
+		assert.Equal(t, 2-i, res.Remaining, "request %d: remaining mismatch", i)
+	}
+	res := alg.Take(&s, testNow)
+	assert.False(t, res.Allowed, "a request over the burst should be refused")
+	assert.Equal(t, time.Second, res.RetryAfter, "a token refills every second")
+	assert.Equal(t, 3*time.Second, res.Reset, "the bucket refills in three seconds")
+
+	res = alg.Take(&s, testNow.Add(time.Second))
+	assert.True(t, res.Allowed, "a refilled token should be usable")
+	assert.Equal(t, 0, res.Remaining, "the refilled token should be used")
+
+	res = alg.Take(&s, testNow.Add(time.Hour))
+	assert.True(t, res.Allowed, "an idle bucket should be full")
+	assert.Equal(t, 2, res.Remaining, "tokens should not exceed the capacity")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	alg := SlidingWindow(4, time.Minute)
+	var s State
+
+	for i := range 4 {
+		res := alg.Take(&s, testNow.Add(30*time.Second))
+		assert.True(t, res.Allowed, "request %d should fit the window", i)
+		assert.Equal(t, 3-i, res.Remaining, "request %d: remaining mismatch", i)
+	}
+	res := alg.Take(&s, testNow.Add(30*time.Second))
+	assert.False(t, res.Allowed, "a request over the limit should be refused")
+	// In the next window, the four requests weigh 4*(1-e/60s) <= 3 from e = 15s.
+	assert.Equal(t, 45*time.Second+1, res.RetryAfter, "retry after mismatch")
+
+	res = alg.Take(&s, testNow.Add(time.Minute+10*time.Second))
+	assert.False(t, res.Allowed, "the previous window should still weigh")
+	assert.Equal(t, 5*time.Second+1, res.RetryAfter, "retry after mismatch")
+
+	res = alg.Take(&s, testNow.Add(time.Minute+15*time.Second+1))
+	assert.True(t, res.Allowed, "the previous window should have slid out enough")
+	assert.Equal(t, 0, res.Remaining, "remaining mismatch")
+
+	res = alg.Take(&s, testNow.Add(10*time.Minute))
+	assert.True(t, res.Allowed, "old windows should not weigh")
+	assert.Equal(t, 3, res.Remaining, "remaining mismatch")
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops expired keys while updating.
+const sweepInterval = time.Minute
+
+// State is the state of one key. Its fields are interpreted by the Algorithm using it;
+// stores keep it as is.
+type State struct {
+	Value    float64
+	Previous float64
+	At       time.Time
+}
+
+// Store keeps the state of keys. Implementations must be safe for concurrent use; a
+// backend shared by several replicas lets them enforce a common quota.
+type Store interface {
+	// Update calls fn with the state of key, the zero State if the key is unknown or
+	// expired, and stores the state fn leaves for ttl. Concurrent updates of a key must
+	// not interleave.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(*State)) error
+}
+
+// MemoryStore keeps the state of keys in process memory. Replicas each enforce their own
+// quota.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type entry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]entry), now: time.Now}
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(
+	_ context.Context,
+	key string,
+	ttl time.Duration,
+	fn func(*State),
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, e := range s.entries {
+			if !now.Before(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		e = entry{}
+	}
+	fn(&e.state)
+	e.expiresAt = now.Add(ttl)
+	s.entries[key] = e
+	return nil
+}
@@ -0,0 +1,216 @@
+package schedule
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a task runs.
+type Schedule interface {
+	// Next returns the first time after t when the task runs, or the zero time if it never
+	// does.
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the shorthands accepted by Parse for common cron expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse returns the Schedule described by spec, either:
+//   - a standard cron expression of 5 fields: minute, hour, day of month, month and day of
+//     week, e.g. "*/15 9-17 * * mon-fri". Fields are lists of values, ranges and steps;
+//     months and days of week may be given by their English three-letter names, and
+//     Sunday is 0 or 7. When both days are restricted, either one matching is enough;
+//   - one of @yearly, @monthly, @weekly, @daily and @hourly;
+//   - @every followed by a duration of at least a second, e.g. "@every 1h30m", counted
+//     from the previous run.
+//
+// Cron expressions are evaluated in the location of the times given to Next.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval < time.Second {
+			return nil, errors.New("@every needs a duration of at least 1s, got: " + d)
+		}
+		return every(interval), nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.New(
+			"expected 5 fields (minute hour day-of-month month day-of-week), got: " + spec,
+		)
+	}
+	var s cron
+	var err error
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], daysOfMonth); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], daysOfWeek); err != nil {
+		return nil, err
+	}
+	// Sunday is both 0 and 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+
+	// Dates such as February 30 never come; every other date does within 5 years.
+	if s.Next(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, errors.New("the expression never matches a date: " + spec)
+	}
+	return &s, nil
+}
+
+// every runs a task at a fixed interval.
+type every time.Duration
+
+// Next implements Schedule.
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron runs a task at the times matching a cron expression. Each field is a set of
+// values, as a bitmask.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is set when the day of month or of week is not restricted, so that both have to
+	// match instead of either one.
+	anyDay bool
+}
+
+// Next implements Schedule, looking for a match in the next 5 years.
+func (s *cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t matches the day of month and the day of week.
+func (s *cron) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// field describes the values of a field of cron expressions.
+type field struct {
+	name     string
+	min, max int
+	// names are the names accepted for values, lowercase, from min on.
+	names []string
+}
+
+var (
+	minutes     = field{name: "minute", min: 0, max: 59}
+	hours       = field{name: "hour", min: 0, max: 23}
+	daysOfMonth = field{name: "day of month", min: 1, max: 31}
+	months      = field{
+		name: "month", min: 1, max: 12,
+		names: []string{
+			"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+		},
+	}
+	daysOfWeek = field{
+		name: "day of week", min: 0, max: 7,
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"},
+	}
+)
+
+// parseField returns the values of a field of a cron expression, a comma-separated list
+// of *, single values and ranges, each optionally followed by a /step.
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(first); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(last); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, errors.New(f.name + " range must not be reversed, got: " + rng)
+				}
+			} else if hasStep {
+				// A single value with a step, e.g. 5/15, stands for the range to the maximum.
+				hi = f.max
+			}
+		}
+		n := 1
+		if hasStep {
+			var err error
+			n, err = strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return 0, errors.New(f.name + " step must be a positive integer, got: " + step)
+			}
+		}
+		for v := lo; v <= hi; v += n {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of f, given as a number or a name.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New(
+			f.name + " must be between " + strconv.Itoa(f.min) + " and " + strconv.Itoa(f.max) +
+				", got: " + s,
+		)
+	}
+	return v, nil
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	// 2026-01-01 is a Thursday.
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{name: "step", spec: "*/15 * * * *", from: at(1, 1, 10, 7), expected: at(1, 1, 10, 15)},
+		{
+			name:     "strictly after",
+			spec:     "0 * * * *",
+			from:     at(1, 1, 10, 0),
+			expected: at(1, 1, 11, 0),
+		},
+		{
+			name:     "seconds are ignored",
+			spec:     "* * * * *",
+			from:     at(1, 1, 10, 0).Add(30 * time.Second),
+			expected: at(1, 1, 10, 1),
+		},
+		{
+			name:     "weekdays by name",
+			spec:     "0 9 * * MON-fri",
+			from:     at(1, 2, 10, 0),
+			expected: at(1, 5, 9, 0),
+		},
+		{name: "list", spec: "0 0 1,15 * *", from: at(1, 2, 0, 0), expected: at(1, 15, 0, 0)},
+		{
+			name:     "either day",
+			spec:     "0 0 13 * fri",
+			from:     at(1, 1, 0, 0),
+			expected: at(1, 2, 0, 0),
+		},
+		{
+			name:     "month by name",
+			spec:     "30 4 * feb *",
+			from:     at(1, 1, 0, 0),
+			expected: at(2, 1, 4, 30),
+		},
+		{name: "sunday as 7", spec: "0 0 * * 7", from: at(1, 1, 0, 0), expected: at(1, 4, 0, 0)},
+		{
+			name:     "start and step",
+			spec:     "5/20 * * * *",
+			from:     at(1, 1, 10, 7),
+			expected: at(1, 1, 10, 25),
+		},
+		{name: "descriptor", spec: "@daily", from: at(1, 1, 10, 0), expected: at(1, 2, 0, 0)},
+		{
+			name:     "leap day",
+			spec:     "0 0 29 2 *",
+			from:     at(3, 1, 0, 0),
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "interval",
+			spec:     "@every 1h30m",
+			from:     at(1, 1, 10, 7),
+			expected: at(1, 1, 11, 37),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err, "Parse should not fail")
+			assert.Equal(t, tt.expected, s.Next(tt.from), "Next mismatch")
+		})
+	}
+}
+
+func TestParse_Location(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err, "Parse should not fail")
+	next := s.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, paris))
+	assert.Equal(t, time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), next.UTC(),
+		"expressions should be evaluated in the location of the given time")
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		containsError string
+	}{
+		{name: "missing field", spec: "* * * *", containsError: "expected 5 fields"},
+		{
+			name:          "out of range",
+			spec:          "60 * * * *",
+			containsError: "minute must be between 0 and 59",
+		},
+		{name: "unknown name", spec: "0 0 * foo *", containsError: "month must be between"},
+		{name: "reversed range", spec: "5-1 * * * *", containsError: "must not be reversed"},
+		{name: "zero step", spec: "*/0 * * * *", containsError: "step must be a positive"},
+		{name: "impossible date", spec: "0 0 30 2 *", containsError: "never matches"},
+		{name: "unknown descriptor", spec: "@often", containsError: "expected 5 fields"},
+		{name: "short interval", spec: "@every 10ms", containsError: "at least 1s"},
+		{name: "invalid interval", spec: "@every soon", containsError: "at least 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			assert.ErrorContains(t, err, tt.containsError, "Parse error mismatch")
+		})
+	}
+}
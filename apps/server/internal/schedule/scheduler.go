@@ -0,0 +1,243 @@
+// Package schedule runs periodic tasks inside the API server, such as cleanups and
+// reports, on cron expressions or fixed intervals. A task never overlaps itself: a run due
+// while the previous one is still going is skipped. The time is read from a Clock, which
+// tests replace to control it.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Task is the work of a scheduled task. Its context is cancelled when the scheduler shuts
+// down.
+type Task func(ctx context.Context) error
+
+// Clock tells the time to a Scheduler.
+type Clock interface {
+	Now() time.Time
+	// After sends the current time on the returned channel once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock of the system.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithClock makes the scheduler read the time from c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) { s.clock = c }
+}
+
+// Entry is the state of a scheduled task.
+type Entry struct {
+	Name string
+	// Spec is the schedule of the task, as given to Add.
+	Spec string
+	// Next is when the task runs next, zero before Start.
+	Next time.Time
+	// Last is when the last run started, LastDuration how long it took and LastError how it
+	// failed, if it did. Last is zero until the task first runs.
+	Last         time.Time
+	LastDuration time.Duration
+	LastError    string
+	// Running is set while the task runs.
+	Running bool
+}
+
+// entry is a task and its state.
+type entry struct {
+	Entry
+	schedule Schedule
+	task     Task
+}
+
+// Scheduler runs tasks on their schedule.
+type Scheduler struct {
+	clock Clock
+
+	// stop is closed by Shutdown, and done by the loop once it stopped.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	// ctx is the parent of the contexts of the tasks, cancelled by Shutdown.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+
+	mu      sync.Mutex
+	entries []*entry
+	started bool
+}
+
+// New returns a Scheduler without tasks.
+func New(opts ...Option) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		clock:  realClock{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add schedules task under name, on the schedule described by spec in the syntax of Parse.
+// Tasks must be added before Start.
+func (s *Scheduler) Add(name, spec string, task Task) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("task " + name + " added after the scheduler started")
+	}
+	for _, e := range s.entries {
+		if e.Name == name {
+			return errors.New("task " + name + " added twice")
+		}
+	}
+	s.entries = append(s.entries, &entry{
+		Entry:    Entry{Name: name, Spec: spec},
+		schedule: schedule,
+		task:     task,
+	})
+	return nil
+}
+
+// Start schedules the next run of every task, then runs them when due until Shutdown.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	now := s.clock.Now()
+	for _, e := range s.entries {
+		e.Next = e.schedule.Next(now)
+	}
+	go s.loop()
+}
+
+// Shutdown stops running tasks, cancels the context of those running and waits for them
+// to return, until ctx is done. It is meant to be registered as a shutdown hook.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if !started {
+		return nil
+	}
+	<-s.done
+	s.cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for running tasks: %w", ctx.Err())
+	}
+}
+
+// Entries returns the state of the tasks, in the order they were added.
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, len(s.entries))
+	for i, e := range s.entries {
+		entries[i] = e.Entry
+	}
+	return entries
+}
+
+// loop runs the due tasks, then sleeps until the next one is due, until the scheduler
+// stops.
+func (s *Scheduler) loop() {
+	defer close(s.done)
+	for {
+		now := s.clock.Now()
+		var next time.Time
+		s.mu.Lock()
+		for _, e := range s.entries {
+			if e.Next.IsZero() {
+				continue
+			}
+			if !e.Next.After(now) {
+				s.launch(e, now)
+				e.Next = e.schedule.Next(now)
+			}
+			if !e.Next.IsZero() && (next.IsZero() || e.Next.Before(next)) {
+				next = e.Next
+			}
+		}
+		s.mu.Unlock()
+
+		// Without any run to come, only the stop is waited for.
+		var due <-chan time.Time
+		if !next.IsZero() {
+			due = s.clock.After(next.Sub(now))
+		}
+		select {
+		case <-due:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// launch runs e in a goroutine, unless it is still running. s.mu must be held.
+func (s *Scheduler) launch(e *entry, now time.Time) {
+	if e.Running {
+		slog.Warn("Skipped scheduled task still running", "task", e.Name, "since", e.Last)
+		return
+	}
+	e.Running, e.Last = true, now
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		err := call(s.ctx, e.task)
+		duration := s.clock.Now().Sub(now)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.Running, e.LastDuration, e.LastError = false, duration, ""
+		if err != nil {
+			e.LastError = err.Error()
+			slog.Error("Scheduled task failed", "task", e.Name, "error", err)
+			return
+		}
+		slog.Debug("Scheduled task finished", "task", e.Name, "duration", duration)
+	}()
+}
+
+// call runs task, turning a panic into an error.
+func call(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return task(ctx)
+}
@@ -0,0 +1,243 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock whose time only moves when told to.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// advance waits for the scheduler to sleep, then moves the time forward by d.
+func (c *fakeClock) advance(t *testing.T, d time.Duration) {
+	t.Helper()
+	c.waitIdle(t)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// waitIdle waits for the scheduler to sleep until the next run.
+func (c *fakeClock) waitIdle(t *testing.T) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.waiters) == 1
+	}, 5*time.Second, time.Millisecond, "the scheduler should wait for the next run")
+}
+
+// newTestScheduler returns a started Scheduler on clock with the tasks added by add, shut
+// down at the end of the test.
+func newTestScheduler(t *testing.T, clock Clock, add func(s *Scheduler)) *Scheduler {
+	t.Helper()
+	s := New(WithClock(clock))
+	add(s)
+	s.Start()
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return s
+}
+
+// receive returns the next value of ch.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the task should run")
+		panic("unreachable")
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	ran := make(chan string, 2)
+	s := newTestScheduler(t, clock, func(s *Scheduler) {
+		require.NoError(t, s.Add("cleanup", "*/15 * * * *", func(context.Context) error {
+			ran <- "cleanup"
+			return nil
+		}), "Add should not fail")
+		require.NoError(t, s.Add("report", "@every 1h", func(context.Context) error {
+			ran <- "report"
+			return errors.New("no data")
+		}), "Add should not fail")
+	})
+
+	entries := s.Entries()
+	require.Len(t, entries, 2, "every task should be listed")
+	assert.Equal(t, start.Add(15*time.Minute), entries[0].Next, "next cleanup mismatch")
+	assert.Equal(t, start.Add(time.Hour), entries[1].Next, "next report mismatch")
+	assert.True(t, entries[0].Last.IsZero(), "tasks should not have run yet")
+
+	clock.advance(t, 10*time.Minute)
+	clock.waitIdle(t)
+	assert.Empty(t, ran, "tasks should not run before their time")
+
+	clock.advance(t, 5*time.Minute)
+	assert.Equal(t, "cleanup", receive(t, ran), "the due task should run")
+	assert.Eventually(t, func() bool { return !s.Entries()[0].Running }, time.Second,
+		time.Millisecond, "the task should finish")
+	entries = s.Entries()
+	assert.Equal(t, start.Add(15*time.Minute), entries[0].Last, "last cleanup mismatch")
+	assert.Equal(t, start.Add(30*time.Minute), entries[0].Next, "next cleanup mismatch")
+	assert.Empty(t, entries[0].LastError, "the run should succeed")
+
+	clock.advance(t, 45*time.Minute)
+	runs := []string{receive(t, ran), receive(t, ran)}
+	assert.ElementsMatch(t, []string{"cleanup", "report"}, runs, "both tasks should run")
+	assert.Eventually(t, func() bool { return s.Entries()[1].LastError == "no data" },
+		time.Second, time.Millisecond, "the failure should be recorded")
+	assert.Equal(t, start.Add(2*time.Hour), s.Entries()[1].Next, "next report mismatch")
+}
+
+func TestScheduler_Overlap(t *testing.T) {
+	clock := newFakeClock()
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	s := newTestScheduler(t, clock, func(s *Scheduler) {
+		require.NoError(t, s.Add("slow", "@every 1m", func(context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}), "Add should not fail")
+	})
+	start := clock.Now()
+
+	clock.advance(t, time.Minute)
+	receive(t, started)
+	clock.advance(t, time.Minute)
+	clock.waitIdle(t)
+	entry := s.Entries()[0]
+	assert.True(t, entry.Running, "the first run should still be going")
+	assert.Equal(t, start.Add(time.Minute), entry.Last, "the second run should be skipped")
+	assert.Equal(t, start.Add(3*time.Minute), entry.Next, "the next run should be scheduled")
+	assert.Empty(t, started, "runs should not overlap")
+
+	close(release)
+	assert.Eventually(t, func() bool { return !s.Entries()[0].Running }, time.Second,
+		time.Millisecond, "the task should finish")
+	clock.advance(t, time.Minute)
+	receive(t, started)
+}
+
+func TestScheduler_Shutdown(t *testing.T) {
+	t.Run("cancel", func(t *testing.T) {
+		clock := newFakeClock()
+		started := make(chan struct{}, 1)
+		s := New(WithClock(clock))
+		require.NoError(t, s.Add("wait", "@every 1m", func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}), "Add should not fail")
+		s.Start()
+		clock.advance(t, time.Minute)
+		receive(t, started)
+
+		require.NoError(t, s.Shutdown(context.Background()), "Shutdown should not fail")
+		entry := s.Entries()[0]
+		assert.False(t, entry.Running, "the task should have returned")
+		assert.Contains(t, entry.LastError, "context canceled",
+			"the task context should be cancelled")
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		clock := newFakeClock()
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		defer close(release)
+		s := New(WithClock(clock))
+		require.NoError(t, s.Add("stuck", "@every 1m", func(context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}), "Add should not fail")
+		s.Start()
+		clock.advance(t, time.Minute)
+		receive(t, started)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded, "Shutdown error mismatch")
+	})
+
+	t.Run("not started", func(t *testing.T) {
+		assert.NoError(t, New().Shutdown(context.Background()), "Shutdown should not fail")
+	})
+}
+
+func TestScheduler_Panic(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(t, clock, func(s *Scheduler) {
+		require.NoError(t, s.Add("oops", "@every 1m", func(context.Context) error {
+			panic("oops")
+		}), "Add should not fail")
+	})
+
+	clock.advance(t, time.Minute)
+	assert.Eventually(t, func() bool { return s.Entries()[0].LastError == "panic: oops" },
+		time.Second, time.Millisecond, "panics should be recorded as failures")
+}
+
+func TestScheduler_Add(t *testing.T) {
+	s := New()
+	noop := func(context.Context) error { return nil }
+	require.NoError(t, s.Add("cleanup", "@hourly", noop), "Add should not fail")
+
+	assert.ErrorContains(t, s.Add("report", "every hour", noop), "task report: expected 5",
+		"invalid schedules should be refused")
+	assert.ErrorContains(t, s.Add("cleanup", "@daily", noop), "added twice",
+		"names should be unique")
+	s.Start()
+	defer func() { _ = s.Shutdown(context.Background()) }()
+	assert.ErrorContains(t, s.Add("report", "@daily", noop), "after the scheduler started",
+		"tasks should be added before Start")
+}
@@ -0,0 +1,226 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+// acceptGUID is appended to the key of a handshake to compute the accept value proving
+// the server understood it, see RFC 6455 section 4.2.2.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// handshakeTimeout bounds the write of the handshake response.
+const handshakeTimeout = 10 * time.Second
+
+// maxErrorBody bounds the body of a refused handshake kept by Dial.
+const maxErrorBody = 64 << 10
+
+// ErrBadHandshake is wrapped by the errors of Dial when the server refuses the handshake.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Upgrade completes the WebSocket handshake of r and returns the connection, which the
+// caller must close. Requests that are not valid handshakes get a problem response, 426
+// Upgrade Required if they do not ask for WebSocket version 13 at all, and an error is
+// returned. The headers already set on w, such as X-Request-Id, are sent with the
+// handshake response.
+//
+// Upgrade does not check the Origin header: handlers serving browsers must do so, as
+// browsers let any page open WebSocket connections to any server.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if status, err := checkHandshake(r); err != nil {
+		if status == http.StatusUpgradeRequired {
+			w.Header().Set("Upgrade", "websocket")
+			w.Header().Set("Sec-WebSocket-Version", "13")
+		}
+		problem.Write(w, r, problem.New(status, err.Error()))
+		return nil, err
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		slog.Error("Failed to take over WebSocket connection", "error", err)
+		problem.Write(w, r, err)
+		return nil, err
+	}
+	// The deadlines set by the HTTP server do not apply to the WebSocket connection.
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	var resp bytes.Buffer
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h := w.Header().Clone()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(r.Header.Get("Sec-WebSocket-Key")))
+	_ = h.Write(&resp)
+	resp.WriteString("\r\n")
+	if _, err := conn.Write(resp.Bytes()); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return newConn(conn, brw.Reader, false), nil
+}
+
+// checkHandshake returns an error, with the status of the response refusing it, if r is
+// not a valid opening handshake.
+func checkHandshake(r *http.Request) (int, error) {
+	switch {
+	case r.Method != http.MethodGet:
+		return http.StatusBadRequest, errors.New("the WebSocket handshake must be a GET request")
+	case !headerHasToken(r.Header, "Connection", "upgrade"),
+		!headerHasToken(r.Header, "Upgrade", "websocket"):
+		return http.StatusUpgradeRequired, errors.New("the request must ask for an upgrade " +
+			"to the WebSocket protocol")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		return http.StatusUpgradeRequired, errors.New("only version 13 of the WebSocket " +
+			"protocol is supported")
+	}
+	key, err := base64.StdEncoding.DecodeString(r.Header.Get("Sec-WebSocket-Key"))
+	if err != nil || len(key) != 16 {
+		return http.StatusBadRequest, errors.New("Sec-WebSocket-Key must be 16 bytes " +
+			"encoded in base64")
+	}
+	return 0, nil
+}
+
+// Dial opens a WebSocket connection to rawURL, a ws or wss URL, sending header with the
+// handshake request, e.g. to authenticate. The handshake response is returned with the
+// connection; when the server refuses the handshake, it is returned with its body and an
+// error wrapping ErrBadHandshake.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	port := "80"
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme, port = "https", "443"
+	default:
+		return nil, nil, errors.New("websocket: URL scheme must be ws or wss, got: " + u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, resp, err := handshake(ctx, conn, u, header)
+	if err != nil {
+		_ = conn.Close()
+		return nil, resp, err
+	}
+	return c, resp, nil
+}
+
+// handshake sends the opening handshake to u over conn and checks the response.
+func handshake(
+	ctx context.Context,
+	conn net.Conn,
+	u *url.URL,
+	header http.Header,
+) (*Conn, *http.Response, error) {
+	// Cancelling ctx interrupts the handshake.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+	if u.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: u.Hostname(),
+			NextProtos: []string{"http/1.1"},
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, nil, err
+		}
+		conn = tlsConn
+	}
+
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    u,
+		Host:   u.Host,
+		Header: header.Clone(),
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return nil, nil, ctxErr(ctx, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, ctxErr(ctx, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil, resp, fmt.Errorf("%w: status %d", ErrBadHandshake, resp.StatusCode)
+	}
+	if !headerHasToken(resp.Header, "Upgrade", "websocket") ||
+		!headerHasToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, resp, fmt.Errorf("%w: invalid upgrade response", ErrBadHandshake)
+	}
+	if !stop() {
+		return nil, resp, ctx.Err()
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return newConn(conn, br, true), resp, nil
+}
+
+// ctxErr returns the error of ctx if it interrupted the handshake, and err otherwise.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value answering key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether the comma-separated list of header name contains token,
+// ignoring case.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for t := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
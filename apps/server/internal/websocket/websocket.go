@@ -0,0 +1,420 @@
+// Package websocket implements the WebSocket protocol of RFC 6455: Upgrade turns an HTTP
+// request into a Conn on the server, and Dial opens one as a client. Messages are sent in
+// a single frame and received whole; extensions such as compression are not supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// Message types.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// maxControlPayload is the largest payload of a control frame.
+const maxControlPayload = 125
+
+// DefaultReadLimit is the size, in bytes, of the largest message a Conn accepts unless
+// SetReadLimit says otherwise.
+const DefaultReadLimit = 1 << 20
+
+// StatusCode is the status code of a close frame, telling why the connection is closed.
+type StatusCode int
+
+// Status codes defined by RFC 6455 and the IANA registry. StatusNoStatus is reported when
+// a close frame carries no code; it is never sent.
+const (
+	StatusNormalClosure   StatusCode = 1000
+	StatusGoingAway       StatusCode = 1001
+	StatusProtocolError   StatusCode = 1002
+	StatusUnsupportedData StatusCode = 1003
+	StatusNoStatus        StatusCode = 1005
+	StatusInvalidPayload  StatusCode = 1007
+	StatusPolicyViolation StatusCode = 1008
+	StatusMessageTooBig   StatusCode = 1009
+	StatusInternalError   StatusCode = 1011
+	StatusTryAgainLater   StatusCode = 1013
+)
+
+// ErrClosed is returned when writing to a Conn after its close frame was sent.
+var ErrClosed = errors.New("websocket: close frame already sent")
+
+// CloseError is returned by ReadMessage once the connection is closed: Code is the status
+// received from the peer, or the one sent when the peer broke the protocol.
+type CloseError struct {
+	Code   StatusCode
+	Reason string
+}
+
+// Error returns the status code and reason of the close frame.
+func (e *CloseError) Error() string {
+	msg := "websocket: closed with status " + strconv.Itoa(int(e.Code))
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// CloseStatus returns the status code of err if it is, or wraps, a *CloseError, and -1
+// otherwise.
+func CloseStatus(err error) StatusCode {
+	var ce *CloseError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	return -1
+}
+
+// Conn is a WebSocket connection. Its messages must be read by a single goroutine, while
+// any number of goroutines may write: frames are written one at a time.
+//
+// ReadMessage answers pings and close frames, so a Conn must be read for the connection
+// to stay healthy, even by applications that only send messages.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// client tells whether the frames sent are masked, as they must be by clients, and
+	// those received must not be.
+	client bool
+
+	readLimit   int64
+	pongHandler func(data []byte)
+	readErr     error
+
+	wmu          sync.Mutex
+	writeTimeout time.Duration
+	closeSent    bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool) *Conn {
+	return &Conn{conn: conn, br: br, client: client, readLimit: DefaultReadLimit}
+}
+
+// SetReadLimit sets the size, in bytes, of the largest message accepted. The connection
+// is closed with StatusMessageTooBig when a larger one is received.
+func (c *Conn) SetReadLimit(n int64) {
+	c.readLimit = n
+}
+
+// SetReadDeadline sets the deadline of the reads of the connection; a read exceeding it
+// breaks the connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteTimeout bounds each frame write to d, none by default. A write exceeding it
+// breaks the connection.
+func (c *Conn) SetWriteTimeout(d time.Duration) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.writeTimeout = d
+}
+
+// SetPongHandler sets the function called by ReadMessage with the payload of each pong
+// received. It must be set before reading.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// RemoteAddr returns the network address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close closes the network connection, without a close frame: WriteClose sends one first.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// WriteMessage sends data as a single message of type typ.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return errors.New("websocket: unknown message type " + strconv.Itoa(int(typ)))
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// Ping sends a ping carrying data, at most 125 bytes; the peer answers with a pong
+// carrying the same data.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: ping payload exceeds 125 bytes")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// WriteClose starts the closing handshake by sending a close frame with code and reason,
+// cut to fit in a control frame. The peer answers with its own close frame, which
+// ReadMessage returns as a *CloseError; the network connection can then be closed.
+// Nothing can be written afterwards.
+func (c *Conn) WriteClose(code StatusCode, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, truncateUTF8(reason, maxControlPayload-2)...)
+	return c.writeFrame(opClose, payload)
+}
+
+// writeFrame sends payload in a single frame with opcode op.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op)
+	var mask byte
+	if c.client {
+		mask = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, mask|byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, mask|126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, mask|127), uint64(n))
+	}
+	if c.client {
+		var key [4]byte
+		_, _ = rand.Read(key[:])
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(key, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if c.writeTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// ReadMessage returns the next data message. It answers the pings and close frames
+// received in the meantime, and returns a *CloseError once the connection is closed.
+// Peers breaking the protocol, e.g. with a message over the read limit or a text message
+// that is not UTF-8, get a close frame telling why. Once it fails, ReadMessage keeps
+// returning the same error.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, data, err := c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return typ, data, err
+}
+
+func (c *Conn) readMessage() (MessageType, []byte, error) {
+	var (
+		typ MessageType
+		msg []byte
+	)
+	for {
+		f, err := c.readFrame(c.readLimit - int64(len(msg)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.op {
+		case opPing:
+			if err := c.writeFrame(opPong, f.payload); err != nil && !errors.Is(err, ErrClosed) {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case opClose:
+			return 0, nil, c.closeReceived(f.payload)
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(StatusProtocolError, "continuation frame without a message")
+			}
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(StatusProtocolError, "new message before the last frame")
+			}
+			typ = MessageType(f.op)
+		default:
+			return 0, nil, c.fail(StatusProtocolError, "unknown opcode")
+		}
+
+		msg = append(msg, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if typ == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, c.fail(StatusInvalidPayload, "text message is not valid UTF-8")
+		}
+		if msg == nil {
+			msg = []byte{}
+		}
+		return typ, msg, nil
+	}
+}
+
+// frame is a frame received, its payload unmasked.
+type frame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+// readFrame reads the next frame, whose payload, if it belongs to a data message, must
+// not exceed limit bytes.
+func (c *Conn) readFrame(limit int64) (frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: header[0]&0x80 != 0, op: header[0] & 0x0f}
+	if header[0]&0x70 != 0 {
+		return f, c.fail(StatusProtocolError, "reserved bits set without an extension")
+	}
+	if masked := header[1]&0x80 != 0; masked == c.client {
+		if c.client {
+			return f, c.fail(StatusProtocolError, "frames from the server must not be masked")
+		}
+		return f, c.fail(StatusProtocolError, "frames from the client must be masked")
+	}
+
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if f.op >= opClose {
+		if !f.fin || n > maxControlPayload {
+			return f, c.fail(StatusProtocolError, "control frames must fit in 125 bytes")
+		}
+	} else if n > uint64(max(limit, 0)) {
+		return f, c.fail(StatusMessageTooBig, "message exceeds "+
+			strconv.FormatInt(c.readLimit, 10)+" bytes")
+	}
+
+	var key [4]byte
+	if !c.client {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return f, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, err
+	}
+	if !c.client {
+		maskBytes(key, f.payload)
+	}
+	return f, nil
+}
+
+// closeReceived answers the close frame carrying payload, unless one was already sent,
+// and returns the *CloseError reporting it.
+func (c *Conn) closeReceived(payload []byte) error {
+	ce := &CloseError{Code: StatusNoStatus}
+	switch {
+	case len(payload) == 1:
+		return c.fail(StatusProtocolError, "close frame with a truncated status code")
+	case len(payload) >= 2:
+		ce.Code = StatusCode(binary.BigEndian.Uint16(payload))
+		ce.Reason = string(payload[2:])
+		if !validCloseCode(ce.Code) {
+			return c.fail(StatusProtocolError, "invalid close status code")
+		}
+		if !utf8.ValidString(ce.Reason) {
+			return c.fail(StatusInvalidPayload, "close reason is not valid UTF-8")
+		}
+	}
+
+	var err error
+	if ce.Code == StatusNoStatus {
+		err = c.writeFrame(opClose, nil)
+	} else {
+		err = c.WriteClose(ce.Code, "")
+	}
+	if err != nil && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return ce
+}
+
+// fail closes the connection with code and reason, because the peer broke the protocol,
+// and returns the *CloseError reporting it.
+func (c *Conn) fail(code StatusCode, reason string) error {
+	_ = c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code StatusCode) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	default:
+		// Registered by libraries and frameworks, or private to applications.
+		return code >= 3000 && code <= 4999
+	}
+}
+
+// maskBytes masks, or unmasks, b with key.
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+// truncateUTF8 returns the longest prefix of s of at most n bytes that does not cut a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/apps/server/internal/problem"
+)
+
+func TestUpgrade_Refused(t *testing.T) {
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+	tests := []struct {
+		name           string
+		method         string
+		header         http.Header
+		expectedStatus int
+	}{
+		{name: "plain request", method: http.MethodGet, expectedStatus: http.StatusUpgradeRequired},
+		{
+			name:           "POST",
+			method:         http.MethodPost,
+			header:         valid,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "other version",
+			method:         http.MethodGet,
+			header:         http.Header{"Sec-Websocket-Version": {"8"}},
+			expectedStatus: http.StatusUpgradeRequired,
+		},
+		{
+			name:           "invalid key",
+			method:         http.MethodGet,
+			header:         http.Header{"Sec-Websocket-Key": {"short"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/ws", nil)
+			if tt.header != nil {
+				req.Header = valid.Clone()
+				for k, v := range tt.header {
+					req.Header[k] = v
+				}
+			}
+			rec := httptest.NewRecorder()
+			conn, err := Upgrade(rec, req)
+
+			assert.Error(t, err, "Upgrade should refuse the request")
+			assert.Nil(t, conn, "no connection should be returned")
+			assert.Equal(t, tt.expectedStatus, rec.Code, "status mismatch")
+			assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"),
+				"refusals should be problems")
+			if tt.expectedStatus == http.StatusUpgradeRequired {
+				assert.Equal(t, "13", rec.Header().Get("Sec-WebSocket-Version"),
+					"the supported version should be advertised")
+			}
+		})
+	}
+}
+
+// echoServer starts a server sending every message back, and returns its ws URL and a
+// channel receiving the error that ended each connection.
+func echoServer(t *testing.T) (string, <-chan error) {
+	t.Helper()
+	ended := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		conn, err := Upgrade(w, r)
+		if !assert.NoError(t, err, "Upgrade should accept the handshake") {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				ended <- err
+				return
+			}
+			if err := conn.WriteMessage(typ, data); err != nil {
+				ended <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), ended
+}
+
+func TestConn_Echo(t *testing.T) {
+	url, _ := echoServer(t)
+	conn, resp, err := Dial(context.Background(), url, nil)
+	require.NoError(t, err, "Dial should complete the handshake")
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"),
+		"the headers set before Upgrade should be sent")
+
+	tests := []struct {
+		name string
+		typ  MessageType
+		data string
+	}{
+		{name: "text", typ: TextMessage, data: "héllo"},
+		{name: "empty", typ: TextMessage, data: ""},
+		{name: "binary", typ: BinaryMessage, data: "\x00\xff\x10"},
+		{name: "16-bit length", typ: BinaryMessage, data: strings.Repeat("a", 300)},
+		{name: "64-bit length", typ: TextMessage, data: strings.Repeat("b", 70000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, conn.WriteMessage(tt.typ, []byte(tt.data)), "WriteMessage")
+			typ, data, err := conn.ReadMessage()
+			require.NoError(t, err, "ReadMessage")
+			assert.Equal(t, tt.typ, typ, "message type mismatch")
+			assert.Equal(t, tt.data, string(data), "message mismatch")
+		})
+	}
+}
+
+func TestConn_Ping(t *testing.T) {
+	url, _ := echoServer(t)
+	conn, _, err := Dial(context.Background(), url, nil)
+	require.NoError(t, err, "Dial should complete the handshake")
+	defer func() { _ = conn.Close() }()
+
+	var pongs []string
+	conn.SetPongHandler(func(data []byte) { pongs = append(pongs, string(data)) })
+	require.NoError(t, conn.Ping([]byte("are you there")), "Ping")
+	require.NoError(t, conn.WriteMessage(TextMessage, []byte("after")), "WriteMessage")
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err, "ReadMessage")
+	assert.Equal(t, "after", string(data), "the message should follow the pong")
+	assert.Equal(t, []string{"are you there"}, pongs, "pings should be answered")
+	assert.Error(t, conn.Ping(make([]byte, 126)), "pings over 125 bytes should be refused")
+}
+
+func TestConn_CloseHandshake(t *testing.T) {
+	url, ended := echoServer(t)
+	conn, _, err := Dial(context.Background(), url, nil)
+	require.NoError(t, err, "Dial should complete the handshake")
+	defer func() { _ = conn.Close() }()
+
+	require.NoError(t, conn.WriteClose(StatusNormalClosure, "bye"), "WriteClose")
+	assert.ErrorIs(t, conn.WriteMessage(TextMessage, []byte("late")), ErrClosed,
+		"nothing should be written after the close frame")
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, StatusNormalClosure, CloseStatus(err), "the server should echo the close")
+	assert.Equal(t, &CloseError{Code: StatusNormalClosure, Reason: "bye"}, <-ended,
+		"the server should receive the close frame")
+}
+
+func TestDial_Refused(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "go away", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, resp, err := Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.ErrorIs(t, err, ErrBadHandshake, "Dial should report the refusal")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "status mismatch")
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "go away\n", string(body), "the body of the refusal should be kept")
+
+	_, _, err = Dial(context.Background(), srv.URL, nil)
+	assert.ErrorContains(t, err, "scheme must be ws or wss", "http URLs should be refused")
+}
+
+// clientFrame returns a masked frame, as sent by clients.
+func clientFrame(fin bool, op byte, payload string) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(len(payload)))
+	}
+	key := [4]byte{1, 2, 3, 4}
+	masked := []byte(payload)
+	maskBytes(key, masked)
+	return append(append(frame, key[:]...), masked...)
+}
+
+// rawPeer returns the server end of a TCP connection, as a Conn, and the client end.
+func rawPeer(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+	defer func() { _ = ln.Close() }()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err, "Setup: dialing")
+	server, err := ln.Accept()
+	require.NoError(t, err, "Setup: accepting")
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return newConn(server, bufio.NewReader(server), false), client
+}
+
+func TestConn_ReadMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		frames       [][]byte
+		expected     string
+		expectedCode StatusCode
+	}{
+		{
+			name: "fragmented message with a ping in between",
+			frames: [][]byte{
+				clientFrame(false, opText, "hel"),
+				clientFrame(true, opPing, ""),
+				clientFrame(true, opContinuation, "lo"),
+			},
+			expected: "hello",
+		},
+		{
+			name:         "unmasked frame",
+			frames:       [][]byte{{0x81, 0x01, 'a'}},
+			expectedCode: StatusProtocolError,
+		},
+		{
+			name:         "reserved bits",
+			frames:       [][]byte{append([]byte{0xc1}, clientFrame(true, opText, "a")[1:]...)},
+			expectedCode: StatusProtocolError,
+		},
+		{
+			name:         "continuation without a message",
+			frames:       [][]byte{clientFrame(true, opContinuation, "a")},
+			expectedCode: StatusProtocolError,
+		},
+		{
+			name:         "fragmented control frame",
+			frames:       [][]byte{clientFrame(false, opPing, "a")},
+			expectedCode: StatusProtocolError,
+		},
+		{
+			name:         "invalid UTF-8",
+			frames:       [][]byte{clientFrame(true, opText, "\xff\xfe")},
+			expectedCode: StatusInvalidPayload,
+		},
+		{
+			name: "message over the limit across fragments",
+			frames: [][]byte{
+				clientFrame(false, opBinary, strings.Repeat("a", 100)),
+				clientFrame(true, opContinuation, strings.Repeat("a", 200)),
+			},
+			expectedCode: StatusMessageTooBig,
+		},
+		{
+			name:         "close without status",
+			frames:       [][]byte{clientFrame(true, opClose, "")},
+			expectedCode: StatusNoStatus,
+		},
+		{
+			name:         "close with a reserved status",
+			frames:       [][]byte{clientFrame(true, opClose, "\x03\xed")},
+			expectedCode: StatusProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, peer := rawPeer(t)
+			conn.SetReadLimit(256)
+			for _, f := range tt.frames {
+				_, err := peer.Write(f)
+				require.NoError(t, err, "Setup: writing frame")
+			}
+
+			_, data, err := conn.ReadMessage()
+			if tt.expectedCode == 0 {
+				require.NoError(t, err, "ReadMessage should not fail")
+				assert.Equal(t, tt.expected, string(data), "message mismatch")
+				return
+			}
+			assert.Equal(t, tt.expectedCode, CloseStatus(err), "close status mismatch")
+			_, _, again := conn.ReadMessage()
+			assert.Equal(t, err, again, "the error should be sticky")
+
+			// The peer gets a close frame, echoing its own or telling why it broke the protocol.
+			client := newConn(peer, bufio.NewReader(peer), true)
+			_ = peer.SetDeadline(time.Now().Add(time.Second))
+			_, _, err = client.ReadMessage()
+			assert.Equal(t, tt.expectedCode, CloseStatus(err),
+				"the peer should be told why it is disconnected")
+		})
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "ab", truncateUTF8("abc", 2), "ASCII should be cut at n")
+	assert.Equal(t, "a", truncateUTF8("aé", 2), "runes should not be cut")
+	assert.Equal(t, "aé", truncateUTF8("aé", 3), "short strings should be kept")
+}
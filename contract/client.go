@@ -0,0 +1,150 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Client calls the API server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests, e.g. one whose transport is
+// instrumented. The default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		if c != nil {
+			cl.httpClient = c
+		}
+	}
+}
+
+// WithBearerToken authenticates every request with token, a JWT or an API key, sent in an
+// "Authorization: Bearer" header.
+func WithBearerToken(token string) Option {
+	return func(cl *Client) { cl.token = token }
+}
+
+// NewClient returns a Client for the API server at baseURL, e.g. http://localhost:3000.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check calls the sample endpoint reporting that the API is reachable.
+func (c *Client) Check(ctx context.Context) (MessageResponse, error) {
+	var resp MessageResponse
+	err := c.do(ctx, http.MethodGet, PathCheck, nil, &resp, http.StatusOK)
+	return resp, err
+}
+
+// Liveness returns the liveness report of the API. A failing API answers with
+// 503 Service Unavailable and still carries a report, which is returned without error.
+func (c *Client) Liveness(ctx context.Context) (HealthReport, error) {
+	return c.probe(ctx, PathLiveness)
+}
+
+// Readiness returns the readiness report of the API, see Liveness.
+func (c *Client) Readiness(ctx context.Context) (HealthReport, error) {
+	return c.probe(ctx, PathReadiness)
+}
+
+// JobQueue returns the state of the background job queue.
+func (c *Client) JobQueue(ctx context.Context) (JobQueueState, error) {
+	var state JobQueueState
+	err := c.do(ctx, http.MethodGet, PathJobs, nil, &state, http.StatusOK)
+	return state, err
+}
+
+func (c *Client) probe(ctx context.Context, path string) (HealthReport, error) {
+	var report HealthReport
+	err := c.do(ctx, http.MethodGet, path, nil, &report,
+		http.StatusOK, http.StatusServiceUnavailable)
+	if err != nil {
+		return HealthReport{}, err
+	}
+	return report, nil
+}
+
+// do sends a request with body, if not nil, encoded as JSON and decodes the response into
+// out when its status is one of ok. Other statuses are returned as a *Problem.
+func (c *Client) do(
+	ctx context.Context,
+	method, path string,
+	body, out any,
+	ok ...int,
+) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, "+ProblemContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	if !slices.Contains(ok, resp.StatusCode) {
+		return decodeProblem(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.New("decoding " + method + " " + path + " response: " + err.Error())
+	}
+	return nil
+}
+
+// decodeProblem returns the problem carried by an error response, or one derived from its
+// status code when the body is not a problem document.
+func decodeProblem(resp *http.Response) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == ProblemContentType {
+		var p Problem
+		if err := json.NewDecoder(resp.Body).Decode(&p); err == nil && p.Status != 0 {
+			return &p
+		}
+	}
+	return statusProblem(resp.StatusCode)
+}
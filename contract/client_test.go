@@ -0,0 +1,189 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supergeoff/go-starter/pkg/health"
+	"github.com/supergeoff/go-starter/pkg/sse"
+)
+
+func TestClient_Check(t *testing.T) {
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		expectedMessage string
+		expectedProblem *Problem
+		expectError     bool
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, PathCheck, r.URL.Path, "client requested wrong path")
+				_ = json.NewEncoder(w).Encode(MessageResponse{Message: "check"})
+			},
+			expectedMessage: "check",
+		},
+		{
+			name: "problem response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", ProblemContentType)
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(Problem{
+					Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "busy",
+				})
+			},
+			expectedProblem: &Problem{
+				Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "busy",
+			},
+		},
+		{
+			name: "plain error response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "oops", http.StatusBadGateway)
+			},
+			expectedProblem: &Problem{
+				Type: "about:blank", Title: "Bad Gateway", Status: http.StatusBadGateway,
+			},
+		},
+		{
+			name: "malformed body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("{"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			resp, err := NewClient(srv.URL + "/").Check(context.Background())
+			switch {
+			case tt.expectedProblem != nil:
+				var p *Problem
+				require.True(t, errors.As(err, &p), "error should be a *Problem, got %v", err)
+				assert.Equal(t, tt.expectedProblem, p, "problem mismatch")
+			case tt.expectError:
+				assert.Error(t, err, "Check should fail")
+			default:
+				require.NoError(t, err, "Check should succeed")
+				assert.Equal(t, tt.expectedMessage, resp.Message, "message mismatch")
+			}
+		})
+	}
+}
+
+func TestClient_Readiness(t *testing.T) {
+	failing := HealthReport{
+		Status: health.StatusFail,
+		Checks: []health.Result{{Name: "db", Status: health.StatusFail, Critical: true}},
+	}
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(failing)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, WithHTTPClient(srv.Client()))
+	report, err := client.Readiness(context.Background())
+	require.NoError(t, err, "a failing report is not a client error")
+	assert.Equal(t, PathReadiness, gotPath, "client requested wrong path")
+	assert.Equal(t, failing, report, "report mismatch")
+
+	_, err = client.Liveness(context.Background())
+	require.NoError(t, err, "Liveness should decode the report too")
+	assert.Equal(t, PathLiveness, gotPath, "client requested wrong path")
+}
+
+func TestClient_JobQueue(t *testing.T) {
+	expected := JobQueueState{
+		Counts:  map[string]int{"pending": 2, "dead": 1},
+		Workers: 4,
+		Busy:    1,
+		DeadJobs: []DeadJob{{
+			ID: 3, Kind: "email", Attempts: 5, LastError: "smtp down",
+			FailedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+	}
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewEncoder(w).Encode(expected)
+	}))
+	defer srv.Close()
+
+	state, err := NewClient(srv.URL).JobQueue(context.Background())
+	require.NoError(t, err, "JobQueue should succeed")
+	assert.Equal(t, PathJobs, gotPath, "client requested wrong path")
+	assert.Equal(t, expected, state, "state mismatch")
+}
+
+func TestClient_WithBearerToken(t *testing.T) {
+	var gotAuthorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		_ = json.NewEncoder(w).Encode(MessageResponse{Message: "check"})
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, WithBearerToken("gsk_secret")).Check(context.Background())
+	require.NoError(t, err, "Check should succeed")
+	assert.Equal(t, "Bearer gsk_secret", gotAuthorization, "Authorization header mismatch")
+
+	_, err = NewClient(srv.URL).Check(context.Background())
+	require.NoError(t, err, "Check should succeed")
+	assert.Empty(t, gotAuthorization, "no token should send no Authorization header")
+}
+
+func TestClient_HealthEvents(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, PathHealthEvents, r.URL.Path, "client requested wrong path")
+		assert.Equal(t, "7", r.Header.Get("Last-Event-ID"), "Last-Event-ID mismatch")
+		sw, err := sse.NewWriter(w)
+		if !assert.NoError(t, err, "Setup: starting the stream") {
+			return
+		}
+		_ = sw.Send(sse.Event{Retry: time.Second})
+		_ = sw.Send(sse.Event{ID: "8", Type: "other", Data: "ignored"})
+		_ = sw.Send(sse.Event{ID: "9", Type: EventHealth, Data: `{"status":"fail","checks":[]}`})
+	}))
+	defer api.Close()
+
+	stream, err := NewClient(api.URL).HealthEvents(context.Background(), "7")
+	require.NoError(t, err, "HealthEvents should not fail")
+	defer func() { _ = stream.Close() }()
+
+	event, err := stream.Next()
+	require.NoError(t, err, "Next should not fail")
+	assert.Equal(t, "9", event.ID, "event ID mismatch")
+	assert.Equal(t, health.StatusFail, event.Report.Status, "report status mismatch")
+	assert.Equal(t, time.Second, stream.Retry(), "reconnection delay mismatch")
+	_, err = stream.Next()
+	assert.ErrorIs(t, err, io.EOF, "Next should report the end of the stream")
+}
+
+func TestClient_HealthEventsProblem(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer api.Close()
+
+	_, err := NewClient(api.URL).HealthEvents(context.Background(), "")
+	var problem *Problem
+	require.ErrorAs(t, err, &problem, "HealthEvents should return a *Problem")
+	assert.Equal(t, http.StatusTooManyRequests, problem.Status, "problem status mismatch")
+}
@@ -0,0 +1,94 @@
+// Package contract defines the HTTP API between the web client and the API server: the
+// paths of the endpoints, their request and response types, and a typed client.
+//
+// The server builds its handlers on these types and the client calls them through
+// Client, so that a change to the API breaks the build instead of the pages.
+package contract
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/supergeoff/go-starter/pkg/health"
+)
+
+// Paths of the API endpoints.
+const (
+	PathCheck     = "/api"
+	PathLiveness  = "/livez"
+	PathReadiness = "/readyz"
+	// PathHealthEvents streams the readiness reports as Server-Sent Events of type
+	// EventHealth: the current report, then one each time the status changes.
+	PathHealthEvents = "/readyz/events"
+	// PathWebSocket upgrades authenticated requests to WebSocket connections, over which
+	// clients join rooms and exchange JSON messages with their members.
+	PathWebSocket = "/ws"
+	// PathJobs reports the state of the background job queue.
+	PathJobs = "/jobs"
+)
+
+// EventHealth is the type of the events carrying a HealthReport.
+const EventHealth = "health"
+
+// MessageResponse is a response carrying a single message.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// HealthReport is the body of the liveness and readiness probes.
+type HealthReport = health.Report
+
+// JobQueueState is the state of the background job queue.
+type JobQueueState struct {
+	// Counts is the number of jobs in each state: pending, running, succeeded or dead.
+	Counts map[string]int `json:"counts"`
+	// Workers is the size of the worker pool, and Busy the number of workers running a job.
+	Workers int `json:"workers"`
+	Busy    int `json:"busy"`
+	// DeadJobs lists the last jobs that failed for good, most recent first.
+	DeadJobs []DeadJob `json:"dead_jobs"`
+}
+
+// DeadJob is a job that failed for good.
+type DeadJob struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, the body of every error response of the
+// API. It implements error, and is the error returned by Client for such responses.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists invalid request fields; it is an extension member.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Error returns the title and detail of the problem.
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// FieldError describes why one field of a request is invalid.
+// Field is prefixed with where it comes from, e.g. "body.name" or "query.limit".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// statusProblem returns the problem standing for an error response without a problem body.
+func statusProblem(status int) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status}
+}
@@ -0,0 +1,85 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/supergeoff/go-starter/pkg/sse"
+)
+
+// HealthEvent is a readiness report streamed on PathHealthEvents.
+type HealthEvent struct {
+	// ID identifies the event, to resume the stream after it.
+	ID     string
+	Report HealthReport
+}
+
+// HealthStream is a stream of readiness reports, opened with Client.HealthEvents.
+type HealthStream struct {
+	body   io.ReadCloser
+	reader *sse.Reader
+}
+
+// HealthEvents opens the stream of the readiness reports of the API. lastEventID, when not
+// empty, resumes a previous stream after that event.
+func (c *Client) HealthEvents(ctx context.Context, lastEventID string) (*HealthStream, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+PathHealthEvents, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", sse.ContentType+", "+ProblemContentType)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != sse.ContentType {
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode == http.StatusOK {
+			return nil, errors.New("GET " + PathHealthEvents + " did not answer an event stream")
+		}
+		return nil, decodeProblem(resp)
+	}
+	return &HealthStream{body: resp.Body, reader: sse.NewReader(resp.Body)}, nil
+}
+
+// Next returns the next report of the stream, blocking until the API sends it. It returns
+// io.EOF when the API ends the stream, e.g. when it shuts down.
+func (s *HealthStream) Next() (HealthEvent, error) {
+	for {
+		e, err := s.reader.Next()
+		if err != nil {
+			return HealthEvent{}, err
+		}
+		if e.Type != EventHealth {
+			continue
+		}
+		var report HealthReport
+		if err := json.Unmarshal([]byte(e.Data), &report); err != nil {
+			return HealthEvent{}, errors.New("decoding " + EventHealth + " event: " + err.Error())
+		}
+		return HealthEvent{ID: e.ID, Report: report}, nil
+	}
+}
+
+// Retry returns how long the API asked clients to wait before reconnecting, or zero.
+func (s *HealthStream) Retry() time.Duration {
+	return s.reader.Retry()
+}
+
+// Close closes the stream.
+func (s *HealthStream) Close() error {
+	return s.body.Close()
+}
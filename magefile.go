@@ -23,6 +23,26 @@ func Serve(dirpath string) error {
 	return sh.RunV("mage", "-d", "./tools", "serve", dirpath)
 }
 
+// OpenAPI delegates writing the server's OpenAPI document to the tools directory.
+func OpenAPI() error {
+	log.Println("Delegating OpenAPI generation to tools...")
+	return sh.RunV("mage", "-d", "./tools", "openapi")
+}
+
+// Migrate delegates running the server's database migrations to the tools directory.
+// command is up, down or status; down reverts a single migration.
+func Migrate(command string) error {
+	log.Printf("Delegating migrate %s to tools...\n", command)
+	return sh.RunV("mage", "-d", "./tools", "migrate", command)
+}
+
+// DevCert delegates creating a local CA and a certificate for localhost to the tools
+// directory. Both are written to certs/.
+func DevCert() error {
+	log.Println("Delegating development certificate creation to tools...")
+	return sh.RunV("mage", "-d", "./tools", "devcert")
+}
+
 // Install syncs Go workspace and then delegates installation to the tools magefile.
 func Install() error {
 	log.Println("Syncing Go workspace...")
@@ -0,0 +1,409 @@
+// Package config loads application configuration from layered sources.
+//
+// Values are resolved in a fixed precedence, each layer overriding the previous one:
+//
+//  1. the defaults already present in the destination struct,
+//  2. a JSON config file,
+//  3. environment variables,
+//  4. command-line flags.
+//
+// The destination must be a pointer to a struct. Every exported field is a setting
+// whose name comes from its `config` struct tag (or its lowercased field name).
+// Nested structs group settings: a field `Addr` tagged `config:"addr"` inside a
+// field tagged `config:"server"` is read from the file key {"server": {"addr": ...}},
+// the environment variable PREFIX_SERVER_ADDR and the flag -server.addr.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configFlag is the name of the flag (and environment variable suffix) that points to the config file.
+const configFlag = "config"
+
+// Validator is implemented by configuration structs that can check their own consistency.
+// Load calls Validate once every layer has been applied.
+type Validator interface {
+	Validate() error
+}
+
+// Options controls how Load resolves the configuration.
+type Options struct {
+	// Name is the program name shown in the flag usage output.
+	Name string
+	// EnvPrefix is prepended to every environment variable name, e.g. "API" gives API_ADDR.
+	EnvPrefix string
+	// Args are the command-line arguments to parse, usually os.Args[1:].
+	Args []string
+	// ConfigFile is the default config file path. It is optional: a missing default
+	// file is ignored, whereas a file requested with -config or PREFIX_CONFIG must exist.
+	ConfigFile string
+	// LookupEnv resolves environment variables. It defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// Output receives the flag usage and parse errors. It defaults to os.Stderr.
+	Output io.Writer
+}
+
+// setting is a single leaf field of the configuration struct.
+type setting struct {
+	key   string // Dotted file key, e.g. "server.addr".
+	env   string // Environment variable name, e.g. "API_SERVER_ADDR".
+	flag  string // Flag name, e.g. "server.addr".
+	usage string
+	value reflect.Value
+}
+
+// Load fills dst from defaults, the config file, the environment and the flags, in that order,
+// then validates the result if dst implements Validator.
+// It returns flag.ErrHelp (possibly wrapped) when -h or -help was requested.
+func Load(dst any, opts Options) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		slog.Error("config destination must be a non-nil pointer to a struct")
+		return errors.New("config destination must be a non-nil pointer to a struct")
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+
+	settings, err := collect(rv.Elem(), nil, opts.EnvPrefix)
+	if err != nil {
+		return err
+	}
+
+	// Flags are parsed first so that -config is known before reading the file,
+	// but their values are only applied last, on top of every other layer.
+	flagValues, configPath, err := parseFlags(settings, opts)
+	if err != nil {
+		return err
+	}
+
+	path, required := opts.ConfigFile, false
+	if v, ok := opts.LookupEnv(envName(opts.EnvPrefix, []string{configFlag})); ok && v != "" {
+		path, required = v, true
+	}
+	if configPath != "" {
+		path, required = configPath, true
+	}
+	if path != "" {
+		if err := applyFile(settings, path, required); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range settings {
+		raw, ok := opts.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value, raw); err != nil {
+			slog.Error("invalid config value from environment", "variable", s.env, "error", err)
+			return fmt.Errorf("environment variable %s: %w", s.env, err)
+		}
+	}
+
+	for _, s := range settings {
+		raw, ok := flagValues[s.flag]
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value, raw); err != nil {
+			slog.Error("invalid config value from flag", "flag", s.flag, "error", err)
+			return fmt.Errorf("flag -%s: %w", s.flag, err)
+		}
+	}
+
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			slog.Error("invalid configuration", "error", err)
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+// collect walks the struct value and returns one setting per supported leaf field.
+func collect(v reflect.Value, path []string, envPrefix string) ([]setting, error) {
+	var settings []setting
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fieldPath := append(append([]string(nil), path...), name)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			nested, err := collect(fv, fieldPath, envPrefix)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, nested...)
+			continue
+		}
+		if !supported(fv) {
+			slog.Error("unsupported config field type", "field", field.Name, "type", fv.Type())
+			return nil, fmt.Errorf("config field %s has unsupported type %s", field.Name, fv.Type())
+		}
+		settings = append(settings, setting{
+			key:   strings.Join(fieldPath, "."),
+			env:   envName(envPrefix, fieldPath),
+			flag:  strings.ReplaceAll(strings.Join(fieldPath, "."), "_", "-"),
+			usage: field.Tag.Get("usage"),
+			value: fv,
+		})
+	}
+	return settings, nil
+}
+
+// envName builds the environment variable name for a setting path.
+func envName(prefix string, path []string) string {
+	parts := path
+	if prefix != "" {
+		parts = append([]string{prefix}, path...)
+	}
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// parseFlags registers one flag per setting plus -config and parses opts.Args.
+// It returns the raw value of every flag that was explicitly set, and the config file path.
+func parseFlags(settings []setting, opts Options) (map[string]string, string, error) {
+	fs := flag.NewFlagSet(opts.Name, flag.ContinueOnError)
+	fs.SetOutput(opts.Output)
+
+	var configPath string
+	fs.StringVar(&configPath, configFlag, "", "path to a JSON config file")
+	for _, s := range settings {
+		fs.Var(&rawFlag{def: formatValue(s.value), isBool: s.value.Kind() == reflect.Bool},
+			s.flag, s.usage)
+	}
+
+	if err := fs.Parse(opts.Args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, "", err
+		}
+		slog.Error("failed to parse command-line flags", "error", err)
+		return nil, "", fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		slog.Error("unexpected command-line arguments", "args", fs.Args())
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	values := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if rf, ok := f.Value.(*rawFlag); ok {
+			values[f.Name] = rf.raw
+		}
+	})
+	return values, configPath, nil
+}
+
+// applyFile reads the JSON config file at path and applies it to the settings.
+// Unknown keys are rejected so that typos do not go unnoticed.
+func applyFile(settings []setting, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return nil
+		}
+		slog.Error("failed to read config file", "path", path, "error", err)
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		slog.Error("failed to parse config file", "path", path, "error", err)
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]any)
+	flatten(doc, "", values)
+	for _, s := range settings {
+		raw, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		delete(values, s.key)
+		if err := setJSONValue(s.value, raw); err != nil {
+			slog.Error("invalid config value in file", "path", path, "key", s.key, "error", err)
+			return fmt.Errorf("config file %s: key %s: %w", path, s.key, err)
+		}
+	}
+
+	if len(values) > 0 {
+		unknown := make([]string, 0, len(values))
+		for k := range values {
+			unknown = append(unknown, k)
+		}
+		sort.Strings(unknown)
+		slog.Error("unknown keys in config file", "path", path, "keys", unknown)
+		return fmt.Errorf("config file %s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// flatten turns nested JSON objects into dotted keys.
+func flatten(doc map[string]any, prefix string, out map[string]any) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(nested, key, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// setJSONValue assigns a decoded JSON value to a setting.
+func setJSONValue(v reflect.Value, raw any) error {
+	switch val := raw.(type) {
+	case string:
+		return setValue(v, val)
+	case json.Number:
+		return setValue(v, val.String())
+	case bool:
+		return setValue(v, strconv.FormatBool(val))
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return errors.New("unexpected array")
+		}
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("array items must be strings")
+			}
+			items = append(items, s)
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	case nil:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	default:
+		return fmt.Errorf("unsupported JSON value %v", raw)
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// supported reports whether a leaf field can be set from a string.
+func supported(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+// setValue parses raw according to the kind of v and assigns it.
+// Slices are read as comma-separated lists.
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// formatValue renders the current value of a setting for the flag usage output.
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// rawFlag is a flag.Value that only records the raw string it was given.
+// Parsing is deferred to setValue so that flags share the same rules as the other layers.
+type rawFlag struct {
+	def    string
+	raw    string
+	isBool bool
+}
+
+func (f *rawFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.def
+}
+
+func (f *rawFlag) Set(s string) error {
+	f.raw = s
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be passed as -flag without a value.
+func (f *rawFlag) IsBoolFlag() bool { return f.isBool }
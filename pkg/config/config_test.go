@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	Addr    string        `config:"addr"    usage:"listen address"`
+	Timeout time.Duration `config:"timeout" usage:"request timeout"`
+}
+
+type testConfig struct {
+	Server  testServer `config:"server"`
+	Debug   bool       `config:"debug"`
+	Workers int        `config:"workers"`
+	Origins []string   `config:"origins"`
+	Ignored string     `config:"-"`
+}
+
+func (c testConfig) Validate() error {
+	if c.Workers < 0 {
+		return errors.New("workers must not be negative")
+	}
+	return nil
+}
+
+func defaults() testConfig {
+	return testConfig{
+		Server:  testServer{Addr: ":3000", Timeout: time.Second},
+		Workers: 1,
+	}
+}
+
+// env returns a LookupEnv function backed by a map.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600), "Setup: writing config file")
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, `{
+		"server": {"addr": ":4000", "timeout": "5s"},
+		"workers": 2,
+		"origins": ["https://a.test"]
+	}`)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want testConfig
+	}{
+		{
+			name: "defaults only",
+			want: defaults(),
+		},
+		{
+			name: "file overrides defaults",
+			args: []string{"-config", file},
+			want: testConfig{
+				Server:  testServer{Addr: ":4000", Timeout: 5 * time.Second},
+				Workers: 2,
+				Origins: []string{"https://a.test"},
+			},
+		},
+		{
+			name: "env overrides file",
+			env: map[string]string{
+				"APP_CONFIG":      file,
+				"APP_SERVER_ADDR": ":5000",
+				"APP_ORIGINS":     "https://b.test, https://c.test",
+			},
+			want: testConfig{
+				Server:  testServer{Addr: ":5000", Timeout: 5 * time.Second},
+				Workers: 2,
+				Origins: []string{"https://b.test", "https://c.test"},
+			},
+		},
+		{
+			name: "flags override env",
+			env:  map[string]string{"APP_SERVER_ADDR": ":5000", "APP_DEBUG": "false"},
+			args: []string{"-server.addr", ":6000", "-debug", "-workers=8"},
+			want: testConfig{
+				Server:  testServer{Addr: ":6000", Timeout: time.Second},
+				Debug:   true,
+				Workers: 8,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults()
+			err := Load(&cfg, Options{
+				Name:      "test",
+				EnvPrefix: "APP",
+				Args:      tt.args,
+				LookupEnv: env(tt.env),
+				Output:    io.Discard,
+			})
+			require.NoError(t, err, "Load should not fail")
+			assert.Equal(t, tt.want, cfg, "Resolved configuration mismatch")
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		configFile    string
+		env           map[string]string
+		args          []string
+		containsError string
+	}{
+		{
+			name:          "unknown file key",
+			configFile:    `{"server": {"port": 1}}`,
+			containsError: "unknown keys: server.port",
+		},
+		{
+			name:          "malformed file",
+			configFile:    `{"server":`,
+			containsError: "failed to parse config file",
+		},
+		{
+			name:          "invalid env value",
+			env:           map[string]string{"APP_WORKERS": "many"},
+			containsError: "environment variable APP_WORKERS",
+		},
+		{
+			name:          "invalid flag value",
+			args:          []string{"-server.timeout", "soon"},
+			containsError: "flag -server.timeout",
+		},
+		{
+			name:          "unknown flag",
+			args:          []string{"-nope"},
+			containsError: "failed to parse flags",
+		},
+		{
+			name:          "validation failure",
+			args:          []string{"-workers", "-1"},
+			containsError: "invalid configuration: workers must not be negative",
+		},
+		{
+			name:          "missing explicit config file",
+			args:          []string{"-config", "/does/not/exist.json"},
+			containsError: "failed to read config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.configFile != "" {
+				args = append([]string{"-config", writeFile(t, tt.configFile)}, args...)
+			}
+			cfg := defaults()
+			err := Load(&cfg, Options{
+				EnvPrefix: "APP",
+				Args:      args,
+				LookupEnv: env(tt.env),
+				Output:    io.Discard,
+			})
+			require.Error(t, err, "Load should fail")
+			assert.Contains(t, err.Error(), tt.containsError, "Error message mismatch")
+		})
+	}
+}
+
+func TestLoad_MissingDefaultFileIsIgnored(t *testing.T) {
+	cfg := defaults()
+	err := Load(&cfg, Options{
+		ConfigFile: filepath.Join(t.TempDir(), "absent.json"),
+		LookupEnv:  env(nil),
+		Output:     io.Discard,
+	})
+	require.NoError(t, err, "A missing default config file should not be an error")
+	assert.Equal(t, defaults(), cfg, "Configuration should keep its defaults")
+}
+
+func TestLoad_Help(t *testing.T) {
+	cfg := defaults()
+	err := Load(&cfg, Options{Args: []string{"-h"}, LookupEnv: env(nil), Output: io.Discard})
+	assert.ErrorIs(t, err, flag.ErrHelp, "Load should report that help was requested")
+}
+
+func TestLoad_InvalidDestination(t *testing.T) {
+	var notAStruct string
+	assert.Error(t, Load(&notAStruct, Options{}), "Load should reject non-struct destinations")
+	assert.Error(t, Load(defaults(), Options{}), "Load should reject non-pointer destinations")
+}
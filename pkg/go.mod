@@ -0,0 +1,14 @@
+module github.com/supergeoff/go-starter/pkg
+
+go 1.24.2
+
+require (
+	github.com/go-chi/chi/v5 v5.2.1
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,241 @@
+// Package health provides a registry of named health checks and the HTTP handlers
+// serving their aggregated results as liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a check that was registered without a timeout.
+const DefaultTimeout = 2 * time.Second
+
+// Probe identifies the set of checks a request asks for.
+type Probe string
+
+const (
+	// Liveness checks tell whether the process should be restarted.
+	Liveness Probe = "liveness"
+	// Readiness checks tell whether the process can serve traffic.
+	Readiness Probe = "readiness"
+)
+
+// Status is the outcome of a check or of a whole probe.
+type Status string
+
+const (
+	// StatusPass means the check succeeded.
+	StatusPass Status = "pass"
+	// StatusWarn means a non-critical check failed; the probe still succeeds.
+	StatusWarn Status = "warn"
+	// StatusFail means a critical check failed; the probe fails.
+	StatusFail Status = "fail"
+)
+
+// CheckFunc reports the health of a component. It must honor ctx cancellation.
+type CheckFunc func(ctx context.Context) error
+
+// Check is a named health check.
+type Check struct {
+	// Name identifies the check in reports. It must be unique per probe.
+	Name string
+	// Timeout bounds the check. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// Critical checks fail the whole probe; others only downgrade it to a warning.
+	Critical bool
+	// Func performs the check.
+	Func CheckFunc
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the aggregated outcome of a probe.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Registry holds the checks of each probe.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[Probe][]Check
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[Probe][]Check)}
+}
+
+// Register adds a check to the given probe.
+// It returns an error if the check has no name or function, or if the name is already taken.
+func (r *Registry) Register(probe Probe, check Check) error {
+	if check.Name == "" || check.Func == nil {
+		slog.Error("health check must have a name and a function", "probe", probe)
+		return errors.New("health check must have a name and a function")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.checks[probe] {
+		if c.Name == check.Name {
+			slog.Error("health check already registered", "probe", probe, "check", check.Name)
+			return fmt.Errorf("health check %q already registered for %s", check.Name, probe)
+		}
+	}
+	r.checks[probe] = append(r.checks[probe], check)
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for start-up wiring.
+func (r *Registry) MustRegister(probe Probe, check Check) {
+	if err := r.Register(probe, check); err != nil {
+		panic("Error: " + err.Error())
+	}
+}
+
+// Run executes every check of the probe concurrently and aggregates their results.
+// Results are sorted by name so that reports are stable.
+func (r *Registry) Run(ctx context.Context, probe Probe) Report {
+	r.mu.RLock()
+	checks := append([]Check(nil), r.checks[probe]...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report := Report{Status: StatusPass, Checks: results}
+	for _, res := range results {
+		switch {
+		case res.Status == StatusFail && res.Critical:
+			report.Status = StatusFail
+		case res.Status == StatusFail && report.Status == StatusPass:
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+// Watch runs the checks of probe every interval until ctx is done. It calls fn with the
+// first report, then with every report whose status, or the status of one of its checks,
+// changed since the last report passed to fn.
+func (r *Registry) Watch(
+	ctx context.Context,
+	probe Probe,
+	interval time.Duration,
+	fn func(Report),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last Report
+	for first := true; ; first = false {
+		report := r.Run(ctx, probe)
+		if ctx.Err() != nil {
+			return
+		}
+		if first || statusChanged(last, report) {
+			fn(report)
+			last = report
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusChanged reports whether the status of b, or of one of its checks, differs from a.
+// Latencies and error messages are ignored.
+func statusChanged(a, b Report) bool {
+	if a.Status != b.Status || len(a.Checks) != len(b.Checks) {
+		return true
+	}
+	for i := range a.Checks {
+		if a.Checks[i].Name != b.Checks[i].Name || a.Checks[i].Status != b.Checks[i].Status {
+			return true
+		}
+	}
+	return false
+}
+
+// runCheck runs a single check within its timeout. A check that ignores its context
+// is abandoned once the timeout expires.
+func runCheck(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Func(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s: %w", c.Timeout, ctx.Err())
+	}
+
+	res := Result{
+		Name:      c.Name,
+		Status:    StatusPass,
+		Critical:  c.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Handler returns an http.HandlerFunc serving the JSON report of the probe.
+// It responds with 503 Service Unavailable when a critical check fails.
+func (r *Registry) Handler(probe Probe) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), probe)
+		if report.Status == StatusFail {
+			slog.Warn("health probe failing", "probe", probe, "checks", report.Checks)
+		}
+
+		body, err := json.Marshal(report)
+		if err != nil {
+			slog.Error("failed to encode health report", "probe", probe, "error", err)
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == StatusFail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(append(body, '\n'))
+	}
+}
@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pass(context.Context) error { return nil }
+
+func failWith(msg string) CheckFunc {
+	return func(context.Context) error { return errors.New(msg) }
+}
+
+// blockUntilDone returns only once its context is cancelled.
+func blockUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register(Readiness, Check{Name: "db", Func: pass}), "first register")
+
+	assert.Error(t, r.Register(Readiness, Check{Name: "db", Func: pass}),
+		"duplicate names should be rejected within a probe")
+	assert.NoError(t, r.Register(Liveness, Check{Name: "db", Func: pass}),
+		"the same name may be reused by another probe")
+	assert.Error(t, r.Register(Readiness, Check{Name: "nofunc"}), "checks need a function")
+	assert.Error(t, r.Register(Readiness, Check{Func: pass}), "checks need a name")
+	assert.Panics(t, func() { r.MustRegister(Readiness, Check{Name: "db", Func: pass}) },
+		"MustRegister should panic on error")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         []Check
+		expectedStatus int
+		expectedReport Status
+		expectedChecks map[string]Status
+		containsError  map[string]string
+	}{
+		{
+			name:           "no checks",
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusPass,
+			expectedChecks: map[string]Status{},
+		},
+		{
+			name: "all passing",
+			checks: []Check{
+				{Name: "a", Critical: true, Func: pass},
+				{Name: "b", Func: pass},
+			},
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusPass,
+			expectedChecks: map[string]Status{"a": StatusPass, "b": StatusPass},
+		},
+		{
+			name: "non-critical failure warns",
+			checks: []Check{
+				{Name: "a", Critical: true, Func: pass},
+				{Name: "b", Func: failWith("cache unreachable")},
+			},
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusWarn,
+			expectedChecks: map[string]Status{"a": StatusPass, "b": StatusFail},
+			containsError:  map[string]string{"b": "cache unreachable"},
+		},
+		{
+			name: "critical failure fails",
+			checks: []Check{
+				{Name: "a", Critical: true, Func: failWith("db down")},
+				{Name: "b", Func: pass},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: StatusFail,
+			expectedChecks: map[string]Status{"a": StatusFail, "b": StatusPass},
+			containsError:  map[string]string{"a": "db down"},
+		},
+		{
+			name: "critical timeout fails",
+			checks: []Check{
+				{
+					Name:     "slow",
+					Critical: true,
+					Timeout:  20 * time.Millisecond,
+					Func:     blockUntilDone,
+				},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: StatusFail,
+			expectedChecks: map[string]Status{"slow": StatusFail},
+			containsError:  map[string]string{"slow": "check timed out after 20ms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for _, c := range tt.checks {
+				r.MustRegister(Readiness, c)
+			}
+
+			rr := httptest.NewRecorder()
+			r.Handler(Readiness).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, "handler returned wrong status code")
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"),
+				"handler returned wrong content type")
+
+			var report Report
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report), "decoding report")
+			assert.Equal(t, tt.expectedReport, report.Status, "report status mismatch")
+
+			got := make(map[string]Status)
+			for _, res := range report.Checks {
+				got[res.Name] = res.Status
+				assert.GreaterOrEqual(t, res.LatencyMS, 0.0, "latency should not be negative")
+				if want, ok := tt.containsError[res.Name]; ok {
+					assert.Contains(t, res.Error, want, "check %s error mismatch", res.Name)
+				} else {
+					assert.Empty(t, res.Error, "check %s should not report an error", res.Name)
+				}
+			}
+			assert.Equal(t, tt.expectedChecks, got, "per-check statuses mismatch")
+		})
+	}
+}
+
+func TestRegistry_RunIsolatesProbes(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(Liveness, Check{Name: "ping", Critical: true, Func: pass})
+	r.MustRegister(Readiness, Check{Name: "db", Critical: true, Func: failWith("down")})
+
+	assert.Equal(t, StatusPass, r.Run(context.Background(), Liveness).Status,
+		"liveness should not run readiness checks")
+	assert.Equal(t, StatusFail, r.Run(context.Background(), Readiness).Status,
+		"readiness should fail on its critical check")
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	// The check fails on its third and fourth runs, with a new error message each time.
+	var runs atomic.Int32
+	r := NewRegistry()
+	r.MustRegister(
+		Readiness,
+		Check{Name: "flaky", Critical: true, Func: func(context.Context) error {
+			if n := runs.Add(1); n == 3 || n == 4 {
+				return fmt.Errorf("failure %d", n)
+			}
+			return nil
+		}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var statuses []Status
+	r.Watch(ctx, Readiness, time.Millisecond, func(report Report) {
+		statuses = append(statuses, report.Status)
+		if len(statuses) == 3 {
+			cancel()
+		}
+	})
+
+	assert.Equal(t, []Status{StatusPass, StatusFail, StatusPass}, statuses,
+		"Watch should report the first status and its changes only")
+	assert.Equal(t, int32(5), runs.Load(), "Watch should run the checks every interval")
+}
@@ -0,0 +1,243 @@
+// Package lifecycle runs an HTTP server until the process is asked to stop,
+// then drains in-flight requests and runs the registered shutdown hooks.
+package lifecycle
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Exit codes returned by ExitCode, meant to be passed to os.Exit.
+const (
+	// ExitOK means the server stopped cleanly after draining every request.
+	ExitOK = 0
+	// ExitServeError means the server could not start or stopped unexpectedly.
+	ExitServeError = 1
+	// ExitUsage means the program was started with an invalid configuration.
+	ExitUsage = 2
+	// ExitShutdownError means the drain deadline was exceeded or a shutdown hook failed.
+	ExitShutdownError = 3
+)
+
+// DefaultShutdownTimeout is the drain deadline used when none is configured.
+const DefaultShutdownTimeout = 15 * time.Second
+
+var (
+	// ErrServe is returned when the server fails to listen or serve.
+	ErrServe = errors.New("server failed")
+	// ErrDrainTimeout is returned when in-flight requests did not complete before the deadline.
+	ErrDrainTimeout = errors.New("shutdown deadline exceeded before in-flight requests completed")
+	// ErrHook is returned when at least one shutdown hook failed.
+	ErrHook = errors.New("shutdown hook failed")
+)
+
+// Hook is a function run once the server has stopped accepting requests.
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	fn   Hook
+}
+
+// Server wraps an http.Server with signal handling and graceful shutdown.
+type Server struct {
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	tls             *TLSConfig
+	stopping        chan struct{}
+
+	mu    sync.Mutex
+	hooks []namedHook
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithShutdownTimeout sets how long in-flight requests are given to complete, and how long
+// each shutdown hook is given to run, once a stop signal is received.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// WithSignals overrides the signals that trigger a graceful shutdown (SIGINT and SIGTERM by default).
+func WithSignals(signals ...os.Signal) Option {
+	return func(s *Server) {
+		s.signals = signals
+	}
+}
+
+// New returns a Server listening on addr and serving handler.
+func New(addr string, handler http.Handler, opts ...Option) *Server {
+	s := &Server{
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		shutdownTimeout: DefaultShutdownTimeout,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		stopping:        make(chan struct{}),
+	}
+	s.httpServer.BaseContext = func(net.Listener) context.Context {
+		return context.WithValue(context.Background(), stoppingKey{}, s.stopping)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type stoppingKey struct{}
+
+// Stopping returns a channel closed once the server handling the request whose context is
+// ctx starts shutting down, or nil outside of a Server. Requests that never end on their
+// own, such as event streams, select on it so that they do not hold up the drain.
+func Stopping(ctx context.Context) <-chan struct{} {
+	stopping, _ := ctx.Value(stoppingKey{}).(chan struct{})
+	return stopping
+}
+
+// HTTPServer returns the underlying http.Server so callers can tune it before Run.
+func (s *Server) HTTPServer() *http.Server {
+	return s.httpServer
+}
+
+// OnShutdown registers a hook run after the server stopped accepting requests.
+// Hooks run in reverse registration order, like deferred calls.
+func (s *Server) OnShutdown(name string, hook Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, fn: hook})
+}
+
+// Run listens on the configured address and serves until ctx is cancelled or a stop signal
+// is received, then shuts down gracefully. If it cannot listen, it still runs the shutdown
+// hooks, so that what was started along with the server is stopped.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		slog.Error("failed to listen", "addr", s.httpServer.Addr, "error", err)
+		hookErr := s.runHooks()
+		return errors.Join(fmt.Errorf("%w: %w", ErrServe, err), hookErr)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is cancelled or a stop signal is received, then stops
+// accepting connections, drains in-flight requests within the shutdown timeout and runs
+// the shutdown hooks. It returns nil on a clean shutdown.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, s.signals...)
+	defer stop()
+
+	serve := s.httpServer.Serve
+	if s.tls != nil {
+		certs, err := newCertReloader(s.tls.CertFile, s.tls.KeyFile)
+		if err != nil {
+			slog.Error("failed to load TLS certificate", "cert_file", s.tls.CertFile, "error", err)
+			_ = ln.Close()
+			hookErr := s.runHooks()
+			return errors.Join(fmt.Errorf("%w: %w", ErrServe, err), hookErr)
+		}
+		go certs.watch(ctx, s.tls.ReloadInterval)
+		s.httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.getCertificate,
+		}
+		serve = func(ln net.Listener) error { return s.httpServer.ServeTLS(ln, "", "") }
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", ln.Addr().String(), "tls", s.tls != nil)
+		serveErr <- serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// Serve only returns before Shutdown on a real failure.
+		slog.Error("server stopped unexpectedly", "error", err)
+		hookErr := s.runHooks()
+		return errors.Join(fmt.Errorf("%w: %w", ErrServe, err), hookErr)
+	case <-ctx.Done():
+	}
+
+	// Restore the default signal behaviour so that a second signal terminates immediately.
+	stop()
+	close(s.stopping)
+	slog.Info("shutting down server", "timeout", s.shutdownTimeout.String())
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var drainErr error
+	if err := s.httpServer.Shutdown(drainCtx); err != nil {
+		slog.Error("failed to drain in-flight requests, closing remaining connections",
+			"error", err)
+		_ = s.httpServer.Close()
+		drainErr = ErrDrainTimeout
+	}
+	<-serveErr // Serve has returned http.ErrServerClosed.
+
+	hookErr := s.runHooks()
+	if err := errors.Join(drainErr, hookErr); err != nil {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+// runHooks runs every shutdown hook in reverse registration order, each within the shutdown
+// timeout, and returns an error wrapping ErrHook if any of them failed.
+func (s *Server) runHooks() error {
+	s.mu.Lock()
+	hooks := append([]namedHook(nil), s.hooks...)
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := s.runHook(h); err != nil {
+			slog.Error("shutdown hook failed", "hook", h.name, "error", err)
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrHook, h.name, err))
+			continue
+		}
+		slog.Info("shutdown hook completed", "hook", h.name)
+	}
+	return errors.Join(errs...)
+}
+
+// runHook runs h with a context of its own, so that a slow hook does not eat into the
+// timeout of the hooks run after it.
+func (s *Server) runHook(h namedHook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return h.fn(ctx)
+}
+
+// ExitCode maps the error returned by Run or Serve to a process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrServe):
+		return ExitServeError
+	default:
+		return ExitShutdownError
+	}
+}
@@ -0,0 +1,308 @@
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperEnv marks the test binary as a child process started by startHelper.
+const helperEnv = "LIFECYCLE_TEST_HELPER"
+
+// TestHelperProcess is not a real test: it is the body of the child process started by
+// startHelper. It serves a slow endpoint and reports progress on stdout.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		t.Skip("only runs as a child process")
+	}
+
+	requestDuration, _ := time.ParseDuration(os.Getenv("HELPER_REQUEST_DURATION"))
+	shutdownTimeout, _ := time.ParseDuration(os.Getenv("HELPER_SHUTDOWN_TIMEOUT"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println("request started")
+		time.Sleep(requestDuration)
+		_, _ = io.WriteString(w, "done")
+	})
+
+	srv := New("", mux, WithShutdownTimeout(shutdownTimeout))
+	srv.OnShutdown("report", func(context.Context) error {
+		fmt.Println("hook ran")
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.Exit(ExitServeError)
+	}
+	fmt.Println("listening " + ln.Addr().String())
+	os.Exit(ExitCode(srv.Serve(context.Background(), ln)))
+}
+
+type helper struct {
+	cmd   *exec.Cmd
+	addr  string
+	lines chan string
+}
+
+// startHelper re-executes the test binary as a child process running TestHelperProcess.
+func startHelper(t *testing.T, requestDuration, shutdownTimeout time.Duration) *helper {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(),
+		helperEnv+"=1",
+		"HELPER_REQUEST_DURATION="+requestDuration.String(),
+		"HELPER_SHUTDOWN_TIMEOUT="+shutdownTimeout.String(),
+	)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err, "Setup: creating stdout pipe")
+	require.NoError(t, cmd.Start(), "Setup: starting child process")
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	h := &helper{cmd: cmd, lines: make(chan string, 16)}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			h.lines <- scanner.Text()
+		}
+		close(h.lines)
+	}()
+
+	line := h.waitFor(t, "listening ")
+	h.addr = strings.TrimPrefix(line, "listening ")
+	return h
+}
+
+// waitFor blocks until the child prints a line starting with prefix and returns it.
+func (h *helper) waitFor(t *testing.T, prefix string) string {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-h.lines:
+			require.True(t, ok, "child process exited before printing %q", prefix)
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for child output", "want prefix %q", prefix)
+		}
+	}
+}
+
+// exitCode waits for the child to exit and returns its exit code.
+func (h *helper) exitCode(t *testing.T) int {
+	t.Helper()
+	err := h.cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	require.NoError(t, err, "waiting for child process")
+	return 0
+}
+
+func TestServe_SignalsDrainInFlightRequests(t *testing.T) {
+	if os.Getenv(helperEnv) == "1" {
+		t.Skip("running as a child process")
+	}
+
+	tests := []struct {
+		name            string
+		signal          syscall.Signal
+		requestDuration time.Duration
+		shutdownTimeout time.Duration
+		expectedCode    int
+		expectResponse  bool
+	}{
+		{
+			name:            "SIGTERM drains in-flight request",
+			signal:          syscall.SIGTERM,
+			requestDuration: 300 * time.Millisecond,
+			shutdownTimeout: 5 * time.Second,
+			expectedCode:    ExitOK,
+			expectResponse:  true,
+		},
+		{
+			name:            "SIGINT drains in-flight request",
+			signal:          syscall.SIGINT,
+			requestDuration: 300 * time.Millisecond,
+			shutdownTimeout: 5 * time.Second,
+			expectedCode:    ExitOK,
+			expectResponse:  true,
+		},
+		{
+			name:            "drain deadline exceeded",
+			signal:          syscall.SIGTERM,
+			requestDuration: 5 * time.Second,
+			shutdownTimeout: 200 * time.Millisecond,
+			expectedCode:    ExitShutdownError,
+			expectResponse:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := startHelper(t, tt.requestDuration, tt.shutdownTimeout)
+
+			type result struct {
+				body string
+				err  error
+			}
+			results := make(chan result, 1)
+			go func() {
+				resp, err := http.Get("http://" + h.addr + "/slow")
+				if err != nil {
+					results <- result{err: err}
+					return
+				}
+				defer func() { _ = resp.Body.Close() }()
+				body, err := io.ReadAll(resp.Body)
+				results <- result{body: string(body), err: err}
+			}()
+
+			h.waitFor(t, "request started")
+			require.NoError(t, h.cmd.Process.Signal(tt.signal), "sending %s to child", tt.signal)
+
+			res := <-results
+			if tt.expectResponse {
+				require.NoError(t, res.err, "in-flight request should complete")
+				assert.Equal(t, "done", res.body, "in-flight request body mismatch")
+			} else {
+				assert.Error(t, res.err, "in-flight request should be cut off after the deadline")
+			}
+
+			h.waitFor(t, "hook ran")
+			assert.Equal(t, tt.expectedCode, h.exitCode(t), "child exit code mismatch")
+
+			_, err := net.DialTimeout("tcp", h.addr, time.Second)
+			assert.Error(t, err, "server should no longer accept connections")
+		})
+	}
+}
+
+func TestServe_StoppingEndsStreams(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+
+	started := make(chan struct{})
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-Stopping(r.Context())
+		_, _ = io.WriteString(w, "stopped")
+	})
+	srv := New("", stream, WithSignals(syscall.SIGUSR1), WithShutdownTimeout(5*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+
+	assert.NoError(t, <-done, "the stream should end before the drain deadline")
+	assert.Equal(t, "stopped", <-body, "the stream should complete its response")
+	assert.Nil(t, Stopping(context.Background()), "Stopping should be nil outside a Server")
+}
+
+func TestServe_Hooks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+
+	srv := New("", http.NotFoundHandler(), WithSignals(syscall.SIGUSR1))
+	var order []string
+	srv.OnShutdown("first", func(context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	srv.OnShutdown("second", func(context.Context) error {
+		order = append(order, "second")
+		return errors.New("boom")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	cancel()
+
+	err = <-done
+	assert.ErrorIs(t, err, ErrHook, "Serve should report the failing hook")
+	assert.ErrorContains(t, err, "second: boom", "Serve error should name the failing hook")
+	assert.Equal(t, []string{"second", "first"}, order, "hooks should run in reverse order")
+	assert.Equal(t, ExitShutdownError, ExitCode(err), "exit code mismatch")
+}
+
+func TestServe_HookTimeouts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+
+	srv := New("", http.NotFoundHandler(),
+		WithSignals(syscall.SIGUSR1), WithShutdownTimeout(50*time.Millisecond))
+	var lateErr error
+	srv.OnShutdown("late", func(ctx context.Context) error {
+		lateErr = ctx.Err()
+		return nil
+	})
+	srv.OnShutdown("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	cancel()
+
+	err = <-done
+	assert.ErrorContains(t, err, "slow: "+context.DeadlineExceeded.Error(),
+		"Serve should report the hook that timed out")
+	assert.NoError(t, lateErr, "a slow hook should not use up the timeout of the next ones")
+}
+
+func TestRun_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+	defer func() { _ = ln.Close() }()
+
+	srv := New(ln.Addr().String(), http.NotFoundHandler())
+	var hookRan bool
+	srv.OnShutdown("report", func(context.Context) error {
+		hookRan = true
+		return nil
+	})
+
+	err = srv.Run(context.Background())
+	assert.ErrorIs(t, err, ErrServe, "Run should fail when the address is in use")
+	assert.Equal(t, ExitServeError, ExitCode(err), "exit code mismatch")
+	assert.True(t, hookRan, "shutdown hooks should run when the server cannot listen")
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil), "nil error should map to ExitOK")
+	assert.Equal(t, ExitShutdownError, ExitCode(ErrDrainTimeout), "drain timeout exit code")
+}
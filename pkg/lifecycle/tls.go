@@ -0,0 +1,150 @@
+package lifecycle
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig selects how a Server secures its connections. It is meant to be embedded in the
+// configuration of each application.
+type TLSConfig struct {
+	CertFile       string        `config:"cert_file"       usage:"PEM certificate chain, enables HTTPS"`
+	KeyFile        string        `config:"key_file"        usage:"PEM private key of cert_file"`
+	ReloadInterval time.Duration `config:"reload_interval" usage:"how often cert files are checked"`
+	H2C            bool          `config:"h2c"             usage:"accept HTTP/2 without TLS"`
+}
+
+// DefaultTLSConfig returns a configuration serving plain HTTP, whose certificates, once
+// configured, are checked for changes every ten seconds.
+func DefaultTLSConfig() TLSConfig {
+	return TLSConfig{ReloadInterval: 10 * time.Second}
+}
+
+// Enabled reports whether c serves HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate checks that the configuration is usable.
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("tls.cert_file and tls.key_file must be set together")
+	}
+	if c.Enabled() && c.ReloadInterval <= 0 {
+		return errors.New("tls.reload_interval must be positive")
+	}
+	return nil
+}
+
+// WithTLS configures the protocols of the Server: with a certificate, it serves HTTPS with
+// HTTP/2 and HTTP/1.1, reloading the certificate when its files change; without one, it
+// serves HTTP/1.1 and, with H2C, HTTP/2 without TLS, for traffic that never leaves a trusted
+// network.
+func WithTLS(c TLSConfig) Option {
+	return func(s *Server) {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		if c.Enabled() {
+			protocols.SetHTTP2(true)
+			s.tls = &c
+		} else {
+			s.tls = nil
+			protocols.SetUnencryptedHTTP2(c.H2C)
+		}
+		s.httpServer.Protocols = &protocols
+	}
+}
+
+// certReloader serves the certificate of a key pair, and loads it again when its files
+// change, so that renewed certificates are picked up without a restart.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader loads the key pair of certFile and keyFile.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// lastModified returns the latest modification time of the key pair files.
+func (cr *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload loads the key pair again if its files changed since the last load, and reports
+// whether it did. The previous certificate is kept when the files cannot be loaded, e.g.
+// while only one of them has been replaced.
+func (cr *certReloader) reload() (bool, error) {
+	modTime, err := cr.lastModified()
+	if err != nil {
+		return false, err
+	}
+	cr.mu.RLock()
+	unchanged := cr.cert != nil && modTime.Equal(cr.modTime)
+	cr.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return false, err
+	}
+	cr.mu.Lock()
+	cr.cert, cr.modTime = &cert, modTime
+	cr.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the key pair every interval until ctx is done.
+func (cr *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := cr.reload()
+		if err != nil {
+			slog.Error("failed to reload TLS certificate, keeping the previous one",
+				"cert_file", cr.certFile, "error", err)
+			continue
+		}
+		if reloaded {
+			slog.Info("TLS certificate reloaded", "cert_file", cr.certFile)
+		}
+	}
+}
+
+// getCertificate is the tls.Config.GetCertificate of the Server.
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
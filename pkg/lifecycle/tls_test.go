@@ -0,0 +1,228 @@
+package lifecycle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 named cn, and its key, to
+// cert.pem and key.pem in dir, with the given modification time. It returns the
+// certificate.
+func writeCert(t *testing.T, dir, cn string, modTime time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Setup: generating key")
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err, "Setup: creating certificate")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err, "Setup: marshalling key")
+
+	files := map[string]*pem.Block{
+		"cert.pem": {Type: "CERTIFICATE", Bytes: der},
+		"key.pem":  {Type: "PRIVATE KEY", Bytes: keyDER},
+	}
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600),
+			"Setup: writing %s", name)
+		require.NoError(t, os.Chtimes(path, modTime, modTime), "Setup: touching %s", name)
+	}
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err, "Setup: parsing certificate")
+	return cert
+}
+
+// startServer serves a handler reporting the protocol of each request with srv, and stops
+// it when the test ends. It returns the address of the server.
+func startServer(t *testing.T, opts ...Option) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup: listening")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	opts = append(opts, WithSignals(syscall.SIGUSR1))
+	srv := New("", handler, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done, "Serve should stop cleanly")
+	})
+	return ln.Addr().String()
+}
+
+// get sends a GET request with client and returns the response, whose body is closed when
+// the test ends.
+func get(t *testing.T, client *http.Client, url string) *http.Response {
+	t.Helper()
+	resp, err := client.Get(url)
+	require.NoError(t, err, "GET %s should succeed", url)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestServe_TLS(t *testing.T) {
+	dir := t.TempDir()
+	first := writeCert(t, dir, "first", time.Now().Add(-time.Minute))
+	addr := startServer(t, WithTLS(TLSConfig{
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+		ReloadInterval: 10 * time.Millisecond,
+	}))
+
+	// newClient returns a client trusting only cert, which attempts HTTP/2 if http2 is set
+	// and never reuses its connections.
+	newClient := func(cert *x509.Certificate, http2 bool) *http.Client {
+		roots := x509.NewCertPool()
+		roots.AddCert(cert)
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+			ForceAttemptHTTP2: http2,
+			DisableKeepAlives: true,
+		}}
+	}
+
+	resp := get(t, newClient(first, true), "https://"+addr)
+	assert.Equal(t, "HTTP/2.0", resp.Proto, "HTTPS should negotiate HTTP/2")
+	assert.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName,
+		"the configured certificate should be served")
+
+	second := writeCert(t, dir, "second", time.Now())
+	client := newClient(second, true)
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get("https://" + addr)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName == "second"
+	}, 5*time.Second, 20*time.Millisecond, "the replaced certificate should be reloaded")
+
+	client = newClient(second, false)
+	resp = get(t, client, "https://"+addr)
+	assert.Equal(t, "HTTP/1.1", resp.Proto, "HTTP/1.1 should still be served over TLS")
+}
+
+func TestServe_TLSCertificateError(t *testing.T) {
+	tests := []struct {
+		name  string
+		write bool
+	}{
+		{name: "missing certificate"},
+		{name: "unreadable certificate", write: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err, "Setup: listening")
+			dir := t.TempDir()
+			certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+			if tt.write {
+				require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600),
+					"Setup: writing the certificate")
+				require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600),
+					"Setup: writing the key")
+			}
+			srv := New("", http.NotFoundHandler(), WithTLS(TLSConfig{
+				CertFile:       certFile,
+				KeyFile:        keyFile,
+				ReloadInterval: time.Second,
+			}))
+			var hookRan bool
+			srv.OnShutdown("report", func(context.Context) error {
+				hookRan = true
+				return nil
+			})
+
+			err = srv.Serve(context.Background(), ln)
+			assert.ErrorIs(t, err, ErrServe, "Serve should fail without a usable certificate")
+			assert.Equal(t, ExitServeError, ExitCode(err), "exit code mismatch")
+			assert.True(t, hookRan, "shutdown hooks should run when the certificate cannot load")
+		})
+	}
+}
+
+func TestServe_H2C(t *testing.T) {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	h2c := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	addr := startServer(t, WithTLS(TLSConfig{H2C: true}))
+	resp := get(t, h2c, "http://"+addr)
+	assert.Equal(t, "HTTP/2.0", resp.Proto, "h2c should be served when enabled")
+	resp = get(t, http.DefaultClient, "http://"+addr)
+	assert.Equal(t, "HTTP/1.1", resp.Proto, "HTTP/1.1 should still be served")
+
+	addr = startServer(t, WithTLS(DefaultTLSConfig()))
+	_, err := h2c.Get("http://" + addr)
+	assert.Error(t, err, "h2c should be refused unless enabled")
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      TLSConfig
+		expectError bool
+	}{
+		{name: "default", config: DefaultTLSConfig()},
+		{name: "h2c", config: TLSConfig{H2C: true}},
+		{
+			name: "key pair",
+			config: TLSConfig{
+				CertFile:       "cert.pem",
+				KeyFile:        "key.pem",
+				ReloadInterval: time.Second,
+			},
+		},
+		{name: "certificate only", config: TLSConfig{CertFile: "cert.pem"}, expectError: true},
+		{name: "key only", config: TLSConfig{KeyFile: "key.pem"}, expectError: true},
+		{
+			name:        "no reload interval",
+			config:      TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectError {
+				assert.Error(t, err, "Validate should fail")
+			} else {
+				assert.NoError(t, err, "Validate should not fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"bufio"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, suited to request durations in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// atomicFloat is a float64 updated with compare-and-swap.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) load() float64 { return math.Float64frombits(f.bits.Load()) }
+
+func (f *atomicFloat) store(v float64) { f.bits.Store(math.Float64bits(v)) }
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if f.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	v atomicFloat
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add increments the counter by delta. Negative deltas are ignored, as counters never decrease.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		slog.Warn("ignoring negative counter increment", "delta", delta)
+		return
+	}
+	c.v.add(delta)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 { return c.v.load() }
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	v atomicFloat
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) { g.v.store(v) }
+
+// Inc increments the gauge by 1.
+func (g *Gauge) Inc() { g.v.add(1) }
+
+// Dec decrements the gauge by 1.
+func (g *Gauge) Dec() { g.v.add(-1) }
+
+// Add adds delta, which may be negative, to the gauge.
+func (g *Gauge) Add(delta float64) { g.v.add(delta) }
+
+// SetToCurrentTime sets the gauge to the current Unix time in seconds.
+func (g *Gauge) SetToCurrentTime() {
+	g.Set(float64(time.Now().UnixNano()) / float64(time.Second))
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 { return g.v.load() }
+
+// Histogram counts observations in configurable buckets.
+type Histogram struct {
+	upperBounds []float64
+	counts      []atomic.Uint64 // Non-cumulative count per bucket; the last one is +Inf.
+	sum         atomicFloat
+	count       atomic.Uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		counts:      make([]atomic.Uint64, len(buckets)+1),
+	}
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upperBounds, v) // First bucket whose upper bound is >= v.
+	h.counts[i].Add(1)
+	h.sum.add(v)
+	h.count.Add(1)
+}
+
+// ObserveDuration observes the time elapsed since start, in seconds.
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 { return h.count.Load() }
+
+// Sum returns the sum of all observations.
+func (h *Histogram) Sum() float64 { return h.sum.load() }
+
+// vec holds the children of a metric family, one per distinct set of label values.
+type vec[T any] struct {
+	d        descriptor
+	newChild func() *T
+
+	mu       sync.RWMutex
+	children map[string]*T
+	values   map[string][]string
+}
+
+func newVec[T any](d descriptor, newChild func() *T) *vec[T] {
+	return &vec[T]{
+		d:        d,
+		newChild: newChild,
+		children: make(map[string]*T),
+		values:   make(map[string][]string),
+	}
+}
+
+func (v *vec[T]) desc() *descriptor { return &v.d }
+
+// with returns the child for the given label values, creating it on first use.
+// It panics if the number of values does not match the number of labels.
+func (v *vec[T]) with(values ...string) *T {
+	if len(values) != len(v.d.labels) {
+		slog.Error("wrong number of label values", "metric", v.d.name,
+			"expected", len(v.d.labels), "got", len(values))
+		panic("Error: wrong number of label values for metric " + v.d.name)
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if child, ok := v.children[key]; ok {
+		return child
+	}
+	child = v.newChild()
+	v.children[key] = child
+	v.values[key] = append([]string(nil), values...)
+	return child
+}
+
+// each calls fn for every child, sorted by label values.
+func (v *vec[T]) each(fn func(values []string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	values := make([][]string, len(keys))
+	for i, k := range keys {
+		children[i], values[i] = v.children[k], v.values[k]
+	}
+	v.mu.RUnlock()
+
+	for i := range keys {
+		fn(values[i], children[i])
+	}
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// WithLabelValues returns the counter for the given label values, in label order.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter { return v.with(values...) }
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.each(func(values []string, c *Counter) {
+		writeSample(w, v.d.name, v.d.labels, values, c.Value())
+	})
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+// WithLabelValues returns the gauge for the given label values, in label order.
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge { return v.with(values...) }
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.each(func(values []string, g *Gauge) {
+		writeSample(w, v.d.name, v.d.labels, values, g.Value())
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	*vec[Histogram]
+}
+
+// WithLabelValues returns the histogram for the given label values, in label order.
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram { return v.with(values...) }
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	bucketLabels := append(append([]string(nil), v.d.labels...), "le")
+	v.each(func(values []string, h *Histogram) {
+		bucketValues := append(append([]string(nil), values...), "")
+		var cumulative uint64
+		for i := range h.counts {
+			cumulative += h.counts[i].Load()
+			bucketValues[len(values)] = "+Inf"
+			if i < len(h.upperBounds) {
+				bucketValues[len(values)] = strconv.FormatFloat(h.upperBounds[i], 'g', -1, 64)
+			}
+			writeSample(w, v.d.name+"_bucket", bucketLabels, bucketValues, float64(cumulative))
+		}
+		writeSample(w, v.d.name+"_sum", v.d.labels, values, h.Sum())
+		writeSample(w, v.d.name+"_count", v.d.labels, values, float64(h.Count()))
+	})
+}
+
+// gaugeFunc is a gauge without labels whose value is computed at collection time.
+type gaugeFunc struct {
+	d  descriptor
+	fn func() float64
+}
+
+func (g *gaugeFunc) desc() *descriptor { return &g.d }
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeSample(w, g.d.name, nil, nil, g.fn())
+}
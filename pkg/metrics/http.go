@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// Middleware returns a chi middleware recording inbound request counts, durations and
+// in-flight requests, labelled by route pattern so that path parameters do not explode
+// the number of series.
+func Middleware(reg *Registry) func(http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_requests_total",
+		"Total number of HTTP requests handled.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds",
+		"Duration of HTTP requests in seconds.", nil, "method", "route")
+	inFlight := reg.NewGauge("http_requests_in_flight",
+		"Number of HTTP requests currently being handled.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			rw := middleware.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			route := middleware.RoutePattern(r)
+			requests.WithLabelValues(r.Method, route, strconv.Itoa(rw.Status())).Inc()
+			duration.WithLabelValues(r.Method, route).ObserveDuration(start)
+		})
+	}
+}
+
+// InstrumentRoundTripper wraps next so that outbound requests are counted and timed,
+// labelled by target (a logical name for the remote service, e.g. "api"), method and
+// status code. Transport errors are recorded with the status "error".
+// If next is nil, http.DefaultTransport is used.
+func InstrumentRoundTripper(
+	reg *Registry,
+	target string,
+	next http.RoundTripper,
+) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return instrumentedTransport{
+		next:   next,
+		target: target,
+		requests: reg.NewCounterVec("http_client_requests_total",
+			"Total number of outbound HTTP requests.", "target", "method", "status"),
+		duration: reg.NewHistogramVec("http_client_request_duration_seconds",
+			"Duration of outbound HTTP requests in seconds.", nil, "target", "method"),
+	}
+}
+
+// instrumentedTransport is the http.RoundTripper returned by InstrumentRoundTripper.
+type instrumentedTransport struct {
+	next     http.RoundTripper
+	target   string
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.duration.WithLabelValues(t.target, req.Method).ObserveDuration(start)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.requests.WithLabelValues(t.target, req.Method, status).Inc()
+	return resp, err
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, reg *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, reg.Render(&buf), "Render should not fail")
+	return buf.String()
+}
+
+func TestMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	r := chi.NewRouter()
+	r.Use(Middleware(reg))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	out := render(t, reg)
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/users/{id}",status="202"} 2`,
+		"requests should be counted by route pattern")
+	assert.Contains(t, out, `http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		"unmatched requests should share a single series")
+	assert.Contains(t, out,
+		`http_request_duration_seconds_count{method="GET",route="/users/{id}"} 2`,
+		"request durations should be observed")
+	assert.Contains(t, out, "http_requests_in_flight 0", "in-flight gauge should be back to 0")
+}
+
+// refusingTransport is an http.RoundTripper whose connections are refused.
+type refusingTransport struct{}
+
+func (refusingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestInstrumentRoundTripper(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	reg := NewRegistry()
+	client := &http.Client{Transport: InstrumentRoundTripper(reg, "api", nil)}
+	resp, err := client.Get(upstream.URL)
+	require.NoError(t, err, "request should succeed")
+	_ = resp.Body.Close()
+
+	failing := &http.Client{Transport: InstrumentRoundTripper(reg, "api", refusingTransport{})}
+	_, err = failing.Get(upstream.URL)
+	require.Error(t, err, "request should fail")
+
+	out := render(t, reg)
+	assert.Contains(t, out, `http_client_requests_total{target="api",method="GET",status="503"} 1`,
+		"outbound responses should be counted by status")
+	assert.Contains(
+		t,
+		out,
+		`http_client_requests_total{target="api",method="GET",status="error"} 1`,
+		"transport errors should be counted",
+	)
+	assert.Contains(t, out,
+		`http_client_request_duration_seconds_count{target="api",method="GET"} 2`,
+		"outbound durations should be observed")
+}
+
+func TestRegisterRuntimeMetrics(t *testing.T) {
+	reg := NewRegistry()
+	RegisterRuntimeMetrics(reg)
+	out := render(t, reg)
+	for _, name := range []string{"go_goroutines", "go_memstats_heap_alloc_bytes", "process_start_time_seconds"} {
+		assert.Contains(
+			t,
+			out,
+			"# TYPE "+name+" gauge",
+			"runtime metric %s should be registered",
+			name,
+		)
+	}
+}
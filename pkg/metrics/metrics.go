@@ -0,0 +1,236 @@
+// Package metrics provides counters, gauges and histograms rendered in the Prometheus
+// text exposition format, without any external dependency.
+//
+// Metrics are created through a Registry, which serves them at /metrics:
+//
+//	reg := metrics.NewRegistry()
+//	requests := reg.NewCounterVec("jobs_processed_total", "Jobs processed.", "queue")
+//	requests.WithLabelValues("emails").Inc()
+//	r.Handle("/metrics", reg.Handler())
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	validMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	validLabelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// metricType is the TYPE line value of a metric family.
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// family is a named group of metrics sharing a type and label names.
+type family interface {
+	desc() *descriptor
+	// write appends the family samples, sorted by label values, to w.
+	write(w *bufio.Writer)
+}
+
+// descriptor holds what identifies a metric family.
+type descriptor struct {
+	name   string
+	help   string
+	typ    metricType
+	labels []string
+}
+
+// Registry holds metric families and renders them.
+// Its methods are safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]family
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// register returns the family already registered under d.name if it has the same type and
+// labels, or stores the one built by create. It panics on an invalid or conflicting
+// definition, which is a programming error detected at start-up.
+func (r *Registry) register(d descriptor, create func() family) family {
+	if !validMetricName.MatchString(d.name) {
+		slog.Error("invalid metric name", "metric", d.name)
+		panic("Error: invalid metric name: " + d.name)
+	}
+	for _, l := range d.labels {
+		if !validLabelName.MatchString(l) || strings.HasPrefix(l, "__") || l == "le" {
+			slog.Error("invalid metric label name", "metric", d.name, "label", l)
+			panic("Error: invalid label name " + l + " for metric " + d.name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.families[d.name]; ok {
+		e := existing.desc()
+		if e.typ != d.typ || !slices.Equal(e.labels, d.labels) {
+			slog.Error("metric already registered with a different definition", "metric", d.name)
+			panic("Error: metric already registered with a different definition: " + d.name)
+		}
+		return existing
+	}
+	f := create()
+	r.families[d.name] = f
+	return f
+}
+
+// NewCounterVec registers a counter partitioned by the given labels.
+// Calling it again with the same definition returns the existing counter.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	d := descriptor{name: name, help: help, typ: typeCounter, labels: labels}
+	return r.register(d, func() family {
+		return &CounterVec{newVec(d, func() *Counter { return &Counter{} })}
+	}).(*CounterVec)
+}
+
+// NewCounter registers a counter without labels.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+// NewGaugeVec registers a gauge partitioned by the given labels.
+// Calling it again with the same definition returns the existing gauge.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	d := descriptor{name: name, help: help, typ: typeGauge, labels: labels}
+	return r.register(d, func() family {
+		return &GaugeVec{newVec(d, func() *Gauge { return &Gauge{} })}
+	}).(*GaugeVec)
+}
+
+// NewGauge registers a gauge without labels.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).WithLabelValues()
+}
+
+// NewGaugeFunc registers a gauge whose value is computed by fn at collection time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	d := descriptor{name: name, help: help, typ: typeGauge}
+	r.register(d, func() family { return &gaugeFunc{d: d, fn: fn} })
+}
+
+// NewHistogramVec registers a histogram partitioned by the given labels.
+// Buckets are upper bounds in increasing order; nil means DefBuckets.
+// Calling it again with the same definition returns the existing histogram.
+func (r *Registry) NewHistogramVec(
+	name, help string,
+	buckets []float64,
+	labels ...string,
+) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		slog.Error("histogram buckets must be sorted", "metric", name)
+		panic("Error: histogram buckets must be sorted for metric " + name)
+	}
+	d := descriptor{name: name, help: help, typ: typeHistogram, labels: labels}
+	return r.register(d, func() family {
+		return &HistogramVec{newVec(d, func() *Histogram { return newHistogram(buckets) })}
+	}).(*HistogramVec)
+}
+
+// NewHistogram registers a histogram without labels.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).WithLabelValues()
+}
+
+// Render writes every metric family, sorted by name, in the text exposition format.
+func (r *Registry) Render(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	r.mu.RLock()
+	families := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].desc().name < families[j].desc().name
+	})
+
+	for _, f := range families {
+		d := f.desc()
+		if d.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+		f.write(w)
+	}
+	return w.Flush()
+}
+
+// Handler returns an http.Handler serving the metrics in the text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := r.Render(w); err != nil {
+			slog.Error("failed to write metrics", "error", err)
+		}
+	})
+}
+
+// writeSample writes a single sample line.
+func writeSample(w *bufio.Writer, name string, labels, values []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabelValue(values[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat renders a sample value the way Prometheus expects it.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Render(t *testing.T) {
+	reg := NewRegistry()
+	jobs := reg.NewCounterVec("jobs_total", "Jobs processed.", "queue")
+	jobs.WithLabelValues("emails").Add(2)
+	jobs.WithLabelValues(`we"ird\`).Inc()
+	temp := reg.NewGauge("temperature_celsius", "Current\ntemperature.")
+	temp.Set(21.5)
+	temp.Dec()
+	latency := reg.NewHistogram("latency_seconds", "", []float64{0.1, 1})
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(3)
+	reg.NewGaugeFunc("answer", "The answer.", func() float64 { return 42 })
+
+	var buf bytes.Buffer
+	require.NoError(t, reg.Render(&buf), "Render should not fail")
+
+	expected := `# HELP answer The answer.
+# TYPE answer gauge
+answer 42
+# HELP jobs_total Jobs processed.
+# TYPE jobs_total counter
+jobs_total{queue="emails"} 2
+jobs_total{queue="we\"ird\\"} 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.55
+latency_seconds_count 3
+# HELP temperature_celsius Current\ntemperature.
+# TYPE temperature_celsius gauge
+temperature_celsius 20.5
+`
+	assert.Equal(t, expected, buf.String(), "exposition output mismatch")
+}
+
+func TestRegistry_RegisterIsIdempotent(t *testing.T) {
+	reg := NewRegistry()
+	first := reg.NewCounterVec("requests_total", "Requests.", "code")
+	second := reg.NewCounterVec("requests_total", "Requests.", "code")
+	assert.Same(t, first, second, "same definition should return the existing metric")
+}
+
+func TestRegistry_InvalidDefinitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(reg *Registry)
+	}{
+		{
+			name:     "invalid metric name",
+			register: func(reg *Registry) { reg.NewCounter("bad-name", "") },
+		},
+		{
+			name:     "reserved label name",
+			register: func(reg *Registry) { reg.NewCounterVec("ok_total", "", "le") },
+		},
+		{
+			name: "conflicting type",
+			register: func(reg *Registry) {
+				reg.NewCounter("thing", "")
+				reg.NewGauge("thing", "")
+			},
+		},
+		{
+			name: "conflicting labels",
+			register: func(reg *Registry) {
+				reg.NewCounterVec("thing_total", "", "a")
+				reg.NewCounterVec("thing_total", "", "b")
+			},
+		},
+		{
+			name:     "unsorted buckets",
+			register: func(reg *Registry) { reg.NewHistogram("h", "", []float64{1, 0.5}) },
+		},
+		{
+			name:     "wrong label count",
+			register: func(reg *Registry) { reg.NewCounterVec("c_total", "", "a").WithLabelValues() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Panics(t, func() { tt.register(NewRegistry()) }, "definition should be rejected")
+		})
+	}
+}
+
+func TestCounter_IgnoresNegativeIncrements(t *testing.T) {
+	c := NewRegistry().NewCounter("c_total", "")
+	c.Add(3)
+	c.Add(-1)
+	assert.Equal(t, 3.0, c.Value(), "counters must never decrease")
+}
+
+func TestCounter_ConcurrentIncrements(t *testing.T) {
+	c := NewRegistry().NewCounterVec("c_total", "", "worker")
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c.WithLabelValues("w").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5000.0, c.WithLabelValues("w").Value(), "concurrent increments lost")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("hits_total", "Hits.").Inc()
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "handler returned wrong status code")
+	assert.Equal(t, ContentType, rr.Header().Get("Content-Type"), "wrong content type")
+	assert.Contains(t, rr.Body.String(), "hits_total 1\n", "metrics body mismatch")
+}
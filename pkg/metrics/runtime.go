@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// RegisterRuntimeMetrics registers gauges describing the Go runtime and the process.
+func RegisterRuntimeMetrics(reg *Registry) {
+	startTime := float64(time.Now().Unix())
+	reg.NewGaugeFunc("process_start_time_seconds",
+		"Start time of the process since unix epoch in seconds.",
+		func() float64 { return startTime })
+	reg.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	reg.NewGaugeFunc("go_memstats_heap_alloc_bytes",
+		"Number of heap bytes allocated and still in use.",
+		func() float64 {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return float64(m.HeapAlloc)
+		})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_AccessLog(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		expectedRoute string
+		expectedCode  int
+		expectedBytes float64
+		expectedLevel string
+	}{
+		{
+			name:          "matched route",
+			path:          "/users/42",
+			expectedRoute: "/users/{id}",
+			expectedCode:  http.StatusCreated,
+			expectedBytes: 2,
+			expectedLevel: "INFO",
+		},
+		{
+			name:          "server error",
+			path:          "/fail",
+			expectedRoute: "/fail",
+			expectedCode:  http.StatusInternalServerError,
+			expectedBytes: 0,
+			expectedLevel: "ERROR",
+		},
+		{
+			name:          "unmatched route",
+			path:          "/nope",
+			expectedRoute: "unmatched",
+			expectedCode:  http.StatusNotFound,
+			expectedBytes: 19, // "404 page not found\n"
+			expectedLevel: "INFO",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			var handlerID string
+			r := chi.NewRouter()
+			r.Use(RequestID, Logger(logger))
+			r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				handlerID = GetRequestID(r.Context())
+				LoggerFromContext(r.Context()).Info("inside handler")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("ok"))
+			})
+			r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(lines[len(lines)-1], &record), "decoding access log")
+
+			assert.Equal(t, "http request", record["msg"], "access log message mismatch")
+			assert.Equal(t, tt.expectedLevel, record["level"], "access log level mismatch")
+			assert.Equal(t, "req-1", record["request_id"], "access log request_id mismatch")
+			assert.Equal(t, http.MethodGet, record["method"], "access log method mismatch")
+			assert.Equal(t, tt.expectedRoute, record["route"], "access log route mismatch")
+			assert.Equal(
+				t,
+				float64(tt.expectedCode),
+				record["status"],
+				"access log status mismatch",
+			)
+			assert.Equal(t, tt.expectedBytes, record["bytes"], "access log bytes mismatch")
+			assert.Contains(t, record, "duration", "access log should record the duration")
+
+			if handlerID != "" {
+				require.Len(t, lines, 2, "handler log and access log expected")
+				assert.Contains(t, string(lines[0]), `"request_id":"req-1"`,
+					"per-request logger should carry the request ID")
+			}
+		})
+	}
+}
+
+func TestLoggerFromContext_Default(t *testing.T) {
+	assert.Equal(t, slog.Default(), LoggerFromContext(t.Context()),
+		"LoggerFromContext should fall back to slog.Default()")
+}
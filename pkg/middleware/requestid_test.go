@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		incoming   string
+		expectKeep bool
+	}{
+		{name: "generated when missing", incoming: "", expectKeep: false},
+		{name: "incoming ID is reused", incoming: "abc-123.x:y_z", expectKeep: true},
+		{name: "malformed ID is replaced", incoming: "bad id\n", expectKeep: false},
+		{name: "oversized ID is replaced", incoming: strings.Repeat("a", 129), expectKeep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = GetRequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			require.NotEmpty(t, seen, "handler should see a request ID")
+			assert.Equal(t, seen, rr.Header().Get(RequestIDHeader), "response should echo the ID")
+			if tt.expectKeep {
+				assert.Equal(t, tt.incoming, seen, "incoming ID should be reused")
+			} else {
+				assert.NotEqual(t, tt.incoming, seen, "incoming ID should be replaced")
+				assert.Len(t, seen, 32, "generated IDs are 128-bit hex strings")
+			}
+		})
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: PropagateRequestID(nil)}
+	req, err := http.NewRequestWithContext(
+		WithRequestID(t.Context(), "req-42"), http.MethodGet, upstream.URL, nil)
+	require.NoError(t, err, "Setup: creating request")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err, "request should succeed")
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "req-42", forwarded, "upstream should receive the request ID")
+	assert.Empty(t, req.Header.Get(RequestIDHeader), "caller's request must not be modified")
+}
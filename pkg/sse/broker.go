@@ -0,0 +1,245 @@
+package sse
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supergeoff/go-starter/pkg/lifecycle"
+)
+
+const (
+	// DefaultHistory is the number of events a Broker keeps to replay to reconnecting
+	// subscribers.
+	DefaultHistory = 64
+	// DefaultKeepAlive is how often a Broker writes a comment to idle streams.
+	DefaultKeepAlive = 15 * time.Second
+)
+
+// subscriberBuffer is the number of events a subscriber may lag behind before it is
+// disconnected, to catch up from the history when it reconnects.
+const subscriberBuffer = 16
+
+// Source produces the events of a Broker by calling its Publish method, until ctx is done.
+type Source func(ctx context.Context, b *Broker)
+
+// Broker fans the events it publishes out to its subscribers, and serves them as event
+// streams. It keeps the last events, so that reconnecting subscribers resume after the
+// last one they received. A subscriber whose last event is not kept anymore, or which
+// has none, starts from the last event: events are meant to carry a whole state, such as
+// a status, rather than a change to it.
+type Broker struct {
+	history   int
+	keepAlive time.Duration
+	retry     time.Duration
+	source    Source
+
+	mu          sync.Mutex
+	prefix      string
+	seq         uint64
+	events      []Event
+	subscribers map[chan Event]struct{}
+	stopSource  context.CancelFunc
+	closed      bool
+}
+
+// Option configures a Broker.
+type Option func(*Broker)
+
+// WithHistory sets the number of events kept for reconnecting subscribers, DefaultHistory
+// by default.
+func WithHistory(n int) Option {
+	return func(b *Broker) {
+		if n > 0 {
+			b.history = n
+		}
+	}
+}
+
+// WithKeepAlive sets how often a comment is written to idle streams, DefaultKeepAlive by
+// default.
+func WithKeepAlive(d time.Duration) Option {
+	return func(b *Broker) {
+		if d > 0 {
+			b.keepAlive = d
+		}
+	}
+}
+
+// WithRetry sets how long clients wait before reconnecting to a stream that ended. The
+// default is left to the clients, about three seconds in browsers.
+func WithRetry(d time.Duration) Option {
+	return func(b *Broker) { b.retry = d }
+}
+
+// WithSource runs source while the Broker has subscribers, so that events are only
+// produced when someone listens. It is started by the first subscriber, and its context
+// is cancelled when the last one leaves.
+func WithSource(source Source) Option {
+	return func(b *Broker) { b.source = source }
+}
+
+// NewBroker returns a Broker without events.
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		history:   DefaultHistory,
+		keepAlive: DefaultKeepAlive,
+		// Event IDs of another process, e.g. before a restart, are never found.
+		prefix:      strconv.FormatInt(time.Now().UnixNano(), 36) + "-",
+		subscribers: make(map[chan Event]struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish sends an event of type typ carrying data to every subscriber, and returns it.
+func (b *Broker) Publish(typ, data string) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e := Event{ID: b.prefix + strconv.FormatUint(b.seq, 10), Type: typ, Data: data}
+	if b.closed {
+		return e
+	}
+	b.events = append(b.events, e)
+	if len(b.events) > b.history {
+		b.events = append([]Event(nil), b.events[len(b.events)-b.history:]...)
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// Too slow: disconnected, it resumes from the history when it reconnects.
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	if len(b.subscribers) == 0 {
+		b.stop()
+	}
+	return e
+}
+
+// Subscribe returns the events published after the one identified by lastEventID, or the
+// last event if it is unknown, and a channel receiving the events published from now on.
+// The channel is closed when the subscriber lags behind or the Broker is closed.
+// unsubscribe must be called once the events are not read anymore.
+func (b *Broker) Subscribe(
+	lastEventID string,
+) (backlog []Event, events <-chan Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return nil, ch, func() {}
+	}
+
+	backlog = b.backlog(lastEventID)
+	b.subscribers[ch] = struct{}{}
+	if len(b.subscribers) == 1 && b.source != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		b.stopSource = cancel
+		go b.source(ctx, b)
+	}
+
+	var once sync.Once
+	return backlog, ch, func() { once.Do(func() { b.unsubscribe(ch) }) }
+}
+
+// backlog returns the events to send to a subscriber resuming after lastEventID.
+func (b *Broker) backlog(lastEventID string) []Event {
+	for i, e := range b.events {
+		if e.ID == lastEventID {
+			return append([]Event(nil), b.events[i+1:]...)
+		}
+	}
+	if len(b.events) == 0 {
+		return nil
+	}
+	return []Event{b.events[len(b.events)-1]}
+}
+
+func (b *Broker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[ch]; !ok {
+		return // Already disconnected.
+	}
+	delete(b.subscribers, ch)
+	close(ch)
+	if len(b.subscribers) == 0 {
+		b.stop()
+	}
+}
+
+// stop cancels the context of the running source, if any. b.mu must be held.
+func (b *Broker) stop() {
+	if b.stopSource != nil {
+		b.stopSource()
+		b.stopSource = nil
+	}
+}
+
+// Close disconnects every subscriber and stops the source. Events published afterwards
+// are dropped.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	b.stop()
+}
+
+// ServeHTTP streams the events to the client, starting after the event identified by the
+// Last-Event-ID header. The stream ends when the client leaves, when it lags behind, when
+// the Broker is closed or when the server starts shutting down; clients then reconnect.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sw, err := NewWriter(w)
+	if err != nil {
+		slog.Error("Failed to start event stream", "error", err)
+		return
+	}
+	backlog, events, unsubscribe := b.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer unsubscribe()
+
+	if b.retry > 0 {
+		if err := sw.Send(Event{Retry: b.retry}); err != nil {
+			return
+		}
+	}
+	for _, e := range backlog {
+		if err := sw.Send(e); err != nil {
+			return
+		}
+	}
+	keepAlive := time.NewTicker(b.keepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-lifecycle.Stopping(r.Context()):
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			err = sw.Send(e)
+		case <-keepAlive.C:
+			err = sw.Comment("keep-alive")
+		}
+		if err != nil {
+			return // The client left.
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker_Subscribe(t *testing.T) {
+	b := NewBroker(WithHistory(3))
+	var published []Event
+	for i := range 5 {
+		published = append(published, b.Publish("state", strconv.Itoa(i)))
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		expected    []Event
+	}{
+		{name: "new subscriber", expected: published[4:]},
+		{name: "resuming", lastEventID: published[2].ID, expected: published[3:]},
+		{name: "up to date", lastEventID: published[4].ID},
+		{name: "expired event", lastEventID: published[0].ID, expected: published[4:]},
+		{name: "other process", lastEventID: "abc-3", expected: published[4:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlog, _, unsubscribe := b.Subscribe(tt.lastEventID)
+			defer unsubscribe()
+			assert.Equal(t, tt.expected, backlog, "backlog mismatch")
+		})
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	b := NewBroker()
+	backlog, events, unsubscribe := b.Subscribe("")
+	defer unsubscribe()
+	assert.Empty(t, backlog, "a new Broker should have no backlog")
+
+	first := b.Publish("state", "a")
+	second := b.Publish("state", "b")
+	assert.NotEqual(t, first.ID, second.ID, "event IDs should be unique")
+	assert.Equal(t, first, <-events, "subscribers should receive the events in order")
+	assert.Equal(t, second, <-events, "subscribers should receive the events in order")
+
+	for i := range subscriberBuffer + 1 {
+		b.Publish("state", strconv.Itoa(i))
+	}
+	for range subscriberBuffer {
+		<-events
+	}
+	_, ok := <-events
+	assert.False(t, ok, "subscribers lagging behind should be disconnected")
+
+	b.Close()
+	backlog, events, _ = b.Subscribe("")
+	_, ok = <-events
+	assert.Nil(t, backlog, "a closed Broker should have no backlog")
+	assert.False(t, ok, "a closed Broker should disconnect subscribers at once")
+}
+
+func TestBroker_Source(t *testing.T) {
+	var running atomic.Int32
+	b := NewBroker(WithSource(func(ctx context.Context, b *Broker) {
+		running.Add(1)
+		defer running.Add(-1)
+		b.Publish("state", "up")
+		<-ctx.Done()
+	}))
+	assert.Equal(t, int32(0), running.Load(), "the source should wait for subscribers")
+
+	_, first, unsubscribeFirst := b.Subscribe("")
+	_, _, unsubscribeSecond := b.Subscribe("")
+	assert.Equal(t, "up", (<-first).Data, "the source should publish to subscribers")
+	assert.Equal(t, int32(1), running.Load(), "a single source should run")
+
+	unsubscribeFirst()
+	assert.Equal(t, int32(1), running.Load(), "the source should run while subscribed")
+	unsubscribeSecond()
+	assert.Eventually(t, func() bool { return running.Load() == 0 }, time.Second,
+		time.Millisecond, "the source should stop with the last subscriber")
+}
+
+func TestBroker_ServeHTTP(t *testing.T) {
+	b := NewBroker(WithRetry(time.Second), WithKeepAlive(10*time.Millisecond))
+	first := b.Publish("state", "a")
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	// connect opens a stream resuming after lastEventID and returns its Reader.
+	connect := func(lastEventID string) *Reader {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err, "Setup: creating request")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, "connecting to the stream")
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		assert.Equal(t, ContentType, resp.Header.Get("Content-Type"), "Content-Type mismatch")
+		return NewReader(bufio.NewReader(resp.Body))
+	}
+
+	rd := connect("")
+	e, err := rd.Next()
+	require.NoError(t, err, "reading the backlog")
+	assert.Equal(t, first.Data, e.Data, "the stream should start from the last event")
+	assert.Equal(t, time.Second, rd.Retry(), "the reconnection delay should be sent")
+
+	second := b.Publish("state", "b")
+	e, err = rd.Next()
+	require.NoError(t, err, "reading a published event")
+	assert.Equal(t, second.ID, e.ID, "published events should be streamed")
+
+	third := b.Publish("state", "c")
+	rd = connect(first.ID)
+	for _, expected := range []Event{second, third} {
+		e, err = rd.Next()
+		require.NoError(t, err, "reading the replayed events")
+		assert.Equal(t, expected.ID, e.ID, "events after Last-Event-ID should be replayed")
+	}
+
+	b.Close()
+	_, err = rd.Next()
+	assert.Error(t, err, "closing the Broker should end the streams")
+}
@@ -0,0 +1,87 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineSize bounds the lines of the streams a Reader parses.
+const maxLineSize = 1 << 20
+
+// Reader parses an event stream, as sent by Writer.
+type Reader struct {
+	scanner *bufio.Scanner
+	lastID  string
+	retry   time.Duration
+}
+
+// NewReader returns a Reader parsing the event stream read from r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next event of the stream. Its ID is the last ID received, which carries
+// over to the events sent without one, and its Retry the last reconnection delay received.
+// Next returns io.EOF at the end of the stream; an event cut short by it is dropped.
+func (rd *Reader) Next() (Event, error) {
+	var (
+		typ     string
+		data    strings.Builder
+		hasData bool
+	)
+	for rd.scanner.Scan() {
+		line := rd.scanner.Text()
+		if line == "" {
+			if !hasData {
+				typ = ""
+				continue
+			}
+			return Event{
+				ID:    rd.lastID,
+				Type:  typ,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+				Retry: rd.retry,
+			}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // A comment.
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			typ = value
+		case "data":
+			data.WriteString(value + "\n")
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				rd.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				rd.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := rd.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// LastEventID returns the last event ID received, to be sent in the Last-Event-ID header
+// when reconnecting.
+func (rd *Reader) LastEventID() string {
+	return rd.lastID
+}
+
+// Retry returns the last reconnection delay received, or zero.
+func (rd *Reader) Retry() time.Duration {
+	return rd.retry
+}
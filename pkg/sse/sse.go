@@ -0,0 +1,98 @@
+// Package sse implements Server-Sent Events: a Writer streaming events to a response, a
+// Reader parsing a stream, and a Broker fanning published events out to subscribers, which
+// resume after the last event they received through the Last-Event-ID header.
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of event streams.
+const ContentType = "text/event-stream"
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID identifies the event; clients send the last one they received in the
+	// Last-Event-ID header when they reconnect.
+	ID string
+	// Type is the name of the event, "message" when empty.
+	Type string
+	// Data is the payload of the event. It may span several lines.
+	Data string
+	// Retry, when positive, sets how long clients wait before reconnecting.
+	Retry time.Duration
+}
+
+// MarshalText encodes e in the event stream format, followed by the blank line
+// dispatching it. Carriage returns and line feeds are dropped from the ID and type, which
+// cannot span lines.
+func (e Event) MarshalText() ([]byte, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Type != "" {
+		b.WriteString("event: " + singleLine(e.Type) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if e.Data != "" {
+		data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+		for line := range strings.SplitSeq(data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// singleLine drops the line breaks of s.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Writer streams events to an HTTP response.
+type Writer struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewWriter starts an event stream on w: it sends the response headers and returns the
+// Writer of the events. It fails if w cannot be flushed, as events would then be buffered.
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	h := w.Header()
+	h.Set("Content-Type", ContentType)
+	h.Set("Cache-Control", "no-store")
+	// Disables the buffering of reverse proxies such as nginx.
+	h.Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, errors.New("event streams need a response that can be flushed: " + err.Error())
+	}
+	return &Writer{w: w, rc: rc}, nil
+}
+
+// Send writes e and flushes it to the client.
+func (sw *Writer) Send(e Event) error {
+	b, _ := e.MarshalText()
+	return sw.write(b)
+}
+
+// Comment writes a comment, which clients ignore. Sent periodically, it keeps idle
+// connections from being closed by proxies.
+func (sw *Writer) Comment(text string) error {
+	return sw.write([]byte(": " + singleLine(text) + "\n\n"))
+}
+
+func (sw *Writer) write(b []byte) error {
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
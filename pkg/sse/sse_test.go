@@ -0,0 +1,133 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent_MarshalText(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{name: "data only", event: Event{Data: "hello"}, expected: "data: hello\n\n"},
+		{
+			name:     "every field",
+			event:    Event{ID: "7", Type: "health", Data: "ok", Retry: 1500 * time.Millisecond},
+			expected: "id: 7\nevent: health\nretry: 1500\ndata: ok\n\n",
+		},
+		{
+			name:     "multi-line data",
+			event:    Event{Data: "<p>\r\n  up\n</p>"},
+			expected: "data: <p>\ndata:   up\ndata: </p>\n\n",
+		},
+		{
+			name:     "line breaks in the ID and type",
+			event:    Event{ID: "1\n2", Type: "a\r\nb", Data: "x"},
+			expected: "id: 12\nevent: ab\ndata: x\n\n",
+		},
+		{name: "retry only", event: Event{Retry: time.Second}, expected: "retry: 1000\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.event.MarshalText()
+			require.NoError(t, err, "MarshalText should not fail")
+			assert.Equal(t, tt.expected, string(b), "encoding mismatch")
+		})
+	}
+}
+
+// readAll returns every event of stream.
+func readAll(t *testing.T, stream string) []Event {
+	t.Helper()
+	rd := NewReader(strings.NewReader(stream))
+	var events []Event
+	for {
+		e, err := rd.Next()
+		if err == io.EOF {
+			return events
+		}
+		require.NoError(t, err, "Next should not fail")
+		events = append(events, e)
+	}
+}
+
+func TestReader_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		expected []Event
+	}{
+		{
+			name:     "single event",
+			stream:   "id: 1\nevent: health\ndata: ok\n\n",
+			expected: []Event{{ID: "1", Type: "health", Data: "ok"}},
+		},
+		{
+			name:     "multi-line data and CRLF",
+			stream:   "data: a\r\ndata:b\r\n\r\n",
+			expected: []Event{{Data: "a\nb"}},
+		},
+		{
+			name:   "ID carries over and type does not",
+			stream: "id: 1\nevent: health\ndata: a\n\ndata: b\n\n",
+			expected: []Event{
+				{ID: "1", Type: "health", Data: "a"},
+				{ID: "1", Data: "b"},
+			},
+		},
+		{
+			name:     "comments, retry and blocks without data",
+			stream:   ": keep-alive\n\nretry: 2000\n\nevent: ignored\n\ndata: x\n\n",
+			expected: []Event{{Data: "x", Retry: 2 * time.Second}},
+		},
+		{
+			name:     "unknown fields and invalid retry",
+			stream:   "foo: bar\nretry: soon\ndata\n\n",
+			expected: []Event{{Data: ""}},
+		},
+		{
+			name:     "event cut short",
+			stream:   "data: a\n\ndata: b\n",
+			expected: []Event{{Data: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, readAll(t, tt.stream), "events mismatch")
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw, err := NewWriter(rec)
+	require.NoError(t, err, "NewWriter should not fail")
+	require.NoError(t, sw.Send(Event{ID: "1", Type: "health", Data: "a\nb"}), "Send")
+	require.NoError(t, sw.Comment("keep-alive"), "Comment")
+
+	assert.Equal(t, http.StatusOK, rec.Code, "status mismatch")
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"), "Content-Type mismatch")
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"), "Cache-Control mismatch")
+	assert.True(t, rec.Flushed, "events should be flushed")
+	assert.Equal(t, []Event{{ID: "1", Type: "health", Data: "a\nb"}},
+		readAll(t, rec.Body.String()), "the Reader should parse what the Writer sends")
+}
+
+// unflushable is a ResponseWriter that does not support flushing.
+type unflushable struct{ http.ResponseWriter }
+
+func TestNewWriter_Unflushable(t *testing.T) {
+	_, err := NewWriter(unflushable{httptest.NewRecorder()})
+	assert.Error(t, err, "NewWriter should refuse responses that cannot be flushed")
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"errors"
+	"net/url"
+	"os"
+)
+
+// Exporter names accepted by Config.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config selects and configures the span exporter. It is meant to be embedded in the
+// configuration of each binary.
+type Config struct {
+	Exporter     string  `config:"exporter"      usage:"span exporter: none, stdout or otlp"`
+	OTLPEndpoint string  `config:"otlp_endpoint" usage:"base URL of the OTLP/HTTP collector"`
+	SampleRatio  float64 `config:"sample_ratio"  usage:"fraction of new traces that are recorded"`
+}
+
+// DefaultConfig returns a configuration with tracing disabled.
+func DefaultConfig() Config {
+	return Config{
+		Exporter:     ExporterNone,
+		OTLPEndpoint: "http://localhost:4318",
+		SampleRatio:  1,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		u, err := url.Parse(c.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("tracing.otlp_endpoint must be an absolute http(s) URL")
+		}
+	default:
+		return errors.New("tracing.exporter must be one of none, stdout or otlp")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// New returns a Tracer for service as configured by cfg. With the "none" exporter it
+// returns a nil Tracer, which is valid and records nothing.
+func New(service string, cfg Config) *Tracer {
+	var exporter Exporter
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter = NewStdoutExporter(os.Stdout)
+	case ExporterOTLP:
+		exporter = NewOTLPExporter(cfg.OTLPEndpoint, nil)
+	default:
+		return nil
+	}
+	return NewTracer(service, exporter, WithSampleRatio(cfg.SampleRatio))
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/supergeoff/go-starter/pkg/middleware"
+)
+
+// Middleware returns a chi middleware creating a server span per inbound request, as a
+// child of the caller's span when the request carries a traceparent header.
+// The span is named after the route pattern once the request has been routed.
+// A nil tracer disables the middleware.
+func Middleware(t *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if t == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := []SpanOption{
+				WithSpanKind(KindServer),
+				WithAttributes(
+					String("http.request.method", r.Method),
+					String("url.path", r.URL.Path),
+				),
+			}
+			if parent, ok := Extract(r.Header); ok {
+				opts = append(opts, WithRemoteParent(parent))
+			}
+			ctx, span := t.Start(r.Context(), r.Method, opts...)
+			defer span.End()
+
+			rw := middleware.NewResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			route := middleware.RoutePattern(r)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				String("http.route", route),
+				Int("http.response.status_code", rw.Status()),
+			)
+			if rw.Status() >= http.StatusInternalServerError {
+				span.SetError(errorStatus(rw.Status()))
+			}
+		})
+	}
+}
+
+// Transport wraps next so that outbound requests get a client span, child of the span in
+// the request context, and carry its traceparent header. Requests made outside a traced
+// context are passed through unchanged. If next is nil, http.DefaultTransport is used.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return transport{next: next}
+}
+
+// transport is the http.RoundTripper returned by Transport.
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := StartSpan(req.Context(), "HTTP "+req.Method,
+		WithSpanKind(KindClient),
+		WithAttributes(
+			String("http.request.method", req.Method),
+			String("server.address", req.URL.Host),
+			String("url.full", req.URL.Redacted()),
+		),
+	)
+	if span == nil {
+		return t.next.RoundTrip(req)
+	}
+	defer span.End()
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(ctx)
+	Inject(span.SpanContext(), req.Header)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(errorStatus(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// errorStatus is the error recorded on spans for 5xx responses.
+type errorStatus int
+
+func (e errorStatus) Error() string { return http.StatusText(int(e)) }
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name            string
+		value           string
+		expectedTraceID string
+		expectedSpanID  string
+		expectedSampled bool
+		expectError     bool
+	}{
+		{
+			name:            "sampled",
+			value:           "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+			expectedSampled: true,
+		},
+		{
+			name:            "not sampled",
+			value:           "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+			expectedSampled: false,
+		},
+		{
+			name:            "future version with extra field",
+			value:           "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+			expectedSampled: true,
+		},
+		{name: "empty", value: "", expectError: true},
+		{
+			name:        "version ff",
+			value:       "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectError: true,
+		},
+		{
+			name:        "version 00 with extra field",
+			value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x",
+			expectError: true,
+		},
+		{
+			name:        "uppercase hex",
+			value:       "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			expectError: true,
+		},
+		{name: "short trace ID", value: "00-4bf92f35-00f067aa0ba902b7-01", expectError: true},
+		{
+			name:        "zero trace ID",
+			value:       "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			expectError: true,
+		},
+		{
+			name:        "zero span ID",
+			value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if tt.expectError {
+				assert.Error(t, err, "ParseTraceparent(%q) should fail", tt.value)
+				return
+			}
+			require.NoError(t, err, "ParseTraceparent(%q) should succeed", tt.value)
+			assert.Equal(t, tt.expectedTraceID, sc.TraceID.String(), "trace ID mismatch")
+			assert.Equal(t, tt.expectedSpanID, sc.SpanID.String(), "span ID mismatch")
+			assert.Equal(t, tt.expectedSampled, sc.Sampled, "sampled flag mismatch")
+			assert.True(t, sc.Remote, "parsed contexts are remote")
+		})
+	}
+}
+
+func TestInjectExtract_RoundTrip(t *testing.T) {
+	sc := SpanContext{
+		TraceID:    newTraceID(),
+		SpanID:     newSpanID(),
+		Sampled:    true,
+		TraceState: "vendor=1",
+	}
+	h := http.Header{}
+	Inject(sc, h)
+
+	got, ok := Extract(h)
+	require.True(t, ok, "Extract should find the injected context")
+	assert.Equal(t, sc.TraceID, got.TraceID, "trace ID mismatch")
+	assert.Equal(t, sc.SpanID, got.SpanID, "span ID mismatch")
+	assert.Equal(t, sc.Sampled, got.Sampled, "sampled flag mismatch")
+	assert.Equal(t, "vendor=1", got.TraceState, "tracestate should be forwarded")
+
+	empty := http.Header{}
+	Inject(SpanContext{}, empty)
+	assert.Empty(t, empty, "invalid contexts must not be injected")
+}
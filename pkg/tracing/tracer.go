@@ -0,0 +1,422 @@
+// Package tracing records spans for inbound requests, outbound calls and internal work,
+// propagates them with W3C Trace Context headers and exports them in batches, either to
+// stdout as JSON or to an OTLP/HTTP collector.
+//
+// A nil *Tracer is valid and records nothing, which is how tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of a span to its parent. Values match OTLP.
+type SpanKind int
+
+const (
+	// KindInternal is a span for work that does not cross a process boundary.
+	KindInternal SpanKind = 1
+	// KindServer is a span for an inbound request.
+	KindServer SpanKind = 2
+	// KindClient is a span for an outbound request.
+	KindClient SpanKind = 3
+)
+
+// String returns the lowercase name of the kind.
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// StatusCode is the outcome of a span. Values match OTLP.
+type StatusCode int
+
+const (
+	// StatusUnset is the default status of a span.
+	StatusUnset StatusCode = 0
+	// StatusError marks a failed operation.
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key/value pair describing a span. Values are strings, int64s,
+// float64s or bools.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// SpanData is the immutable snapshot of an ended span handed to exporters.
+type SpanData struct {
+	Service       string
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	ParentSpanID  SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span is an operation being timed. A nil *Span is valid and records nothing.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the propagated part of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext // Immutable after creation.
+}
+
+// IsRecording reports whether the span will be exported.
+func (s *Span) IsRecording() bool {
+	return s != nil && s.data.SpanContext.Sampled
+}
+
+// SetName renames the span, e.g. once the route of a request is known.
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetError marks the span as failed with err. A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = StatusError
+	s.data.StatusMessage = err.Error()
+}
+
+// End records the end time and queues the span for export. Later calls are no-ops.
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.tracer.enqueue(data)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span stored in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanOption configures a span at creation.
+type SpanOption func(*spanConfig)
+
+type spanConfig struct {
+	kind         SpanKind
+	remoteParent SpanContext
+	attrs        []Attribute
+}
+
+// WithSpanKind sets the kind of the span; the default is KindInternal.
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(c *spanConfig) { c.kind = kind }
+}
+
+// WithRemoteParent makes the span a child of a context extracted from another process,
+// instead of the span found in the Go context.
+func WithRemoteParent(sc SpanContext) SpanOption {
+	return func(c *spanConfig) { c.remoteParent = sc }
+}
+
+// WithAttributes sets initial attributes on the span.
+func WithAttributes(attrs ...Attribute) SpanOption {
+	return func(c *spanConfig) { c.attrs = append(c.attrs, attrs...) }
+}
+
+// Tracer creates spans and exports them in batches.
+type Tracer struct {
+	service     string
+	exporter    Exporter
+	sampleRatio float64
+	batchSize   int
+	interval    time.Duration
+
+	queue    chan SpanData
+	flushReq chan chan error
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Option configures a Tracer.
+type Option func(*Tracer)
+
+// WithSampleRatio sets the fraction of new root traces that are recorded.
+// Child spans follow the sampling decision of their parent.
+func WithSampleRatio(ratio float64) Option {
+	return func(t *Tracer) { t.sampleRatio = ratio }
+}
+
+// WithBatchInterval sets how often queued spans are exported.
+func WithBatchInterval(d time.Duration) Option {
+	return func(t *Tracer) {
+		if d > 0 {
+			t.interval = d
+		}
+	}
+}
+
+// NewTracer returns a Tracer exporting the spans of service through exporter.
+// It starts a background goroutine stopped by Shutdown.
+func NewTracer(service string, exporter Exporter, opts ...Option) *Tracer {
+	t := &Tracer{
+		service:     service,
+		exporter:    exporter,
+		sampleRatio: 1,
+		batchSize:   256,
+		interval:    5 * time.Second,
+		queue:       make(chan SpanData, 2048),
+		flushReq:    make(chan chan error),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	go t.run()
+	return t
+}
+
+// Start creates a span named name as a child of the span in ctx (or of the remote parent
+// given with WithRemoteParent) and returns a context carrying it.
+// The caller must call End on the returned span.
+func (t *Tracer) Start(
+	ctx context.Context,
+	name string,
+	opts ...SpanOption,
+) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	cfg := spanConfig{kind: KindInternal}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	parent := cfg.remoteParent
+	if !parent.IsValid() {
+		parent = SpanFromContext(ctx).SpanContext()
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.sampleRatio >= 1 || rand.Float64() < t.sampleRatio
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Service:      t.service,
+			Name:         name,
+			Kind:         cfg.kind,
+			SpanContext:  sc,
+			ParentSpanID: parent.SpanID,
+			Start:        time.Now(),
+			Attributes:   cfg.attrs,
+		},
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartSpan creates a child of the span found in ctx, using the same tracer.
+// Without a span in ctx, tracing is not active for this work and a nil span is returned.
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, opts...)
+}
+
+// enqueue hands an ended span to the export goroutine, dropping it if the queue is full
+// rather than slowing down the request.
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case <-t.stop:
+		return
+	default:
+	}
+	select {
+	case t.queue <- data:
+	default:
+		slog.Warn("tracing queue full, dropping span", "span", data.Name)
+	}
+}
+
+// run batches queued spans and exports them on size, on interval, on flush and on stop.
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	export := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := t.exporter.ExportSpans(ctx, batch)
+		if err != nil {
+			slog.Error("failed to export spans", "count", len(batch), "error", err)
+		}
+		batch = nil
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				_ = export()
+			}
+		case <-ticker.C:
+			_ = export()
+		case reply := <-t.flushReq:
+			drain()
+			reply <- export()
+		case <-t.stop:
+			drain()
+			_ = export()
+			return
+		}
+	}
+}
+
+// ForceFlush exports every span ended so far.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	reply := make(chan error, 1)
+	select {
+	case t.flushReq <- reply:
+	case <-t.done:
+		return errors.New("tracer is shut down")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and releases the exporter. It is meant to be
+// registered as a shutdown hook. Spans ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.stopOnce.Do(func() { close(t.stop) })
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		hi, lo := rand.Uint64(), rand.Uint64()
+		for i := range 8 {
+			id[i] = byte(hi >> (8 * i))
+			id[8+i] = byte(lo >> (8 * i))
+		}
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		v := rand.Uint64()
+		for i := range 8 {
+			id[i] = byte(v >> (8 * i))
+		}
+	}
+	return id
+}
@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector is a stand-in for an OTLP/HTTP collector recording the spans it receives.
+type collector struct {
+	*httptest.Server
+	mu    sync.Mutex
+	spans map[string]collectedSpan // By span name.
+}
+
+type collectedSpan struct {
+	service string
+	span    otlpSpan
+}
+
+func newCollector(t *testing.T) *collector {
+	t.Helper()
+	c := &collector{spans: make(map[string]collectedSpan)}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path, "collector path mismatch")
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"), "collector content type")
+
+		var req otlpRequest
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req), "decoding OTLP request") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			service := *rs.Resource.Attributes[0].Value.StringValue
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					c.spans[s.Name] = collectedSpan{service: service, span: s}
+				}
+			}
+		}
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) get(t *testing.T, name string) collectedSpan {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.spans[name]
+	require.True(t, ok, "collector should have received span %q, got %v", name, c.spans)
+	return s
+}
+
+func TestTracing_PropagatesFromClientToServer(t *testing.T) {
+	coll := newCollector(t)
+	apiTracer := NewTracer("api", NewOTLPExporter(coll.URL, nil))
+	webTracer := NewTracer("web", NewOTLPExporter(coll.URL+"/v1/traces", nil))
+
+	api := chi.NewRouter()
+	api.Use(Middleware(apiTracer))
+	api.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	web := chi.NewRouter()
+	web.Use(Middleware(webTracer))
+	web.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(
+			r.Context(),
+			http.MethodGet,
+			apiServer.URL+"/items/7",
+			nil,
+		)
+		require.NoError(t, err, "creating API request")
+		resp, err := client.Do(req)
+		require.NoError(t, err, "calling API")
+		_ = resp.Body.Close()
+
+		_, span := StartSpan(
+			r.Context(),
+			"template.render",
+			WithAttributes(String("template", "home")),
+		)
+		span.End()
+	})
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, incoming)
+	web.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, webTracer.Shutdown(context.Background()), "shutting down web tracer")
+	require.NoError(t, apiTracer.Shutdown(context.Background()), "shutting down api tracer")
+
+	webServer := coll.get(t, "GET /")
+	render := coll.get(t, "template.render")
+	outbound := coll.get(t, "HTTP GET")
+	apiSpan := coll.get(t, "GET /items/{id}")
+
+	for _, s := range []collectedSpan{webServer, render, outbound, apiSpan} {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.span.TraceID,
+			"span %s should belong to the incoming trace", s.span.Name)
+	}
+	assert.Equal(t, "00f067aa0ba902b7", webServer.span.ParentSpanID, "web span parent mismatch")
+	assert.Equal(t, webServer.span.SpanID, render.span.ParentSpanID, "render span parent mismatch")
+	assert.Equal(
+		t,
+		webServer.span.SpanID,
+		outbound.span.ParentSpanID,
+		"client span parent mismatch",
+	)
+	assert.Equal(t, outbound.span.SpanID, apiSpan.span.ParentSpanID, "api span parent mismatch")
+
+	assert.Equal(t, "web", webServer.service, "web span service mismatch")
+	assert.Equal(t, "api", apiSpan.service, "api span service mismatch")
+	assert.Equal(t, KindServer, apiSpan.span.Kind, "api span kind mismatch")
+	assert.Equal(t, KindClient, outbound.span.Kind, "outbound span kind mismatch")
+	assert.Equal(
+		t,
+		StatusError,
+		apiSpan.span.Status.Code,
+		"5xx responses should mark the span failed",
+	)
+	assert.Equal(
+		t,
+		StatusError,
+		outbound.span.Status.Code,
+		"5xx responses should mark the span failed",
+	)
+}
+
+func TestTracer_SamplingAndNoop(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("web", NewStdoutExporter(&buf), WithSampleRatio(0))
+
+	ctx, span := tracer.Start(context.Background(), "root")
+	assert.False(t, span.IsRecording(), "unsampled spans should not record")
+	assert.True(t, span.SpanContext().IsValid(), "unsampled spans still propagate")
+	_, child := StartSpan(ctx, "child")
+	assert.False(t, child.SpanContext().Sampled, "children follow the parent decision")
+	child.End()
+	span.End()
+
+	require.NoError(t, tracer.Shutdown(context.Background()), "Shutdown should not fail")
+	assert.Empty(t, buf.String(), "unsampled spans should not be exported")
+
+	var nilTracer *Tracer
+	noopCtx, noop := nilTracer.Start(context.Background(), "noop")
+	assert.Nil(t, noop, "a nil tracer returns nil spans")
+	noop.SetAttributes(String("k", "v"))
+	noop.End()
+	_, orphan := StartSpan(noopCtx, "orphan")
+	assert.Nil(t, orphan, "StartSpan without a parent returns a nil span")
+	assert.NoError(t, nilTracer.Shutdown(context.Background()), "nil tracers shut down cleanly")
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("api", NewStdoutExporter(&buf))
+
+	ctx, root := tracer.Start(context.Background(), "root", WithSpanKind(KindServer))
+	_, child := StartSpan(ctx, "child", WithAttributes(Int("rows", 3), Bool("cached", true)))
+	child.SetError(assert.AnError)
+	child.End()
+	root.End()
+	require.NoError(t, tracer.ForceFlush(context.Background()), "ForceFlush should not fail")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2, "one JSON line per span expected")
+
+	var got stdoutSpan
+	require.NoError(t, json.Unmarshal(lines[0], &got), "decoding span")
+	assert.Equal(t, "child", got.Name, "span name mismatch")
+	assert.Equal(t, "internal", got.Kind, "span kind mismatch")
+	assert.Equal(t, root.SpanContext().SpanID.String(), got.ParentSpanID, "parent mismatch")
+	assert.Equal(
+		t,
+		map[string]any{"rows": 3.0, "cached": true},
+		got.Attributes,
+		"attributes mismatch",
+	)
+	assert.True(t, got.Error, "errors should be exported")
+	assert.Equal(t, assert.AnError.Error(), got.StatusMessage, "status message mismatch")
+
+	require.NoError(t, tracer.Shutdown(context.Background()), "Shutdown should not fail")
+}
@@ -3,13 +3,24 @@
 package main
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/big"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/mod/modfile"
 )
@@ -202,6 +213,207 @@ func Serve(dirpath string) error {
 	return run(relModuleDir, "go", "tool", "air", "-c", ".air.toml")
 }
 
+// openAPIPath is where OpenAPI writes the server's document, relative to tools/.
+// The file is committed so that API changes show up in review.
+var openAPIPath = filepath.Join("..", "apps", "server", "openapi.json")
+
+// OpenAPI generates the OpenAPI document of the server from its registered routes and
+// writes it to apps/server/openapi.json.
+func OpenAPI() error {
+	slog.Info("Generating OpenAPI document", "output", openAPIPath)
+	var out bytes.Buffer
+	cmd := exec.Command("go", "run", "./cmd/api", "openapi")
+	cmd.Dir = filepath.Join("..", "apps", "server")
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if err := cmd.Run(); err != nil {
+		slog.Error("Failed to generate OpenAPI document", "error", err)
+		return fmt.Errorf("failed to generate OpenAPI document: %w", err)
+	}
+
+	// Written only once generation succeeded, so that a failure keeps the previous file.
+	if err := os.WriteFile(openAPIPath, out.Bytes(), 0o644); err != nil {
+		slog.Error("Failed to write OpenAPI document", "path", openAPIPath, "error", err)
+		return fmt.Errorf("failed to write %s: %w", openAPIPath, err)
+	}
+	slog.Info("OpenAPI document written", "path", openAPIPath)
+	return nil
+}
+
+// Migrate runs the database migrations of the server with the given command: up applies
+// the pending migrations, down reverts the last one and status lists them. The database
+// is the one configured for the server, e.g. through API_DATABASE_PATH.
+func Migrate(command string) error {
+	switch command {
+	case "up", "down", "status":
+	default:
+		err := fmt.Errorf("migrate command must be up, down or status, got: %s", command)
+		slog.Error("Invalid migrate command", "error", err)
+		return err
+	}
+
+	slog.Info("Running database migrations", "command", command)
+	serverDir := filepath.Join("..", "apps", "server")
+	if err := run(serverDir, "go", "run", "./cmd/migrate", command); err != nil {
+		slog.Error("Failed to run database migrations", "command", command, "error", err)
+		return fmt.Errorf("migrate %s failed: %w", command, err)
+	}
+	return nil
+}
+
+// certsDir is where DevCert writes the development certificates, relative to tools/. It is
+// ignored by git: the keys must never be committed.
+var certsDir = filepath.Join("..", "certs")
+
+// devCertHosts are the names and addresses the development certificate is valid for.
+var devCertHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// DevCert creates a local certificate authority and a certificate for localhost signed by it
+// in certs/, so that the applications can be served over HTTPS during development. The CA is
+// created once and reused, so that it only needs to be trusted once, e.g. by adding
+// certs/ca.pem to the system or browser trust store; the localhost certificate is renewed on
+// every run. The servers pick it up through API_TLS_CERT_FILE and API_TLS_KEY_FILE, or
+// WEB_TLS_CERT_FILE and WEB_TLS_KEY_FILE, and the web client trusts the API through
+// WEB_API_CA_FILE.
+func DevCert() error {
+	if err := os.MkdirAll(certsDir, 0o755); err != nil {
+		slog.Error("Failed to create certificates directory", "path", certsDir, "error", err)
+		return fmt.Errorf("failed to create %s: %w", certsDir, err)
+	}
+
+	caCertPath := filepath.Join(certsDir, "ca.pem")
+	caKeyPath := filepath.Join(certsDir, "ca-key.pem")
+	ca, caKey, err := loadDevCA(caCertPath, caKeyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("Creating development CA", "path", caCertPath)
+		ca, caKey, err = createCert(&x509.Certificate{
+			Subject:               pkix.Name{CommonName: "go-starter development CA"},
+			NotAfter:              time.Now().AddDate(10, 0, 0),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		}, nil, nil, caCertPath, caKeyPath)
+	}
+	if err != nil {
+		slog.Error("Failed to prepare development CA", "path", caCertPath, "error", err)
+		return fmt.Errorf("failed to prepare the development CA: %w", err)
+	}
+
+	leaf := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: devCertHosts[0]},
+		NotAfter:    time.Now().AddDate(1, 0, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range devCertHosts {
+		if ip := net.ParseIP(host); ip != nil {
+			leaf.IPAddresses = append(leaf.IPAddresses, ip)
+		} else {
+			leaf.DNSNames = append(leaf.DNSNames, host)
+		}
+	}
+	certPath := filepath.Join(certsDir, "localhost.pem")
+	keyPath := filepath.Join(certsDir, "localhost-key.pem")
+	if _, _, err := createCert(leaf, ca, caKey, certPath, keyPath); err != nil {
+		slog.Error("Failed to create development certificate", "path", certPath, "error", err)
+		return fmt.Errorf("failed to create the development certificate: %w", err)
+	}
+
+	slog.Info("Development certificate created",
+		"cert_file", certPath,
+		"key_file", keyPath,
+		"ca_file", caCertPath,
+		"hosts", devCertHosts,
+	)
+	return nil
+}
+
+// loadDevCA reads the certificate and key of the development CA. The error wraps
+// os.ErrNotExist when the CA has not been created yet.
+func loadDevCA(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	certBlock, err := readPEM(certPath, "CERTIFICATE")
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, err := readPEM(keyPath, "PRIVATE KEY")
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", certPath, err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", keyPath, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s does not hold a signing key", keyPath)
+	}
+	return cert, signer, nil
+}
+
+// readPEM reads the first PEM block of the file at path, which must be of type typ.
+func readPEM(path, typ string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return nil, fmt.Errorf("%s does not hold a PEM %s", path, typ)
+	}
+	return block, nil
+}
+
+// createCert creates a certificate from tmpl with a new P-256 key, signed by parent and
+// parentKey, or self-signed if parent is nil, and writes it and its key to certPath and
+// keyPath.
+func createCert(
+	tmpl, parent *x509.Certificate,
+	parentKey crypto.Signer,
+	certPath, keyPath string,
+) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour) // Tolerates clock skew.
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
 // Install downloads and installs the Tailwind CSS CLI tool into the tools/ directory.
 // It checks if the tool is already present and executable.
 func Install() error {